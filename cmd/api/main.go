@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -11,12 +13,27 @@ import (
 	"time"
 
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/term"
 
+	"github.com/jame-developer/aeontrac/configuration"
 	"github.com/jame-developer/aeontrac/internal/api/router"
+	"github.com/jame-developer/aeontrac/internal/appcore"
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
 	"go.uber.org/zap"
 )
 
+// configReloadInterval is the interval in which the configuration file is checked for changes
+const configReloadInterval = 2 * time.Second
+
 func main() {
+	keyFile := flag.String("keyfile", "", "Keyfile of the encrypted time tracking data, "+appcore.KeyFileEnv+" or "+appcore.PassphraseEnv+" can be used instead")
+	var folders appcore.Folders
+	flag.StringVar(&folders.ConfigDir, "config-dir", "", "Configuration folder, overrides "+appcore.ConfigDirEnv+" and XDG_CONFIG_HOME")
+	flag.StringVar(&folders.DataDir, "data-dir", "", "Data folder, overrides "+appcore.DataDirEnv+" and XDG_DATA_HOME")
+	flag.StringVar(&folders.Vault, "vault", "", "Folder of the time tracking data, overrides "+appcore.VaultEnv+" and the data folder")
+	flag.Parse()
+	appcore.SetFolders(folders)
+
 	// Initialize logger
 	loggerCfg := zap.NewProductionConfig()
 	loggerCfg.EncoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
@@ -29,6 +46,25 @@ func main() {
 		_ = logger.Sync()
 	}(logger)
 
+	// The configuration is held in memory and reloaded when its file changes, an invalid change keeps the previous configuration
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	err = appcore.WatchConfig(watchCtx, configReloadInterval, func(snapshot *configuration.Snapshot, err error) {
+		if err != nil {
+			logger.Error("Invalid configuration change, keeping the previous configuration", zap.Error(err))
+			return
+		}
+		logger.Info("Configuration reloaded", zap.String("file", snapshot.File))
+	})
+	if err != nil {
+		logger.Fatal("can't load configuration", zap.Error(err))
+	}
+
+	// The key of encrypted time tracking data is needed before the first request
+	if err = unlockVault(*keyFile); err != nil {
+		logger.Fatal("can't unlock time tracking data", zap.Error(err))
+	}
+
 	// Setup router
 	r := router.SetupRouter(logger)
 
@@ -63,3 +99,28 @@ func main() {
 
 	logger.Info("Server exiting")
 }
+
+// unlockVault provides the key of encrypted time tracking data from the keyfile, the environment or a terminal prompt,
+// and verifies it by loading the data.
+func unlockVault(keyFile string) error {
+	if keyFile != "" {
+		key, err := vaultcrypt.LoadKeyFile(keyFile)
+		if err != nil {
+			return err
+		}
+		appcore.SetVaultKey(key)
+	}
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		appcore.PassphrasePrompt = func() (string, error) {
+			fmt.Fprint(os.Stderr, "Passphrase: ")
+			passphrase, err := term.ReadPassword(fd)
+			fmt.Fprintln(os.Stderr)
+			return string(passphrase), err
+		}
+	}
+	if _, err := appcore.VaultKey(); err != nil {
+		return err
+	}
+	_, _, _, err := appcore.LoadApp()
+	return err
+}
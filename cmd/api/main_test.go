@@ -237,4 +237,36 @@ func TestReportHandler(t *testing.T) {
 	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
 		t.Errorf("handler returned invalid json: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+func TestAdminConfigHandler(t *testing.T) {
+	setupTest(t)
+	defer teardownTest(t)
+
+	r := router.SetupRouter(zap.NewNop())
+	req, err := http.NewRequest("GET", "/admin/config", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var response handlers.ConfigResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("handler returned invalid json: %v", err)
+	}
+	for _, setting := range response.Settings {
+		if setting.Key == "working_hours.work_day" && (setting.Value != "8h0m0s" || setting.Origin != "default") {
+			t.Errorf("handler returned unexpected setting: got %+v", setting)
+		}
+	}
+	if len(response.Settings) == 0 {
+		t.Errorf("handler returned no settings")
+	}
+}
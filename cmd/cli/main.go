@@ -8,7 +8,7 @@ import (
 
 func main() {
 	if err := cli.Run(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(cli.ExitCode(err))
 	}
 }
@@ -3,51 +3,171 @@ package configuration
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
-	"github.com/go-playground/validator/v10"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
 )
 
+// FileName is the name of the configuration file written if the configuration folder has none.
+const FileName = "config.json"
+
+// Formats of the configuration file
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// FileNames are the names of the configuration files of all formats, a configuration folder holds at most one of them.
+var FileNames = []string{FileName, "config.yaml", "config.yml", "config.toml"}
+
 type Config struct {
 	PublicHolidays PublicHolidaysConfig `mapstructure:"public-holidays" json:"public_holidays"`
 	WorkingHours   WorkingHoursConfig   `mapstructure:"working-hours" json:"working_hours"`
+	Backup         BackupConfig         `mapstructure:"backup" json:"backup"`
+	Lock           LockConfig           `mapstructure:"lock" json:"lock"`
+	Storage        StorageConfig        `mapstructure:"storage" json:"storage"`
 }
 
+// GetDefaultConfig returns the configuration used if no configuration file exists.
+func GetDefaultConfig() Config {
+	return Config{
+		PublicHolidays: PublicHolidaysConfig{Enabled: true, Country: "DE", APIURL: "https://openholidaysapi.org/PublicHolidays"},
+		WorkingHours:   GetDefaultWorkingHoursConfig(),
+		Backup:         GetDefaultBackupConfig(),
+		Lock:           GetDefaultLockConfig(),
+		Storage:        GetDefaultStorageConfig(),
+	}
+}
+
+// LoadConfig loads the configuration file of the provided folder with the environment overrides merged on top.
+// The default configuration is written if the folder has no configuration file.
 func LoadConfig(configPath string) (*Config, error) {
-	configFilePath := filepath.Join(configPath, "config.json")
+	config, _, err := LoadLayeredConfig(configPath)
+	return config, err
+}
 
-	_, err := os.Stat(configFilePath)
+// LoadLayeredConfig loads the configuration like LoadConfig and returns where the value of each setting comes from:
+// the default, the configuration file or an environment variable.
+func LoadLayeredConfig(configPath string) (*Config, Origins, error) {
+	configFilePath, err := FilePath(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = os.Stat(configFilePath)
 	if errors.Is(err, os.ErrNotExist) {
-		defaultConfig := Config{
-			PublicHolidays: PublicHolidaysConfig{Enabled: true, Country: "DE", APIURL: "https://openholidaysapi.org/PublicHolidays"},
-			WorkingHours:   GetDefaultWorkingHoursConfig(),
-		}
-		bytes, err := json.Marshal(&defaultConfig)
-		if err != nil {
-			return nil, err
-		}
-		err = os.WriteFile(configFilePath, bytes, 0644)
-		if err != nil {
-			return nil, err
+		defaultConfig := GetDefaultConfig()
+		if err = SaveConfig(configPath, &defaultConfig); err != nil {
+			return nil, nil, err
 		}
 	} else if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	bytes, err := os.ReadFile(configFilePath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	var config Config
-	err = json.Unmarshal(bytes, &config)
+	// Settings missing in older configuration files keep their default values
+	config, origins, err := parseConfig(bytes, FileFormat(configFilePath), configFilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", configFilePath, err)
+	}
+	if err = applyEnvironment(config, origins); err != nil {
+		return nil, nil, err
+	}
+	if err = ValidateConfig(config); err != nil {
+		return nil, nil, fmt.Errorf("%s with the environment overrides: %w", configFilePath, err)
+	}
+	return config, origins, nil
+}
+
+// LoadConfigFile loads the configuration file of the provided folder without the environment overrides,
+// to change and save it. The default configuration is returned if the folder has no configuration file.
+func LoadConfigFile(configPath string) (*Config, error) {
+	configFilePath, err := FilePath(configPath)
 	if err != nil {
 		return nil, err
 	}
-	validate := validator.New()
-	err = validate.Struct(config)
+	bytes, err := os.ReadFile(configFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		config := GetDefaultConfig()
+		return &config, nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	config, err := ParseConfig(bytes, FileFormat(configFilePath))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configFilePath, err)
+	}
+	return config, nil
+}
+
+// SaveConfig writes the configuration to the configuration file of the provided folder, in the format of the existing file.
+func SaveConfig(configPath string, config *Config) error {
+	configFilePath, err := FilePath(configPath)
+	if err != nil {
+		return err
+	}
+	var bytes []byte
+	switch FileFormat(configFilePath) {
+	case FormatYAML:
+		bytes, err = yaml.Marshal(document(config))
+	case FormatTOML:
+		bytes, err = toml.Marshal(document(config))
+	default:
+		bytes, err = json.MarshalIndent(config, "", "    ")
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(configFilePath, bytes, 0644)
+}
 
-	return &config, nil
+// FilePath returns the configuration file of the provided folder, or the path of FileName if the folder has none.
+// A folder with configuration files of several formats is ambiguous.
+func FilePath(configPath string) (string, error) {
+	var existing []string
+	for _, fileName := range FileNames {
+		if _, err := os.Stat(filepath.Join(configPath, fileName)); err == nil {
+			existing = append(existing, fileName)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	switch len(existing) {
+	case 0:
+		return filepath.Join(configPath, FileName), nil
+	case 1:
+		return filepath.Join(configPath, existing[0]), nil
+	default:
+		return "", fmt.Errorf("the configuration folder %s has several configuration files, keep one of %s", configPath, strings.Join(existing, ", "))
+	}
+}
+
+// RemoveFiles removes the configuration files of all formats from the folder, so a configuration file of another
+// format can replace them without making the folder ambiguous. Other files of the folder are kept.
+func RemoveFiles(configPath string) error {
+	for _, fileName := range FileNames {
+		if err := os.Remove(filepath.Join(configPath, fileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// FileFormat returns the format of a configuration file by its extension.
+func FileFormat(path string) string {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
 }
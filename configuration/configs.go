@@ -26,6 +26,29 @@ type (
 		WorkDay *models.AeonDuration `json:"work_day"`
 		// Duration of the work week
 		WorkWeek *models.AeonDuration `json:"work_week"`
+		// Time zone of the times given to commands, like Europe/Berlin, the time zone of the system is used if it is not set
+		TimeZone string `json:"time_zone" validate:"omitempty,timezone"`
+	}
+	// BackupConfig represents the retention policy for the backups of the time tracking data
+	BackupConfig struct {
+		// Whether a backup is kept before the time tracking data is saved
+		Enabled bool `json:"enabled"`
+		// Maximum number of backups to keep, 0 keeps all backups
+		MaxCount int `json:"max_count" validate:"min=0"`
+		// Maximum age of the backups to keep, without a maximum age backups are kept regardless of their age
+		MaxAge *models.AeonDuration `json:"max_age"`
+	}
+	// StorageConfig represents the configuration of the storage backend of the time tracking data
+	StorageConfig struct {
+		// Storage backend, a single JSON file, a SQLite database or an event log
+		Backend string `json:"backend" validate:"oneof=json sqlite eventlog"`
+		// Number of events of the event log after which a snapshot is taken, the default is used if it is not set
+		SnapshotInterval int `json:"snapshot_interval,omitempty" validate:"min=0"`
+	}
+	// LockConfig represents the configuration of the lock, which coordinates processes working on the same time tracking data
+	LockConfig struct {
+		// Maximum time to wait for another process to release the lock
+		Timeout *models.AeonDuration `json:"timeout"`
 	}
 )
 
@@ -39,3 +62,36 @@ func GetDefaultWorkingHoursConfig() WorkingHoursConfig {
 		WorkWeek:   &models.AeonDuration{Duration: time.Hour * 40},
 	}
 }
+
+// Location returns the time zone of the times given to commands, the time zone of the system if none is set.
+func (c WorkingHoursConfig) Location() *time.Location {
+	if c.TimeZone == "" {
+		return time.Local
+	}
+	location, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		// The time zone is validated when the configuration is loaded
+		return time.Local
+	}
+	return location
+}
+
+func GetDefaultBackupConfig() BackupConfig {
+	return BackupConfig{
+		Enabled:  true,
+		MaxCount: 50,
+		MaxAge:   &models.AeonDuration{Duration: time.Hour * 24 * 90},
+	}
+}
+
+func GetDefaultLockConfig() LockConfig {
+	return LockConfig{
+		Timeout: &models.AeonDuration{Duration: time.Second * 10},
+	}
+}
+
+func GetDefaultStorageConfig() StorageConfig {
+	return StorageConfig{
+		Backend: "json",
+	}
+}
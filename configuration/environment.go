@@ -0,0 +1,38 @@
+package configuration
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding settings
+const EnvPrefix = "AEONTRAC_"
+
+// EnvName returns the environment variable overriding a setting, like AEONTRAC_WORKING_HOURS_WORK_DAY for working_hours.work_day.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// applyEnvironment merges the environment overrides into the configuration and records them as origins.
+// Values are parsed like the values of SetValue, empty variables are ignored.
+func applyEnvironment(config *Config, origins Origins) error {
+	var errs []error
+	for _, key := range Keys() {
+		envName := EnvName(key)
+		value := os.Getenv(envName)
+		if value == "" {
+			continue
+		}
+		if err := SetValue(config, key, value); err != nil {
+			var settingError *SettingError
+			if errors.As(err, &settingError) {
+				err = &SettingError{Key: envName, Reason: settingError.Reason}
+			}
+			errs = append(errs, err)
+			continue
+		}
+		origins[key] = envName
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,354 @@
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// clockFormat is the format of the times of day in the settings
+const clockFormat = "15:04"
+
+// SettingError describes an invalid setting of the configuration.
+type SettingError struct {
+	Key    string
+	Reason string
+}
+
+func (e *SettingError) Error() string {
+	return e.Key + ": " + e.Reason
+}
+
+// OriginDefault is the origin of settings which keep their default value
+const OriginDefault = "default"
+
+// Origins maps the keys of the settings to where their values come from:
+// OriginDefault, the path of the configuration file or the name of an environment variable.
+type Origins map[string]string
+
+// setting is a field of a section of the configuration with its key
+type setting struct {
+	key   string
+	value reflect.Value
+}
+
+// Keys returns the keys of all settings in the order of the configuration file.
+// A key is the name of the section and the name of the field in the configuration file, joined by a dot.
+func Keys() []string {
+	config := GetDefaultConfig()
+	settings := settingsOf(&config)
+	keys := make([]string, 0, len(settings))
+	for _, s := range settings {
+		keys = append(keys, s.key)
+	}
+	return keys
+}
+
+// GetValue returns the value of a setting, times of day as hours and minutes and unset durations as empty text.
+func GetValue(config *Config, key string) (string, error) {
+	value, err := lookup(config, key)
+	if err != nil {
+		return "", err
+	}
+	switch v := value.Interface().(type) {
+	case time.Time:
+		return v.Format(clockFormat), nil
+	case *models.AeonDuration:
+		if v == nil {
+			return "", nil
+		}
+		return v.String(), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// SetValue parses the value according to the type of the setting and changes the setting.
+// The configuration is not validated, see ValidateConfig.
+func SetValue(config *Config, key, value string) error {
+	field, err := lookup(config, key)
+	if err != nil {
+		return err
+	}
+	parsed, ok := parseValue(field, strings.TrimSpace(value))
+	if !ok {
+		return &SettingError{Key: key, Reason: expectation(field)}
+	}
+	field.Set(parsed)
+	return nil
+}
+
+// UnsetValue resets a setting to its default value.
+func UnsetValue(config *Config, key string) error {
+	field, err := lookup(config, key)
+	if err != nil {
+		return err
+	}
+	defaultConfig := GetDefaultConfig()
+	defaultValue, err := lookup(&defaultConfig, key)
+	if err != nil {
+		return err
+	}
+	field.Set(defaultValue)
+	return nil
+}
+
+// ParseConfig parses and validates the content of a configuration file in the provided format, without the environment overrides.
+// Missing settings keep their default values. Unknown settings and values of the wrong type are errors,
+// all invalid settings are returned as SettingError.
+func ParseConfig(bytes []byte, format string) (*Config, error) {
+	config, _, err := parseConfig(bytes, format, "")
+	if err != nil {
+		return nil, err
+	}
+	if err = ValidateConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// parseConfig parses the content of a configuration file without validating it, origin is recorded for the settings of the file
+func parseConfig(bytes []byte, format, origin string) (*Config, Origins, error) {
+	var sections map[string]any
+	var err error
+	switch format {
+	case FormatYAML:
+		err = yaml.Unmarshal(bytes, &sections)
+	case FormatTOML:
+		err = toml.Unmarshal(bytes, &sections)
+	default:
+		err = json.Unmarshal(bytes, &sections)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	config := GetDefaultConfig()
+	origins := Origins{}
+	sectionNames := map[string]bool{}
+	var errs []error
+	for _, s := range settingsOf(&config) {
+		origins[s.key] = OriginDefault
+		sectionName, fieldName, _ := strings.Cut(s.key, ".")
+		sectionNames[sectionName] = true
+		section, ok := sections[sectionName].(map[string]any)
+		if !ok {
+			continue
+		}
+		raw, ok := section[fieldName]
+		if !ok {
+			continue
+		}
+		delete(section, fieldName)
+		if !decodeValue(s.value, raw) {
+			errs = append(errs, &SettingError{Key: s.key, Reason: expectation(s.value)})
+		}
+		origins[s.key] = origin
+	}
+	// Only unknown settings are left
+	for _, sectionName := range slices.Sorted(maps.Keys(sections)) {
+		section, ok := sections[sectionName].(map[string]any)
+		switch {
+		case !sectionNames[sectionName]:
+			errs = append(errs, &SettingError{Key: sectionName, Reason: "is not a section"})
+			continue
+		case !ok && sections[sectionName] != nil:
+			errs = append(errs, &SettingError{Key: sectionName, Reason: "must be a section of settings"})
+			continue
+		}
+		for _, fieldName := range slices.Sorted(maps.Keys(section)) {
+			errs = append(errs, &SettingError{Key: sectionName + "." + fieldName, Reason: "is not a setting"})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	return &config, origins, nil
+}
+
+// ValidateConfig validates the configuration, every invalid setting is returned as SettingError.
+func ValidateConfig(config *Config) error {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonName)
+	validate.RegisterStructValidation(validateWorkingHours, WorkingHoursConfig{})
+	err := validate.Struct(config)
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+	errs := make([]error, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		// The namespace starts with the name of the configuration type
+		_, key, _ := strings.Cut(fieldError.Namespace(), ".")
+		errs = append(errs, &SettingError{Key: key, Reason: settingReason(fieldError)})
+	}
+	return errors.Join(errs...)
+}
+
+// settingsOf returns the settings of all sections of the configuration, their values can be set
+func settingsOf(config *Config) []setting {
+	var settings []setting
+	sections := reflect.ValueOf(config).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		sectionName := jsonName(sections.Type().Field(i))
+		for j := 0; j < section.NumField(); j++ {
+			settings = append(settings, setting{key: sectionName + "." + jsonName(section.Type().Field(j)), value: section.Field(j)})
+		}
+	}
+	return settings
+}
+
+// lookup returns the value of the setting with the key
+func lookup(config *Config, key string) (reflect.Value, error) {
+	for _, s := range settingsOf(config) {
+		if s.key == key {
+			return s.value, nil
+		}
+	}
+	return reflect.Value{}, &SettingError{Key: key, Reason: "is not a setting"}
+}
+
+// jsonName returns the name of a field in the configuration file
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// parseValue parses the text of a setting into a value of its type, times of day keep the date of the current value
+func parseValue(current reflect.Value, value string) (reflect.Value, bool) {
+	switch v := current.Interface().(type) {
+	case bool:
+		parsed, err := strconv.ParseBool(value)
+		return reflect.ValueOf(parsed), err == nil
+	case int:
+		parsed, err := strconv.Atoi(value)
+		return reflect.ValueOf(parsed), err == nil
+	case string:
+		return reflect.ValueOf(value), true
+	case time.Time:
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			return reflect.ValueOf(parsed), true
+		}
+		clock, err := time.Parse(clockFormat, value)
+		if err != nil {
+			clock, err = time.Parse(time.TimeOnly, value)
+		}
+		parsed := time.Date(v.Year(), v.Month(), v.Day(), clock.Hour(), clock.Minute(), 0, 0, v.Location())
+		return reflect.ValueOf(parsed), err == nil
+	case *models.AeonDuration:
+		if value == "" {
+			return reflect.ValueOf((*models.AeonDuration)(nil)), true
+		}
+		parsed, err := time.ParseDuration(value)
+		return reflect.ValueOf(&models.AeonDuration{Duration: parsed}), err == nil
+	}
+	return reflect.Value{}, false
+}
+
+// decodeValue sets a setting to a value decoded from a configuration file. Text is parsed like the values of SetValue,
+// so the formats of durations and times of day are the same in all file formats. Null keeps the default, except for durations.
+func decodeValue(field reflect.Value, raw any) bool {
+	if raw == nil {
+		if field.Kind() == reflect.Pointer {
+			field.Set(reflect.Zero(field.Type()))
+		}
+		return true
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return false
+	}
+	var text string
+	if json.Unmarshal(encoded, &text) == nil {
+		parsed, ok := parseValue(field, strings.TrimSpace(text))
+		if ok {
+			field.Set(parsed)
+		}
+		return ok
+	}
+	return json.Unmarshal(encoded, field.Addr().Interface()) == nil
+}
+
+// document returns the sections of the configuration with the values of their settings as written to YAML and TOML files
+func document(config *Config) map[string]map[string]any {
+	sections := map[string]map[string]any{}
+	for _, s := range settingsOf(config) {
+		sectionName, fieldName, _ := strings.Cut(s.key, ".")
+		if sections[sectionName] == nil {
+			sections[sectionName] = map[string]any{}
+		}
+		switch v := s.value.Interface().(type) {
+		case bool, int, string:
+			sections[sectionName][fieldName] = v
+		default:
+			// Times of day and durations are written as text, unset durations as empty text
+			sections[sectionName][fieldName], _ = GetValue(config, s.key)
+		}
+	}
+	return sections
+}
+
+// expectation describes the values a setting accepts
+func expectation(current reflect.Value) string {
+	switch current.Interface().(type) {
+	case bool:
+		return "must be true or false"
+	case int:
+		return "must be a whole number"
+	case time.Time:
+		return "must be a time of day like 09:00"
+	case *models.AeonDuration:
+		return "must be a duration like 8h or 7h30m"
+	default:
+		return "must be text"
+	}
+}
+
+// settingReason describes why a setting is invalid
+func settingReason(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "iso3166_1_alpha2":
+		return "must be a country code like DE"
+	case "url":
+		return "must be a URL"
+	case "min":
+		return "must be at least " + fieldError.Param()
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fieldError.Param(), " ", ", ")
+	case "timezone":
+		return "must be a time zone like Europe/Berlin"
+	case "not_negative":
+		return "must not be negative"
+	case "after_start_time":
+		return "must be after the start time"
+	default:
+		return "failed the " + fieldError.Tag() + " validation"
+	}
+}
+
+func validateWorkingHours(sl validator.StructLevel) {
+	workingHours := sl.Current().Interface().(WorkingHoursConfig)
+	if !workingHours.EndTime.After(workingHours.StartTime) {
+		sl.ReportError(workingHours.EndTime, "end_time", "EndTime", "after_start_time", "")
+	}
+	durations := []struct {
+		name     string
+		duration *models.AeonDuration
+	}{{"lunch_break", workingHours.LunchBreak}, {"work_day", workingHours.WorkDay}, {"work_week", workingHours.WorkWeek}}
+	for _, d := range durations {
+		if d.duration != nil && d.duration.Duration < 0 {
+			sl.ReportError(d.duration, d.name, d.name, "not_negative", "")
+		}
+	}
+}
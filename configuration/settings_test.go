@@ -0,0 +1,225 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetValue(t *testing.T) {
+	tests := []struct {
+		name          string
+		key           string
+		value         string
+		expected      string
+		expectedError string
+	}{
+		{name: "Duration", key: "working_hours.work_day", value: "7h30m", expected: "7h30m0s"},
+		{name: "TimeOfDay", key: "working_hours.start_time", value: "08:15", expected: "08:15"},
+		{name: "Bool", key: "backup.enabled", value: "false", expected: "false"},
+		{name: "Int", key: "backup.max_count", value: "10", expected: "10"},
+		{name: "Text", key: "public_holidays.region", value: "DE-BY", expected: "DE-BY"},
+		{name: "TimeZone", key: "working_hours.time_zone", value: "Europe/Berlin", expected: "Europe/Berlin"},
+		{name: "UnsetDuration", key: "backup.max_age", value: "", expected: ""},
+		{name: "InvalidDuration", key: "working_hours.work_day", value: "8hrs", expectedError: "working_hours.work_day: must be a duration like 8h or 7h30m"},
+		{name: "InvalidInt", key: "backup.max_count", value: "many", expectedError: "backup.max_count: must be a whole number"},
+		{name: "UnknownSetting", key: "working_hours.work_dya", value: "8h", expectedError: "working_hours.work_dya: is not a setting"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := GetDefaultConfig()
+
+			err := SetValue(&config, tt.key, tt.value)
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			value, err := GetValue(&config, tt.key)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestUnsetValue(t *testing.T) {
+	config := GetDefaultConfig()
+	require.NoError(t, SetValue(&config, "working_hours.work_week", "20h"))
+
+	require.NoError(t, UnsetValue(&config, "working_hours.work_week"))
+
+	assert.Equal(t, 40*time.Hour, config.WorkingHours.WorkWeek.Duration)
+}
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        string
+		content       string
+		expectedError string
+	}{
+		{
+			name:    "MissingSettingsKeepDefaults",
+			format:  FormatJSON,
+			content: `{"working_hours": {"work_day": "7h"}}`,
+		},
+		{
+			name:    "YAML",
+			format:  FormatYAML,
+			content: "working_hours:\n  work_day: 7h\n  start_time: \"08:00\"\nbackup:\n  max_count: 20\n",
+		},
+		{
+			name:    "TOML",
+			format:  FormatTOML,
+			content: "[working_hours]\nwork_day = \"7h\"\nstart_time = 08:00:00\n\n[backup]\nmax_count = 20\n",
+		},
+		{
+			name:          "InvalidDuration",
+			format:        FormatJSON,
+			content:       `{"working_hours": {"work_day": "7 hours"}}`,
+			expectedError: "working_hours.work_day: must be a duration like 8h or 7h30m",
+		},
+		{
+			name:          "InvalidYAMLValue",
+			format:        FormatYAML,
+			content:       "backup:\n  max_count: many\n",
+			expectedError: "backup.max_count: must be a whole number",
+		},
+		{
+			name:          "UnknownSettingAndSection",
+			format:        FormatJSON,
+			content:       `{"working_hours": {"work_dya": "7h"}, "holidays": {}}`,
+			expectedError: "holidays: is not a section\nworking_hours.work_dya: is not a setting",
+		},
+		{
+			name:          "FailedValidation",
+			format:        FormatJSON,
+			content:       `{"public_holidays": {"country": "Germany"}, "storage": {"backend": "csv"}}`,
+			expectedError: "public_holidays.country: must be a country code like DE\nstorage.backend: must be one of json, sqlite, eventlog",
+		},
+		{
+			name:          "InvalidTimeZone",
+			format:        FormatJSON,
+			content:       `{"working_hours": {"time_zone": "Berlin"}}`,
+			expectedError: "working_hours.time_zone: must be a time zone like Europe/Berlin",
+		},
+		{
+			name:          "EndTimeBeforeStartTime",
+			format:        FormatJSON,
+			content:       `{"working_hours": {"start_time": "2024-01-01T17:00:00Z", "end_time": "2024-01-01T09:00:00Z"}}`,
+			expectedError: "working_hours.end_time: must be after the start time",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseConfig([]byte(tt.content), tt.format)
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 7*time.Hour, config.WorkingHours.WorkDay.Duration)
+			assert.Equal(t, GetDefaultConfig().PublicHolidays, config.PublicHolidays)
+		})
+	}
+}
+
+func TestSaveConfig(t *testing.T) {
+	for _, fileName := range FileNames {
+		t.Run(fileName, func(t *testing.T) {
+			configPath := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(configPath, fileName), nil, 0644))
+			config := GetDefaultConfig()
+			require.NoError(t, SetValue(&config, "working_hours.start_time", "08:30"))
+			require.NoError(t, SetValue(&config, "backup.max_age", ""))
+
+			require.NoError(t, SaveConfig(configPath, &config))
+
+			loaded, err := LoadConfigFile(configPath)
+			require.NoError(t, err)
+			assert.Equal(t, config, *loaded)
+		})
+	}
+}
+
+func TestLoadLayeredConfig(t *testing.T) {
+	configPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(configPath, "config.yaml"), []byte("working_hours:\n  work_day: 7h\n"), 0644))
+	t.Setenv("AEONTRAC_WORKING_HOURS_WORK_WEEK", "35h")
+
+	config, origins, err := LoadLayeredConfig(configPath)
+
+	require.NoError(t, err)
+	assert.Equal(t, 7*time.Hour, config.WorkingHours.WorkDay.Duration)
+	assert.Equal(t, 35*time.Hour, config.WorkingHours.WorkWeek.Duration)
+	assert.Equal(t, filepath.Join(configPath, "config.yaml"), origins["working_hours.work_day"])
+	assert.Equal(t, "AEONTRAC_WORKING_HOURS_WORK_WEEK", origins["working_hours.work_week"])
+	assert.Equal(t, OriginDefault, origins["working_hours.lunch_break"])
+
+	t.Setenv("AEONTRAC_WORKING_HOURS_WORK_WEEK", "35 hours")
+	_, _, err = LoadLayeredConfig(configPath)
+	assert.EqualError(t, err, "AEONTRAC_WORKING_HOURS_WORK_WEEK: must be a duration like 8h or 7h30m")
+
+	file, err := LoadConfigFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, 40*time.Hour, file.WorkingHours.WorkWeek.Duration, "the environment overrides are not part of the file")
+}
+
+func TestFilePath(t *testing.T) {
+	configPath := t.TempDir()
+	path, err := FilePath(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(configPath, FileName), path)
+
+	require.NoError(t, os.WriteFile(filepath.Join(configPath, "config.toml"), nil, 0644))
+	path, err = FilePath(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(configPath, "config.toml"), path)
+
+	require.NoError(t, os.WriteFile(filepath.Join(configPath, "config.yaml"), nil, 0644))
+	_, err = FilePath(configPath)
+	assert.ErrorContains(t, err, "has several configuration files")
+}
+
+func TestRemoveFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		expected []string
+	}{
+		{name: "NoFiles"},
+		{name: "OtherFormat", existing: []string{"config.yaml"}},
+		{name: "SameFormat", existing: []string{FileName}},
+		{name: "SeveralFormats", existing: []string{"config.yml", "config.toml"}},
+		{name: "OtherFilesAreKept", existing: []string{"config.yaml", "active_profile", "vault.key"}, expected: []string{"active_profile", "vault.key"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := t.TempDir()
+			for _, fileName := range tt.existing {
+				require.NoError(t, os.WriteFile(filepath.Join(configPath, fileName), nil, 0644))
+			}
+
+			require.NoError(t, RemoveFiles(configPath))
+
+			var remaining []string
+			entries, err := os.ReadDir(configPath)
+			require.NoError(t, err)
+			for _, entry := range entries {
+				remaining = append(remaining, entry.Name())
+			}
+			assert.ElementsMatch(t, tt.expected, remaining)
+			// An imported configuration of any format is then the only one
+			require.NoError(t, os.WriteFile(filepath.Join(configPath, FileName), nil, 0644))
+			path, err := FilePath(configPath)
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(configPath, FileName), path)
+		})
+	}
+}
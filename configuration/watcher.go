@@ -0,0 +1,130 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Snapshot is a configuration loaded by a Watcher, with the origins of its values.
+type Snapshot struct {
+	Config   *Config
+	Origins  Origins
+	File     string
+	LoadedAt time.Time
+}
+
+// fileState identifies a version of the configuration file
+type fileState struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// Watcher holds the configuration of a folder in memory and reloads it when the configuration file changes.
+// A valid change replaces the configuration atomically, an invalid change keeps the previous configuration.
+type Watcher struct {
+	configPath string
+	snapshot   atomic.Pointer[Snapshot]
+	// mu serializes reloads and guards state and lastError
+	mu        sync.Mutex
+	state     fileState
+	lastError error
+}
+
+// NewWatcher loads the configuration of the folder like LoadLayeredConfig, an invalid configuration is an error.
+func NewWatcher(configPath string) (*Watcher, error) {
+	w := &Watcher{configPath: configPath}
+	// The configuration file is written by LoadLayeredConfig if none exists, its state is read afterwards
+	config, origins, err := LoadLayeredConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if w.state, err = currentFileState(configPath); err != nil {
+		return nil, err
+	}
+	w.snapshot.Store(&Snapshot{Config: config, Origins: origins, File: w.state.path, LoadedAt: time.Now()})
+	return w, nil
+}
+
+// Config returns a copy of the current configuration.
+func (w *Watcher) Config() *Config {
+	config := *w.snapshot.Load().Config
+	return &config
+}
+
+// Snapshot returns the current configuration with the origins of its values, it must not be changed.
+func (w *Watcher) Snapshot() *Snapshot {
+	return w.snapshot.Load()
+}
+
+// LastError returns the error of the last reload, nil if it succeeded or the configuration file has not changed since.
+func (w *Watcher) LastError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastError
+}
+
+// Reload loads the configuration file if it has changed since the last reload, and reports whether the configuration was replaced.
+// An invalid or removed configuration file keeps the previous configuration, its error is returned once and kept as LastError
+// until the file changes again.
+func (w *Watcher) Reload() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	state, err := currentFileState(w.configPath)
+	if state == w.state {
+		// Unchanged, an error of the unchanged file has already been reported
+		return false, nil
+	}
+	w.state = state
+	var config *Config
+	var origins Origins
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		err = fmt.Errorf("the configuration file %s has been removed, the previous configuration is kept", state.path)
+	case err == nil:
+		config, origins, err = LoadLayeredConfig(w.configPath)
+	}
+	if err != nil {
+		w.lastError = err
+		return false, err
+	}
+	w.lastError = nil
+	w.snapshot.Store(&Snapshot{Config: config, Origins: origins, File: state.path, LoadedAt: time.Now()})
+	return true, nil
+}
+
+// Watch reloads the configuration every interval until the context is done.
+// onReload is called after the configuration has been replaced, and with the error of every failed reload.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration, onReload func(snapshot *Snapshot, err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := w.Reload()
+			if reloaded || err != nil {
+				onReload(w.Snapshot(), err)
+			}
+		}
+	}
+}
+
+// currentFileState returns the state of the configuration file of the folder, the path is set if the file does not exist
+func currentFileState(configPath string) (fileState, error) {
+	path, err := FilePath(configPath)
+	if err != nil {
+		return fileState{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{path: path}, err
+	}
+	return fileState{path: path, modTime: info.ModTime(), size: info.Size()}, nil
+}
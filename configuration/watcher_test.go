@@ -0,0 +1,81 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcherReload(t *testing.T) {
+	configPath := t.TempDir()
+	configFile := filepath.Join(configPath, "config.yaml")
+	// Every write gets a later modification time, file systems with coarse timestamps would miss the change otherwise
+	modTime := time.Now()
+	write := func(content string) {
+		require.NoError(t, os.WriteFile(configFile, []byte(content), 0644))
+		modTime = modTime.Add(time.Second)
+		require.NoError(t, os.Chtimes(configFile, modTime, modTime))
+	}
+	write("working_hours:\n  work_day: 7h\n")
+
+	watcher, err := NewWatcher(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, 7*time.Hour, watcher.Config().WorkingHours.WorkDay.Duration)
+
+	tests := []struct {
+		name             string
+		content          string
+		expectedReloaded bool
+		expectedError    string
+		expectedWorkDay  time.Duration
+	}{
+		{name: "Unchanged", expectedWorkDay: 7 * time.Hour},
+		{name: "ValidChange", content: "working_hours:\n  work_day: 6h\n", expectedReloaded: true, expectedWorkDay: 6 * time.Hour},
+		{
+			name:            "InvalidChangeKeepsThePreviousConfiguration",
+			content:         "working_hours:\n  work_day: 6 hours\n",
+			expectedError:   "working_hours.work_day: must be a duration like 8h or 7h30m",
+			expectedWorkDay: 6 * time.Hour,
+		},
+		{name: "InvalidFileIsReportedOnce", expectedWorkDay: 6 * time.Hour},
+		{name: "FixedFile", content: "working_hours:\n  work_day: 5h\n", expectedReloaded: true, expectedWorkDay: 5 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.content != "" {
+				write(tt.content)
+			}
+
+			reloaded, err := watcher.Reload()
+
+			assert.Equal(t, tt.expectedReloaded, reloaded)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedWorkDay, watcher.Config().WorkingHours.WorkDay.Duration)
+		})
+	}
+	assert.NoError(t, watcher.LastError())
+}
+
+func TestWatcherRemovedFile(t *testing.T) {
+	configPath := t.TempDir()
+	watcher, err := NewWatcher(configPath)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(configPath, FileName)))
+
+	reloaded, err := watcher.Reload()
+
+	assert.False(t, reloaded)
+	assert.ErrorContains(t, err, "has been removed")
+	assert.ErrorContains(t, watcher.LastError(), "has been removed")
+	assert.Equal(t, GetDefaultConfig().WorkingHours.WorkDay, watcher.Config().WorkingHours.WorkDay)
+	_, err = os.Stat(filepath.Join(configPath, FileName))
+	assert.ErrorIs(t, err, os.ErrNotExist, "a removed file is not replaced by the default configuration")
+}
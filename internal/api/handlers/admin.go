@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/internal/api/middleware"
+	"github.com/jame-developer/aeontrac/internal/appcore"
+)
+
+// ConfigSetting is a setting of the active configuration with where its value comes from.
+type ConfigSetting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Origin string `json:"origin"`
+}
+
+// ConfigResponse describes the active configuration of the server.
+type ConfigResponse struct {
+	File     string          `json:"file"`
+	LoadedAt time.Time       `json:"loaded_at"`
+	Settings []ConfigSetting `json:"settings"`
+	// ReloadError is the error of the last reload, the previous configuration stays active until the file is fixed
+	ReloadError string `json:"reload_error,omitempty"`
+}
+
+// AdminConfigHandler shows the active configuration values with their origins.
+func AdminConfigHandler(c *gin.Context) {
+	loggerIface, exists := c.Get(middleware.LoggerKey)
+	var logger *zap.Logger
+	if exists {
+		if l, ok := loggerIface.(*zap.Logger); ok {
+			logger = l
+		}
+	}
+	if logger == nil {
+		// fallback logger if not found in context
+		logger, _ = zap.NewProduction()
+	}
+
+	snapshot, err := appcore.ConfigSnapshot()
+	if err != nil {
+		logger.Error("Failed to load configuration", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load configuration"})
+		return
+	}
+	response := ConfigResponse{File: snapshot.File, LoadedAt: snapshot.LoadedAt}
+	for _, key := range configuration.Keys() {
+		value, err := configuration.GetValue(snapshot.Config, key)
+		if err != nil {
+			logger.Error("Failed to read setting", zap.String("key", key), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read configuration"})
+			return
+		}
+		response.Settings = append(response.Settings, ConfigSetting{Key: key, Value: value, Origin: snapshot.Origins[key]})
+	}
+	if err = appcore.ConfigReloadError(); err != nil {
+		response.ReloadError = err.Error()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
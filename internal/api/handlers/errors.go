@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/jame-developer/aeontrac/pkg/commands"
+)
+
+// errorStatuses maps the kinds of command errors to HTTP statuses
+var errorStatuses = map[commands.Kind]int{
+	commands.KindInternal:     http.StatusInternalServerError,
+	commands.KindInvalidInput: http.StatusBadRequest,
+	commands.KindConflict:     http.StatusConflict,
+	commands.KindInvalidData:  http.StatusBadRequest,
+	commands.KindUnavailable:  http.StatusServiceUnavailable,
+	commands.KindNotFound:     http.StatusNotFound,
+}
+
+// respondError responds to a failed command with the status of its kind of error.
+// Internal errors are only logged, their message is not meant for clients.
+func respondError(c *gin.Context, logger *zap.Logger, err error) {
+	kind := commands.KindOf(err)
+	status := errorStatuses[kind]
+	if kind == commands.KindInternal {
+		logger.Error("Failed to update app", zap.Error(err))
+		c.JSON(status, gin.H{"error": "Failed to update app"})
+		return
+	}
+	logger.Warn("Request failed", zap.Stringer("kind", kind), zap.Error(err))
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/jame-developer/aeontrac/configuration"
 	"github.com/jame-developer/aeontrac/internal/api/middleware"
 	"github.com/jame-developer/aeontrac/internal/appcore"
 	"github.com/jame-developer/aeontrac/pkg/commands"
+	"github.com/jame-developer/aeontrac/pkg/journal"
+	"github.com/jame-developer/aeontrac/pkg/models"
 )
 
 func StartHandler(c *gin.Context) {
@@ -31,24 +35,21 @@ func StartHandler(c *gin.Context) {
 		return
 	}
 
-	config, data, dataFolder, err := appcore.LoadApp()
-	if err != nil {
-		logger.Error("Failed to load app", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load app"})
-		return
-	}
-
 	args := []string{}
 	if req.Time != nil && *req.Time != "" {
 		args = append(args, *req.Time)
 	}
 
-	commands.StartCommand(args, data)
-	if err := appcore.SaveApp(config, data, dataFolder); err != nil {
-		logger.Error("Failed to save app", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save app"})
+	var result commands.UnitResult
+	err := appcore.UpdateApp(journal.SourceAPI, "start", strings.Join(args, " "), func(config *configuration.Config, data *models.AeonVault) error {
+		var err error
+		result, err = commands.StartCommand(args, config.WorkingHours, data)
+		return err
+	})
+	if err != nil {
+		respondError(c, logger, err)
 		return
 	}
 
-	c.String(http.StatusOK, "Time tracking started successfully.")
+	c.JSON(http.StatusOK, UnitResponse{Status: "started", Message: "Time tracking started successfully.", UnitResult: result})
 }
@@ -1,6 +1,15 @@
 package handlers
 
+import "github.com/jame-developer/aeontrac/pkg/commands"
+
 // TimeRequest defines the structure for time-related requests.
 type TimeRequest struct {
 	Time *string `json:"time"`
-}
\ No newline at end of file
+}
+
+// UnitResponse is the response to starting and stopping time tracking, with the unit of work and the hours of its day.
+type UnitResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	commands.UnitResult
+}
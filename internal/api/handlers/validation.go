@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"github.com/go-playground/validator/v10"
+
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+)
+
+// payloadValidator validates request payloads with the same cross-field validations as the time tracking data.
+// The validations are registered once, validating is safe for concurrent requests.
+var payloadValidator = newPayloadValidator()
+
+func newPayloadValidator() *validator.Validate {
+	v := validator.New()
+	repositories.RegisterValidations(v)
+	return v
+}
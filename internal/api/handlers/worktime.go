@@ -4,8 +4,12 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/jame-developer/aeontrac/internal/api/middleware"
 	"github.com/jame-developer/aeontrac/internal/service"
 	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
 )
 
 // AddWorkTimeHandler handles the addition of a new work time entry.
@@ -15,10 +19,25 @@ func AddWorkTimeHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
+	if err := repositories.ValidateWorkTimeRequest(req, payloadValidator); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	aeonUnit, err := service.AddWorkTimeEntry(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		loggerIface, exists := c.Get(middleware.LoggerKey)
+		var logger *zap.Logger
+		if exists {
+			if l, ok := loggerIface.(*zap.Logger); ok {
+				logger = l
+			}
+		}
+		if logger == nil {
+			// fallback logger if not found in context
+			logger, _ = zap.NewProduction()
+		}
+		respondError(c, logger, err)
 		return
 	}
 
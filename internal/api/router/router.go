@@ -21,6 +21,7 @@ func SetupRouter(logger *zap.Logger) *gin.Engine {
 	r.POST("/stop", handlers.StopHandler)
 	r.POST("/worktime", handlers.AddWorkTimeHandler)
 	r.GET("/status", handlers.StatusHandler)
+	r.GET("/admin/config", handlers.AdminConfigHandler)
 
 	r.LoadHTMLGlob("web/templates/*")
 	r.GET("/", func(c *gin.Context) {
@@ -2,18 +2,57 @@
 package appcore
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/jame-developer/aeontrac/configuration"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/eventlog"
+	"github.com/jame-developer/aeontrac/pkg/filelock"
+	"github.com/jame-developer/aeontrac/pkg/journal"
 	"github.com/jame-developer/aeontrac/pkg/models"
 	"github.com/jame-developer/aeontrac/pkg/repositories"
 )
 
-var testDir string
+// Warnings receives the warnings of loading the AeonVault data, which do not stop a command.
+var Warnings io.Writer = os.Stderr
+
+// Environment variables overriding the folders of the application, flags take precedence
+const (
+	// ConfigDirEnv is the environment variable naming the configuration folder
+	ConfigDirEnv = "AEONTRAC_CONFIG_DIR"
+	// DataDirEnv is the environment variable naming the data folder
+	DataDirEnv = "AEONTRAC_DATA_DIR"
+	// VaultEnv is the environment variable naming the folder of the time tracking data
+	VaultEnv = "AEONTRAC_VAULT"
+)
+
+// Folders overrides the folders of the application, empty fields keep the environment or default folder.
+type Folders struct {
+	// ConfigDir is the configuration folder, by default aeontrac in XDG_CONFIG_HOME
+	ConfigDir string
+	// DataDir is the data folder, by default aeontrac in XDG_DATA_HOME
+	DataDir string
+	// Vault is the folder of the time tracking data with its backups and journal, by default the data folder
+	Vault string
+	// Profile is the name of the profile, by default the profile selected with UseProfile
+	Profile string
+}
+
+var (
+	testDir string
+	folders Folders
+)
+
+// SetFolders overrides the folders of the application, usually with the values of command line flags.
+func SetFolders(f Folders) {
+	folders = f
+}
 
 // SetTestDir sets a temporary directory for testing purposes.
 func SetTestDir(dir string) {
@@ -22,31 +61,351 @@ func SetTestDir(dir string) {
 
 // LoadApp loads the configuration and AeonVault data, returning config, data, dataFolder, and error.
 func LoadApp() (*configuration.Config, *models.AeonVault, string, error) {
+	return loadApp(validator.New())
+}
+
+// LoadAppForRepair loads the configuration and AeonVault data like LoadApp, but without validating the data,
+// so inconsistencies the validation rejects can be repaired.
+func LoadAppForRepair() (*configuration.Config, *models.AeonVault, string, error) {
+	return loadApp(nil)
+}
+
+// LoadAppReadOnly loads the configuration and AeonVault data for reading without the lock, for commands which only read
+// the data like reports. It neither creates missing data nor loads public holidays of new years, the data must not be saved.
+func LoadAppReadOnly() (*configuration.Config, *models.AeonVault, string, error) {
+	config, repository, dataFolder, err := openReadOnly()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer func(repository repositories.VaultRepository) {
+		_ = repository.Close()
+	}(repository)
+	data, err := repository.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return config, &models.AeonVault{Days: map[string]*models.AeonDay{}}, dataFolder, nil
+	}
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("error loading time tracking data: %w", err)
+	}
+	return config, &data, dataFolder, nil
+}
+
+// LoadDaysReadOnly loads the configuration, the running unit and its day and the days from the first to the last day key
+// returned by days for the current time in the configured location, for reading without the lock like LoadAppReadOnly.
+// The sqlite backend reads only these days, so the status line and the dashboard do not read years of data.
+// The data contains no other days and must not be saved.
+func LoadDaysReadOnly(days func(now time.Time) (fromDayKey, toDayKey string)) (*configuration.Config, *models.AeonVault, string, error) {
+	config, repository, dataFolder, err := openReadOnly()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer func(repository repositories.VaultRepository) {
+		_ = repository.Close()
+	}(repository)
+	fromDayKey, toDayKey := days(time.Now().In(config.WorkingHours.Location()))
+	data, err := loadDays(repository, fromDayKey, toDayKey)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, &models.AeonVault{Days: map[string]*models.AeonDay{}}, dataFolder, nil
+	}
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("error loading time tracking data: %w", err)
+	}
+	return config, data, dataFolder, nil
+}
+
+// loadDays reads the running unit and its day and the days from the first to the last day key
+func loadDays(repository repositories.VaultRepository, fromDayKey, toDayKey string) (*models.AeonVault, error) {
+	days, err := repository.DaysInRange(fromDayKey, toDayKey)
+	if err != nil {
+		return nil, err
+	}
+	running, err := repository.RunningUnit()
+	if err != nil {
+		return nil, err
+	}
+	if running != nil && days[running.DayKey] == nil {
+		day, err := repository.Day(running.DayKey)
+		if err == nil {
+			days[running.DayKey] = day
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return &models.AeonVault{Days: days, CurrentRunningUnit: running}, nil
+}
+
+// openReadOnly loads the configuration and opens the repository of the data folder, whose data is not validated
+func openReadOnly() (*configuration.Config, repositories.VaultRepository, string, error) {
 	configFolder, dataFolder, err := getAppFolders()
 	if err != nil {
 		return nil, nil, "", fmt.Errorf("error getting application folders: %w", err)
 	}
+	config, err := loadConfig(configFolder)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("error loading configuration: %w", err)
+	}
+	key, err := vaultKeyFor(dataFolder)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	repository, err := repositories.NewVaultRepository(dataFolder, config.Storage, nil, key)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return config, repository, dataFolder, nil
+}
 
-	config, err := configuration.LoadConfig(configFolder)
+// loadApp loads the configuration and AeonVault data, validating the data with the validator unless it is nil
+func loadApp(valdtr *validator.Validate) (*configuration.Config, *models.AeonVault, string, error) {
+	configFolder, dataFolder, err := getAppFolders()
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("error getting application folders: %w", err)
+	}
+
+	config, err := loadConfig(configFolder)
 	if err != nil {
 		return nil, nil, "", fmt.Errorf("error loading configuration: %w", err)
 	}
 
-	valdtr := validator.New()
-	data, err := repositories.LoadAeonVault(dataFolder, valdtr)
+	key, err := vaultKeyFor(dataFolder)
 	if err != nil {
-		newData, err2 := repositories.NewAeonVault(time.Now().Year(), config.PublicHolidays)
-		if err2 != nil {
-			return nil, nil, "", fmt.Errorf("error creating new time tracking data: %w", err2)
+		return nil, nil, "", err
+	}
+	repository, err := repositories.NewVaultRepository(dataFolder, config.Storage, valdtr, key)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer func(repository repositories.VaultRepository) {
+		_ = repository.Close()
+	}(repository)
+	data, err := repository.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		data = models.AeonVault{Days: map[string]*models.AeonDay{}}
+		err = ensureYears(&data, config.PublicHolidays, time.Now())
+		if err != nil {
+			fmt.Fprintf(Warnings, "Warning: %v, public holidays are marked once they can be loaded\n", err)
+		}
+		// Save to ensure the data file exists, existing data must not be overwritten by a reading process
+		data.CommandOperation = "init"
+		if err = repository.Save(data); err != nil {
+			return nil, nil, "", fmt.Errorf("error creating new time tracking data: %w", err)
 		}
-		data = newData
+	} else if errors.Is(err, aeonerrors.ErrInvalidData) {
+		return nil, nil, "", fmt.Errorf("error loading time tracking data: %w, check and repair it with 'fsck --repair'", err)
+	} else if err != nil {
+		return nil, nil, "", fmt.Errorf("error loading time tracking data: %w", err)
+	} else if err = ensureYears(&data, config.PublicHolidays, time.Now()); err != nil {
+		fmt.Fprintf(Warnings, "Warning: %v, public holidays are marked once they can be loaded\n", err)
 	}
-	// Save to ensure the data file exists
-	_ = repositories.SaveAeonVault(dataFolder, data)
 
 	return config, &data, dataFolder, nil
 }
 
+// ensureYears creates the days of the current year, and during December those of the next year, when they are used for the first time.
+// The changes are kept in memory and saved by the next load-modify-save cycle.
+func ensureYears(data *models.AeonVault, publicHolidaysConfig configuration.PublicHolidaysConfig, now time.Time) error {
+	years := []int{now.Year()}
+	if now.Month() == time.December {
+		years = append(years, now.Year()+1)
+	}
+	var errs []error
+	for _, year := range years {
+		errs = append(errs, repositories.EnsureYear(data, year, publicHolidaysConfig))
+	}
+	return errors.Join(errs...)
+}
+
+// LockApp takes the lock on the data folder, which must be held for every load-modify-save cycle of the AeonVault data.
+// It waits up to the lock timeout of the configuration for other processes to release the lock.
+func LockApp() (*filelock.Lock, error) {
+	configFolder, dataFolder, err := getAppFolders()
+	if err != nil {
+		return nil, fmt.Errorf("error getting application folders: %w", err)
+	}
+	config, err := loadConfig(configFolder)
+	if err != nil {
+		return nil, fmt.Errorf("error loading configuration: %w", err)
+	}
+	timeout := configuration.GetDefaultLockConfig().Timeout.Duration
+	if config.Lock.Timeout != nil {
+		timeout = config.Lock.Timeout.Duration
+	}
+
+	return filelock.Acquire(dataFolder, timeout)
+}
+
+// UpdateApp runs a load-modify-save cycle of the AeonVault data while holding the lock on the data folder.
+// If update succeeds, the data is saved and the changes are recorded as operation in the operation journal.
+func UpdateApp(source, operation, arguments string, update func(config *configuration.Config, data *models.AeonVault) error) error {
+	lock, err := LockApp()
+	if err != nil {
+		return err
+	}
+	defer func(lock *filelock.Lock) {
+		_ = lock.Release()
+	}(lock)
+
+	config, data, dataFolder, err := LoadApp()
+	if err != nil {
+		return err
+	}
+	before, err := journal.Capture(data)
+	if err != nil {
+		return fmt.Errorf("error capturing vault state: %w", err)
+	}
+	if err = update(config, data); err != nil {
+		return err
+	}
+	if err = repositories.ValidateAeonVault(data, validator.New()); err != nil {
+		return err
+	}
+	data.CommandOperation = operation
+	if err = SaveApp(config, data, dataFolder); err != nil {
+		return err
+	}
+
+	return recordOperation(dataFolder, source, operation, arguments, before, data)
+}
+
+// MigrateStorage copies the AeonVault data to the provided storage backend and switches the configuration to it.
+// Existing data of the target backend is only replaced if force is set, the data of the previous backend is kept.
+func MigrateStorage(backend string, force bool) error {
+	lock, err := LockApp()
+	if err != nil {
+		return err
+	}
+	defer func(lock *filelock.Lock) {
+		_ = lock.Release()
+	}(lock)
+
+	configFolder, dataFolder, err := getAppFolders()
+	if err != nil {
+		return fmt.Errorf("error getting application folders: %w", err)
+	}
+	config, err := configuration.LoadConfig(configFolder)
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+	if config.Storage.Backend == backend {
+		return fmt.Errorf("the time tracking data is already stored in the %s backend", backend)
+	}
+
+	key, err := vaultKeyFor(dataFolder)
+	if err != nil {
+		return err
+	}
+	valdtr := validator.New()
+	source, err := repositories.NewVaultRepository(dataFolder, config.Storage, valdtr, key)
+	if err != nil {
+		return err
+	}
+	defer func(source repositories.VaultRepository) {
+		_ = source.Close()
+	}(source)
+	target, err := repositories.NewVaultRepository(dataFolder, configuration.StorageConfig{Backend: backend}, valdtr, key)
+	if err != nil {
+		return err
+	}
+	defer func(target repositories.VaultRepository) {
+		_ = target.Close()
+	}(target)
+
+	data, err := source.Load()
+	if err != nil {
+		return fmt.Errorf("error loading time tracking data: %w", err)
+	}
+	exists, err := target.Exists()
+	if err != nil {
+		return err
+	}
+	if exists && !force {
+		return fmt.Errorf("the %s backend already contains time tracking data, use --force to replace it", backend)
+	}
+	data.CommandOperation = "storage migrate"
+	if err = target.Save(data); err != nil {
+		return fmt.Errorf("error saving time tracking data: %w", err)
+	}
+
+	// The environment overrides are not saved
+	fileConfig, err := configuration.LoadConfigFile(configFolder)
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+	fileConfig.Storage.Backend = backend
+	if err = configuration.SaveConfig(configFolder, fileConfig); err != nil {
+		return fmt.Errorf("error saving configuration: %w", err)
+	}
+	return nil
+}
+
+// UpgradeStorage upgrades the AeonVault data to the current schema version, keeping a backup of the original data.
+// If dryRun is set, the upgrade is only described. Data is upgraded in memory on every load as well, this makes it permanent.
+func UpgradeStorage(dryRun bool) (repositories.SchemaUpgrade, error) {
+	lock, err := LockApp()
+	if err != nil {
+		return repositories.SchemaUpgrade{}, err
+	}
+	defer func(lock *filelock.Lock) {
+		_ = lock.Release()
+	}(lock)
+
+	configFolder, dataFolder, err := getAppFolders()
+	if err != nil {
+		return repositories.SchemaUpgrade{}, fmt.Errorf("error getting application folders: %w", err)
+	}
+	config, err := configuration.LoadConfig(configFolder)
+	if err != nil {
+		return repositories.SchemaUpgrade{}, fmt.Errorf("error loading configuration: %w", err)
+	}
+	if config.Storage.Backend != "" && config.Storage.Backend != repositories.JSONBackend {
+		return repositories.SchemaUpgrade{}, fmt.Errorf("the %s backend is upgraded whenever the time tracking data is saved", config.Storage.Backend)
+	}
+
+	key, err := vaultKeyFor(dataFolder)
+	if err != nil {
+		return repositories.SchemaUpgrade{}, err
+	}
+
+	return repositories.UpgradeAeonVault(dataFolder, validator.New(), key, dryRun)
+}
+
+// LoadAppAsOf rebuilds the AeonVault data as it was at the provided time, which requires the event log storage backend.
+func LoadAppAsOf(config *configuration.Config, dataFolder string, asOf time.Time) (*models.AeonVault, error) {
+	repository, err := eventLogRepository(config, dataFolder)
+	if err != nil {
+		return nil, err
+	}
+	data, err := repository.LoadAsOf(asOf)
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// ListEvents returns all events recorded in the event log, which requires the event log storage backend.
+func ListEvents(config *configuration.Config, dataFolder string) ([]eventlog.Event, error) {
+	repository, err := eventLogRepository(config, dataFolder)
+	if err != nil {
+		return nil, err
+	}
+	return repository.Events()
+}
+
+// eventLogRepository returns the repository of the event log, if it is the configured storage backend
+func eventLogRepository(config *configuration.Config, dataFolder string) (*repositories.EventLogVaultRepository, error) {
+	if config.Storage.Backend != repositories.EventLogBackend {
+		return nil, fmt.Errorf("the history of the time tracking data is only recorded by the %s backend, switch with 'storage migrate --to %s'",
+			repositories.EventLogBackend, repositories.EventLogBackend)
+	}
+	return repositories.NewEventLogVaultRepository(dataFolder, validator.New(), config.Storage.SnapshotInterval), nil
+}
+
+// DataFolder returns the data folder of the application.
+func DataFolder() (string, error) {
+	_, dataFolder, err := getAppFolders()
+	return dataFolder, err
+}
+
 // getXDGPath returns the path for the given environment variable or the fallback value
 func getXDGPath(envVar string, fallback string) string {
 	value, exists := os.LookupEnv(envVar)
@@ -56,18 +415,62 @@ func getXDGPath(envVar string, fallback string) string {
 	return value
 }
 
-// getAppFolders returns the configuration and data folders for the application
+// firstFolder returns the first folder which is set
+func firstFolder(candidates ...string) string {
+	for _, candidate := range candidates {
+		if candidate != "" {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// getAppFolders returns the configuration and data folders of the active profile
 func getAppFolders() (configFolder, dataFolder string, err error) {
+	profile, err := ActiveProfile()
+	if err != nil {
+		return "", "", err
+	}
+	exists, err := profileExists(profile)
+	if err != nil {
+		return "", "", err
+	}
+	if !exists {
+		return "", "", fmt.Errorf("the profile %s does not exist, create it with 'profile create %s'", profile, profile)
+	}
+	return profileFolders(profile, true)
+}
+
+// rootFolders returns the configuration and data folders of the default profile, the folders of named profiles are below them
+func rootFolders() (configRoot, dataRoot string, err error) {
 	if testDir != "" {
-		configFolder = filepath.Join(testDir, "config")
-		dataFolder = filepath.Join(testDir, "data")
-	} else {
-		configPath := getXDGPath("XDG_CONFIG_HOME", ".config")
-		dataPath := getXDGPath("XDG_DATA_HOME", ".local/share")
+		return filepath.Join(testDir, "config"), filepath.Join(testDir, "data"), nil
+	}
+	configPath := getXDGPath("XDG_CONFIG_HOME", ".config")
+	dataPath := getXDGPath("XDG_DATA_HOME", ".local/share")
 
-		appName := "aeontrac"
-		configFolder = filepath.Join(configPath, appName)
-		dataFolder = filepath.Join(dataPath, appName)
+	appName := "aeontrac"
+	if configRoot, err = filepath.Abs(firstFolder(folders.ConfigDir, os.Getenv(ConfigDirEnv), filepath.Join(configPath, appName))); err != nil {
+		return "", "", err
+	}
+	dataRoot, err = filepath.Abs(firstFolder(folders.DataDir, os.Getenv(DataDirEnv), filepath.Join(dataPath, appName)))
+	return configRoot, dataRoot, err
+}
+
+// profileFolders returns the configuration and data folders of a profile, creating them if needed.
+// The vault folder of the flags or environment replaces the data folder if withVault is set.
+func profileFolders(profile string, withVault bool) (configFolder, dataFolder string, err error) {
+	if configFolder, dataFolder, err = rootFolders(); err != nil {
+		return
+	}
+	if profile != DefaultProfile {
+		configFolder = filepath.Join(configFolder, profilesFolderName, profile)
+		dataFolder = filepath.Join(dataFolder, profilesFolderName, profile)
+	}
+	if vault := firstFolder(folders.Vault, os.Getenv(VaultEnv)); withVault && vault != "" {
+		if dataFolder, err = filepath.Abs(vault); err != nil {
+			return
+		}
 	}
 
 	if err = os.MkdirAll(configFolder, 0755); err != nil {
@@ -80,10 +483,41 @@ func getAppFolders() (configFolder, dataFolder string, err error) {
 	return
 }
 
-// SaveApp saves the configuration and AeonVault data.
+// SaveApp saves the configuration and AeonVault data, keeping a backup of the previous data.
 func SaveApp(config *configuration.Config, data *models.AeonVault, dataFolder string) error {
-	if err := repositories.SaveAeonVault(dataFolder, *data); err != nil {
+	key, err := vaultKeyFor(dataFolder)
+	if err != nil {
+		return err
+	}
+	// The previous data is only read to back it up, it may be inconsistent data being repaired
+	repository, err := repositories.NewVaultRepository(dataFolder, config.Storage, nil, key)
+	if err != nil {
+		return err
+	}
+	defer func(repository repositories.VaultRepository) {
+		_ = repository.Close()
+	}(repository)
+	if err = repositories.SaveVaultWithBackup(repository, dataFolder, *data, config.Backup); err != nil {
 		return fmt.Errorf("error saving time tracking data: %w", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// recordOperation records the changes made to the vault since the before state in the operation journal of the data folder.
+func recordOperation(dataFolder, source, operation, arguments string, before journal.State, data *models.AeonVault) error {
+	key, err := vaultKeyFor(dataFolder)
+	if err != nil {
+		return err
+	}
+	operations, err := journal.Load(dataFolder, key)
+	if err != nil {
+		return fmt.Errorf("error loading operation journal: %w", err)
+	}
+	if err = operations.Record(source, operation, arguments, before, data); err != nil {
+		return fmt.Errorf("error recording operation: %w", err)
+	}
+	if err = journal.Save(dataFolder, operations, key); err != nil {
+		return fmt.Errorf("error saving operation journal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package appcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/filelock"
+)
+
+// configWatcher holds the configuration of a long running process in memory, without it the configuration is loaded on every use
+var configWatcher *configuration.Watcher
+
+// WatchConfig holds the configuration of the active profile in memory for LoadApp and UpdateApp, and reloads it every
+// interval when its file has changed, until the context is done. onReload is called after every reload and failed reload,
+// an invalid configuration keeps the previous one. It must be called before the configuration is used concurrently.
+func WatchConfig(ctx context.Context, interval time.Duration, onReload func(snapshot *configuration.Snapshot, err error)) error {
+	configFolder, _, err := getAppFolders()
+	if err != nil {
+		return fmt.Errorf("error getting application folders: %w", err)
+	}
+	watcher, err := configuration.NewWatcher(configFolder)
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+	configWatcher = watcher
+	go watcher.Watch(ctx, interval, onReload)
+	return nil
+}
+
+// ConfigSnapshot returns the configuration in use with the origins of its values.
+// Without WatchConfig, the configuration is loaded from the configuration folder.
+func ConfigSnapshot() (*configuration.Snapshot, error) {
+	if configWatcher != nil {
+		return configWatcher.Snapshot(), nil
+	}
+	configFolder, _, err := getAppFolders()
+	if err != nil {
+		return nil, fmt.Errorf("error getting application folders: %w", err)
+	}
+	config, origins, err := configuration.LoadLayeredConfig(configFolder)
+	if err != nil {
+		return nil, fmt.Errorf("error loading configuration: %w", err)
+	}
+	configFile, err := configuration.FilePath(configFolder)
+	if err != nil {
+		return nil, err
+	}
+	return &configuration.Snapshot{Config: config, Origins: origins, File: configFile, LoadedAt: time.Now()}, nil
+}
+
+// ConfigReloadError returns the error of the last reload of the watched configuration, nil without WatchConfig.
+func ConfigReloadError() error {
+	if configWatcher == nil {
+		return nil
+	}
+	return configWatcher.LastError()
+}
+
+// ConfigFile returns the configuration file of the active profile, the default configuration is written if none exists.
+func ConfigFile() (string, error) {
+	configFolder, _, err := getAppFolders()
+	if err != nil {
+		return "", fmt.Errorf("error getting application folders: %w", err)
+	}
+	configFile, err := configuration.FilePath(configFolder)
+	if err != nil {
+		return "", err
+	}
+	if _, err = os.Stat(configFile); errors.Is(err, os.ErrNotExist) {
+		defaultConfig := configuration.GetDefaultConfig()
+		err = configuration.SaveConfig(configFolder, &defaultConfig)
+	}
+	return configFile, err
+}
+
+// LoadConfig loads the configuration of the active profile with the environment overrides,
+// and returns where the value of each setting comes from. The default configuration is written if none exists.
+func LoadConfig() (*configuration.Config, configuration.Origins, error) {
+	configFolder, _, err := getAppFolders()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting application folders: %w", err)
+	}
+	config, origins, err := configuration.LoadLayeredConfig(configFolder)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading configuration: %w", err)
+	}
+	return config, origins, nil
+}
+
+// ValidateConfigFile checks the configuration file of the active profile and the environment overrides without changing them.
+func ValidateConfigFile() (string, error) {
+	configFolder, _, err := getAppFolders()
+	if err != nil {
+		return "", fmt.Errorf("error getting application folders: %w", err)
+	}
+	configFile, err := configuration.FilePath(configFolder)
+	if err != nil {
+		return "", err
+	}
+	_, _, err = configuration.LoadLayeredConfig(configFolder)
+	return configFile, err
+}
+
+// UpdateConfig changes the configuration file of the active profile, it is validated before it is saved.
+// The environment overrides are not saved. The storage backend is only changed by MigrateStorage,
+// which moves the time tracking data as well.
+func UpdateConfig(update func(config *configuration.Config) error) error {
+	lock, err := LockApp()
+	if err != nil {
+		return err
+	}
+	defer func(lock *filelock.Lock) {
+		_ = lock.Release()
+	}(lock)
+
+	configFolder, _, err := getAppFolders()
+	if err != nil {
+		return fmt.Errorf("error getting application folders: %w", err)
+	}
+	config, err := configuration.LoadConfigFile(configFolder)
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+	backend := config.Storage.Backend
+	if err = update(config); err != nil {
+		return err
+	}
+	return saveConfig(configFolder, backend, config)
+}
+
+// ReplaceConfig replaces the configuration file of the active profile, it is validated before it is saved.
+// Unlike UpdateConfig, it replaces invalid configuration files as well.
+func ReplaceConfig(config *configuration.Config) error {
+	configFolder, dataFolder, err := getAppFolders()
+	if err != nil {
+		return fmt.Errorf("error getting application folders: %w", err)
+	}
+	// The backend and lock timeout of an invalid configuration file are unknown, fixing the file must stay possible
+	backend := config.Storage.Backend
+	timeout := configuration.GetDefaultLockConfig().Timeout.Duration
+	if previous, err := configuration.LoadConfigFile(configFolder); err == nil {
+		backend = previous.Storage.Backend
+		if previous.Lock.Timeout != nil {
+			timeout = previous.Lock.Timeout.Duration
+		}
+	}
+	lock, err := filelock.Acquire(dataFolder, timeout)
+	if err != nil {
+		return err
+	}
+	defer func(lock *filelock.Lock) {
+		_ = lock.Release()
+	}(lock)
+
+	return saveConfig(configFolder, backend, config)
+}
+
+// saveConfig validates and saves the configuration, if it keeps the storage backend
+func saveConfig(configFolder, backend string, config *configuration.Config) error {
+	if config.Storage.Backend != backend {
+		return fmt.Errorf("the storage backend is changed with 'storage migrate --to %s', which moves the time tracking data", config.Storage.Backend)
+	}
+	if err := configuration.ValidateConfig(config); err != nil {
+		return fmt.Errorf("the configuration is not saved:\n%w", err)
+	}
+	if err := configuration.SaveConfig(configFolder, config); err != nil {
+		return fmt.Errorf("error saving configuration: %w", err)
+	}
+	return nil
+}
+
+// loadConfig returns the configuration held by WatchConfig, or loads it from the configuration folder
+func loadConfig(configFolder string) (*configuration.Config, error) {
+	if configWatcher != nil {
+		return configWatcher.Config(), nil
+	}
+	return configuration.LoadConfig(configFolder)
+}
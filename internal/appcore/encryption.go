@@ -0,0 +1,157 @@
+package appcore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jame-developer/aeontrac/configuration"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/filelock"
+	"github.com/jame-developer/aeontrac/pkg/journal"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
+)
+
+const (
+	// PassphraseEnv is the environment variable holding the passphrase of the encrypted AeonVault data.
+	PassphraseEnv = "AEONTRAC_PASSPHRASE"
+	// KeyFileEnv is the environment variable holding the path of the keyfile of the encrypted AeonVault data.
+	KeyFileEnv = "AEONTRAC_KEYFILE"
+)
+
+var (
+	vaultKey *vaultcrypt.Key
+	// PassphrasePrompt asks the user for the passphrase of the encrypted AeonVault data, nil if the user cannot be asked.
+	PassphrasePrompt func() (string, error)
+)
+
+// SetVaultKey sets the key of the encrypted AeonVault data, which takes precedence over the environment.
+func SetVaultKey(key *vaultcrypt.Key) {
+	vaultKey = key
+}
+
+// KeyFromEnvironment returns the key provided by the keyfile or passphrase environment variable, nil if neither is set.
+func KeyFromEnvironment() (*vaultcrypt.Key, error) {
+	if keyFile := os.Getenv(KeyFileEnv); keyFile != "" {
+		return vaultcrypt.LoadKeyFile(keyFile)
+	}
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return vaultcrypt.NewPassphraseKey(passphrase)
+	}
+	return nil, nil
+}
+
+// VaultKey returns the key of the AeonVault data, nil if the data is not encrypted.
+// The key is taken from SetVaultKey, the environment or the passphrase prompt, in this order, and kept for later calls.
+func VaultKey() (*vaultcrypt.Key, error) {
+	dataFolder, err := DataFolder()
+	if err != nil {
+		return nil, err
+	}
+	return vaultKeyFor(dataFolder)
+}
+
+// EncryptVault encrypts the AeonVault data, all of its backups and the operation journal with the key.
+func EncryptVault(key *vaultcrypt.Key) error {
+	return reencryptVault(func(oldKey *vaultcrypt.Key) (*vaultcrypt.Key, error) {
+		if oldKey != nil {
+			return nil, errors.New("the time tracking data is already encrypted, use rekey to change the key")
+		}
+		return key, nil
+	})
+}
+
+// DecryptVault decrypts the AeonVault data, all of its backups and the operation journal.
+func DecryptVault() error {
+	return reencryptVault(func(oldKey *vaultcrypt.Key) (*vaultcrypt.Key, error) {
+		if oldKey == nil {
+			return nil, errors.New("the time tracking data is not encrypted")
+		}
+		return nil, nil
+	})
+}
+
+// RekeyVault encrypts the AeonVault data, all of its backups and the operation journal with a new key.
+func RekeyVault(key *vaultcrypt.Key) error {
+	return reencryptVault(func(oldKey *vaultcrypt.Key) (*vaultcrypt.Key, error) {
+		if oldKey == nil {
+			return nil, errors.New("the time tracking data is not encrypted, use encrypt to encrypt it")
+		}
+		return key, nil
+	})
+}
+
+// reencryptVault re-encrypts all files holding time tracking data while holding the lock on the data folder.
+// newKeyFor returns the new key for the current key, nil for plaintext.
+func reencryptVault(newKeyFor func(oldKey *vaultcrypt.Key) (*vaultcrypt.Key, error)) error {
+	lock, err := LockApp()
+	if err != nil {
+		return err
+	}
+	defer func(lock *filelock.Lock) {
+		_ = lock.Release()
+	}(lock)
+
+	configFolder, dataFolder, err := getAppFolders()
+	if err != nil {
+		return fmt.Errorf("error getting application folders: %w", err)
+	}
+	config, err := configuration.LoadConfig(configFolder)
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+	if config.Storage.Backend != "" && config.Storage.Backend != repositories.JSONBackend {
+		return fmt.Errorf("the %s backend does not support encryption", config.Storage.Backend)
+	}
+	exists, err := repositories.NewJSONVaultRepository(dataFolder, nil).Exists()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("no time tracking data has been stored yet")
+	}
+	oldKey, err := vaultKeyFor(dataFolder)
+	if err != nil {
+		return err
+	}
+	newKey, err := newKeyFor(oldKey)
+	if err != nil {
+		return err
+	}
+
+	if err = repositories.ReencryptAeonVault(dataFolder, oldKey, newKey, journal.FilePath(dataFolder)); err != nil {
+		return err
+	}
+	vaultKey = newKey
+	return nil
+}
+
+// vaultKeyFor returns the key of the AeonVault data in the data folder, nil if the data is not encrypted
+func vaultKeyFor(dataFolder string) (*vaultcrypt.Key, error) {
+	encrypted, err := repositories.IsAeonVaultEncrypted(dataFolder)
+	if err != nil || !encrypted {
+		return nil, err
+	}
+	if vaultKey != nil {
+		return vaultKey, nil
+	}
+	key, err := KeyFromEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil && PassphrasePrompt != nil {
+		passphrase, err := PassphrasePrompt()
+		if err != nil {
+			return nil, err
+		}
+		if key, err = vaultcrypt.NewPassphraseKey(passphrase); err != nil {
+			return nil, err
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w, set %s or %s", aeonerrors.ErrVaultLocked, PassphraseEnv, KeyFileEnv)
+	}
+	vaultKey = key
+	return key, nil
+}
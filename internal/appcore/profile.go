@@ -0,0 +1,215 @@
+package appcore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/filelock"
+	"github.com/jame-developer/aeontrac/pkg/profile"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+)
+
+// previousProfilePrefix is the prefix of the archives of the profile replaced by an import, they are kept in the data folder
+const previousProfilePrefix = "profile-before-import-"
+
+// ExportProfile writes the configuration, the AeonVault data, its backups and journal to a profile archive.
+func ExportProfile(fileName string) (profile.Manifest, error) {
+	lock, err := LockApp()
+	if err != nil {
+		return profile.Manifest{}, err
+	}
+	defer func(lock *filelock.Lock) {
+		_ = lock.Release()
+	}(lock)
+
+	return exportProfile(fileName)
+}
+
+// ImportProfile replaces the configuration and the AeonVault data with those of a profile archive.
+// The archive is verified against its manifest, and its configuration and data must load, before anything is replaced.
+// Existing data is only replaced if force is set, the replaced profile is kept as archive in the data folder, its path is returned.
+func ImportProfile(fileName string, force bool) (profile.Manifest, string, error) {
+	lock, err := LockApp()
+	if err != nil {
+		return profile.Manifest{}, "", err
+	}
+	defer func(lock *filelock.Lock) {
+		_ = lock.Release()
+	}(lock)
+
+	configFolder, dataFolder, err := getAppFolders()
+	if err != nil {
+		return profile.Manifest{}, "", fmt.Errorf("error getting application folders: %w", err)
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		return profile.Manifest{}, "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	staging, err := os.MkdirTemp("", "aeontrac-profile-")
+	if err != nil {
+		return profile.Manifest{}, "", err
+	}
+	defer func(staging string) {
+		_ = os.RemoveAll(staging)
+	}(staging)
+	manifest, err := profile.Extract(file, staging)
+	if err != nil {
+		return profile.Manifest{}, "", fmt.Errorf("invalid profile archive: %w", err)
+	}
+	stagedConfig, stagedData := filepath.Join(staging, profile.ConfigFolder), filepath.Join(staging, profile.DataFolder)
+	if err = validateProfile(stagedConfig, stagedData); err != nil {
+		return profile.Manifest{}, "", fmt.Errorf("invalid profile archive: %w", err)
+	}
+
+	exists, err := hasProfileData(dataFolder)
+	if err != nil {
+		return profile.Manifest{}, "", err
+	}
+	if exists && !force {
+		return profile.Manifest{}, "", errors.New("time tracking data already exists, use --force to replace it")
+	}
+	var previous string
+	if exists {
+		previous = filepath.Join(dataFolder, previousProfilePrefix+time.Now().Format("20060102T150405")+".tar.gz")
+		if _, err = exportProfile(previous); err != nil {
+			return profile.Manifest{}, "", fmt.Errorf("error keeping the current profile: %w", err)
+		}
+	}
+
+	if err = clearFolder(dataFolder, func(name string) bool { return keepOnImport(dataFolder, name) }); err != nil {
+		return profile.Manifest{}, previous, err
+	}
+	// The archive may hold the configuration in another format, the folder must not be left with two of them
+	if err = configuration.RemoveFiles(configFolder); err != nil {
+		return profile.Manifest{}, previous, err
+	}
+	if err = copyFolder(stagedConfig, configFolder); err != nil {
+		return profile.Manifest{}, previous, err
+	}
+	return manifest, previous, copyFolder(stagedData, dataFolder)
+}
+
+// exportProfile writes the profile archive, the caller must hold the lock
+func exportProfile(fileName string) (profile.Manifest, error) {
+	configFolder, dataFolder, err := getAppFolders()
+	if err != nil {
+		return profile.Manifest{}, fmt.Errorf("error getting application folders: %w", err)
+	}
+	var archive bytes.Buffer
+	manifest, err := profile.Export(&archive, configFolder, dataFolder, func(relativePath string) bool {
+		return keepOnImport(dataFolder, relativePath) || strings.HasSuffix(relativePath, ".tmp")
+	})
+	if err != nil {
+		return profile.Manifest{}, err
+	}
+	return manifest, repositories.WriteFileAtomic(fileName, archive.Bytes(), 0600)
+}
+
+// validateProfile loads the configuration and the AeonVault data of an extracted profile archive
+func validateProfile(configFolder, dataFolder string) error {
+	configFile, err := configuration.FilePath(configFolder)
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(configFile); err != nil {
+		return fmt.Errorf("the archive contains no configuration: %w", err)
+	}
+	config, err := configuration.LoadConfig(configFolder)
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+	key, err := vaultKeyFor(dataFolder)
+	if err != nil {
+		return err
+	}
+	repository, err := repositories.NewVaultRepository(dataFolder, config.Storage, validator.New(), key)
+	if err != nil {
+		return err
+	}
+	defer func(repository repositories.VaultRepository) {
+		_ = repository.Close()
+	}(repository)
+	if _, err = repository.Load(); err != nil {
+		return fmt.Errorf("error loading time tracking data: %w", err)
+	}
+	return nil
+}
+
+// hasProfileData reports whether the data folder contains files, besides those kept on import
+func hasProfileData(dataFolder string) (bool, error) {
+	entries, err := os.ReadDir(dataFolder)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if !keepOnImport(dataFolder, entry.Name()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// keepOnImport reports whether a file of the configuration or data folder is kept when a profile is imported:
+// the lock, the archives of replaced profiles and the named profiles below the folders of the default profile
+func keepOnImport(folder, relativePath string) bool {
+	return filepath.Join(folder, relativePath) == filelock.FilePath(folder) || strings.HasPrefix(relativePath, previousProfilePrefix) ||
+		relativePath == activeProfileFileName || strings.Split(filepath.ToSlash(relativePath), "/")[0] == profilesFolderName
+}
+
+// clearFolder removes all entries of the folder, except those for which keep returns true
+func clearFolder(folder string, keep func(name string) bool) error {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if keep(entry.Name()) {
+			continue
+		}
+		if err = os.RemoveAll(filepath.Join(folder, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFolder copies all regular files of the source folder into the target folder, keeping their permissions
+func copyFolder(source, target string) error {
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && path == source {
+				return nil
+			}
+			return err
+		}
+		relativePath, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		targetPath := filepath.Join(target, relativePath)
+		if entry.IsDir() {
+			return os.MkdirAll(targetPath, 0755)
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return repositories.WriteFileAtomic(targetPath, data, info.Mode().Perm())
+	})
+}
@@ -0,0 +1,196 @@
+package appcore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+)
+
+const (
+	// ProfileEnv is the environment variable naming the profile, the --profile flag takes precedence
+	ProfileEnv = "AEONTRAC_PROFILE"
+	// DefaultProfile is the profile stored directly in the configuration and data folders
+	DefaultProfile = "default"
+	// profilesFolderName is the folder of the named profiles below the configuration and data folders
+	profilesFolderName = "profiles"
+	// activeProfileFileName is the file in the configuration folder naming the profile selected with UseProfile
+	activeProfileFileName = "active_profile"
+)
+
+var profileNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// ProfileData is the configuration and AeonVault data of a profile.
+type ProfileData struct {
+	Name   string
+	Config *configuration.Config
+	Data   *models.AeonVault
+}
+
+// ActiveProfile returns the name of the active profile: the profile of the flags, the environment,
+// the profile selected with UseProfile or the default profile.
+func ActiveProfile() (string, error) {
+	profile := firstFolder(folders.Profile, os.Getenv(ProfileEnv))
+	if profile == "" {
+		configRoot, _, err := rootFolders()
+		if err != nil {
+			return "", err
+		}
+		selected, err := os.ReadFile(filepath.Join(configRoot, activeProfileFileName))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		profile = strings.TrimSpace(string(selected))
+	}
+	if profile == "" {
+		return DefaultProfile, nil
+	}
+	return profile, validateProfileName(profile)
+}
+
+// ListProfiles returns the names of all profiles, the default profile first.
+func ListProfiles() ([]string, error) {
+	configRoot, _, err := rootFolders()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(configRoot, profilesFolderName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	var profiles []string
+	for _, entry := range entries {
+		if entry.IsDir() && validateProfileName(entry.Name()) == nil && entry.Name() != DefaultProfile {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+	slices.Sort(profiles)
+	return append([]string{DefaultProfile}, profiles...), nil
+}
+
+// CreateProfile creates a profile with the default configuration.
+func CreateProfile(name string) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	exists, err := profileExists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the profile %s already exists", name)
+	}
+	configFolder, _, err := profileFolders(name, false)
+	if err != nil {
+		return err
+	}
+	_, err = configuration.LoadConfig(configFolder)
+	return err
+}
+
+// UseProfile selects the profile used when neither the flags nor the environment name one.
+func UseProfile(name string) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	exists, err := profileExists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("the profile %s does not exist, create it with 'profile create %s'", name, name)
+	}
+	configRoot, _, err := rootFolders()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(configRoot, 0755); err != nil {
+		return err
+	}
+	return repositories.WriteFileAtomic(filepath.Join(configRoot, activeProfileFileName), []byte(name+"\n"), 0644)
+}
+
+// LoadProfiles loads the configuration and AeonVault data of all profiles for reading, without taking their locks.
+// Profiles without data have no days.
+func LoadProfiles() ([]ProfileData, error) {
+	names, err := ListProfiles()
+	if err != nil {
+		return nil, err
+	}
+	active, err := ActiveProfile()
+	if err != nil {
+		return nil, err
+	}
+	profiles := make([]ProfileData, 0, len(names))
+	for _, name := range names {
+		profile, err := loadProfile(name, name == active)
+		if err != nil {
+			return nil, fmt.Errorf("error loading profile %s: %w", name, err)
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// loadProfile loads the configuration and AeonVault data of a profile, the vault folder of the flags or environment is used if withVault is set
+func loadProfile(name string, withVault bool) (ProfileData, error) {
+	configFolder, dataFolder, err := profileFolders(name, withVault)
+	if err != nil {
+		return ProfileData{}, err
+	}
+	config, err := configuration.LoadConfig(configFolder)
+	if err != nil {
+		return ProfileData{}, fmt.Errorf("error loading configuration: %w", err)
+	}
+	key, err := vaultKeyFor(dataFolder)
+	if err != nil {
+		return ProfileData{}, err
+	}
+	repository, err := repositories.NewVaultRepository(dataFolder, config.Storage, validator.New(), key)
+	if err != nil {
+		return ProfileData{}, err
+	}
+	defer func(repository repositories.VaultRepository) {
+		_ = repository.Close()
+	}(repository)
+	data, err := repository.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = models.AeonVault{Days: map[string]*models.AeonDay{}}, nil
+	}
+	if err != nil {
+		return ProfileData{}, fmt.Errorf("error loading time tracking data: %w", err)
+	}
+	return ProfileData{Name: name, Config: config, Data: &data}, nil
+}
+
+// profileExists reports whether the configuration folder of a named profile exists, the default profile always exists
+func profileExists(name string) (bool, error) {
+	if name == DefaultProfile {
+		return true, nil
+	}
+	configRoot, _, err := rootFolders()
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(filepath.Join(configRoot, profilesFolderName, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// validateProfileName checks that the name of a profile is usable as folder name
+func validateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q, use letters, digits, - and _", name)
+	}
+	return nil
+}
@@ -1,82 +1,1048 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/go-playground/validator/v10"
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/internal/appcore"
 	"github.com/jame-developer/aeontrac/pkg/commands"
+	"github.com/jame-developer/aeontrac/pkg/diff"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/eventlog"
+	"github.com/jame-developer/aeontrac/pkg/filelock"
+	"github.com/jame-developer/aeontrac/pkg/integrity"
+	"github.com/jame-developer/aeontrac/pkg/journal"
+	"github.com/jame-developer/aeontrac/pkg/merge"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/output"
 	"github.com/jame-developer/aeontrac/pkg/reporting"
 	"github.com/jame-developer/aeontrac/pkg/repositories"
-	"github.com/jame-developer/aeontrac/internal/appcore"
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// mutatingAnnotation marks commands whose changes to the vault are recorded in the operation journal.
+	mutatingAnnotation = "mutating"
+	// withoutVaultAnnotation marks commands which neither load nor lock the vault.
+	withoutVaultAnnotation = "without-vault"
+	// withoutValidationAnnotation marks commands which load the vault without validating it, to repair it.
+	withoutValidationAnnotation = "without-validation"
+	// readOnlyAnnotation marks commands which load the vault without the lock and never save it.
+	readOnlyAnnotation = "read-only"
 )
 
 // Run initializes and executes the CLI commands.
+// The vault is loaded and locked before a command runs and saved and unlocked after it succeeded,
+// read-only commands load it without the lock and never save it.
 func Run() error {
-	config, data, dataFolder, err := appcore.LoadApp()
-	if err != nil {
-		return fmt.Errorf("error loading app: %w", err)
+	var (
+		config     *configuration.Config
+		data       *models.AeonVault
+		dataFolder string
+		operations *journal.Journal
+		before     journal.State
+		lock       *filelock.Lock
+		key        *vaultcrypt.Key
+		comment    string
+		format     = output.Text
+	)
+	var folders appcore.Folders
+	// render writes the result of a command in the format of --output
+	render := func(result any) error {
+		return output.Write(os.Stdout, format, result)
 	}
+	appcore.PassphrasePrompt = promptPassphrase
+	defer func() {
+		if lock != nil {
+			_ = lock.Release()
+		}
+	}()
 
 	var rootCmd = &cobra.Command{
-		Use:     "",
-		Short:   "TimeLord is a time tracking system",
-		Version: "0.1",
-		Run: func(cmd *cobra.Command, args []string) {
-			reporting.PrintTodayReport(config.WorkingHours, data)
+		Use:           "",
+		Short:         "TimeLord is a time tracking system",
+		Version:       "0.1",
+		SilenceErrors: true,
+		Annotations:   map[string]string{readOnlyAnnotation: "true"},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Arguments are valid at this point, further errors are no usage errors
+			cmd.SilenceUsage = true
+			appcore.SetFolders(folders)
+			if cmd.Annotations[withoutVaultAnnotation] == "true" {
+				return nil
+			}
+			readOnly := cmd.Annotations[readOnlyAnnotation] == "true"
+			var err error
+			if !readOnly {
+				lock, err = appcore.LockApp()
+				if errors.Is(err, aeonerrors.ErrStaleLock) {
+					return fmt.Errorf("%w, remove it with 'unlock' if no other process is working on the time tracking data", err)
+				}
+				if err != nil {
+					return fmt.Errorf("error locking app: %w", err)
+				}
+			}
+			switch {
+			case readOnly:
+				config, data, dataFolder, err = appcore.LoadAppReadOnly()
+			case cmd.Annotations[withoutValidationAnnotation] == "true":
+				config, data, dataFolder, err = appcore.LoadAppForRepair()
+			default:
+				config, data, dataFolder, err = appcore.LoadApp()
+			}
+			if err != nil {
+				return fmt.Errorf("error loading app: %w", err)
+			}
+			data.CommandComment = comment
+			if key, err = appcore.VaultKey(); err != nil {
+				return err
+			}
+			operations, err = journal.Load(dataFolder, key)
+			if err != nil {
+				return fmt.Errorf("error loading operation journal: %w", err)
+			}
+			if readOnly {
+				return nil
+			}
+			before, err = journal.Capture(data)
+			if err != nil {
+				return fmt.Errorf("error capturing vault state: %w", err)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return render(reporting.GetTodayReport(config.WorkingHours, data))
 		},
 	}
+	rootCmd.PersistentFlags().StringVar(&folders.ConfigDir, "config-dir", "", "Configuration folder, overrides "+appcore.ConfigDirEnv+" and XDG_CONFIG_HOME")
+	rootCmd.PersistentFlags().StringVar(&folders.DataDir, "data-dir", "", "Data folder, overrides "+appcore.DataDirEnv+" and XDG_DATA_HOME")
+	rootCmd.PersistentFlags().StringVar(&folders.Vault, "vault", "", "Folder of the time tracking data, overrides "+appcore.VaultEnv+" and the data folder")
+	rootCmd.PersistentFlags().StringVar(&folders.Profile, "profile", "", "Profile to use, overrides "+appcore.ProfileEnv+" and the profile selected with 'profile use'")
+	rootCmd.PersistentFlags().VarP(&format, "output", "o", "Format of the output: text, json, yaml or csv")
 
 	var startCmd = &cobra.Command{
-		Use:   "start [time] [comment]",
-		Short: "Start time tracking for a new unit of work",
-		Args:  cobra.MaximumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			commands.StartCommand(args, data)
-			reporting.PrintTodayReport(config.WorkingHours, data)
+		Use:         "start [time] [comment]",
+		Annotations: map[string]string{mutatingAnnotation: "true"},
+		Short:       "Start time tracking for a new unit of work",
+		Long: `Start time tracking for a new unit of work, now or at a time in the configured time zone:
+a time of day like 9:15, an offset like -10m, a day and time like "last friday 13:00" or 2006-01-02T15:04:05.`,
+		Example: "  aeontrac start 9:15\n  aeontrac start -10m\n  aeontrac start \"yesterday 08:30\"",
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := commands.StartCommand(args, config.WorkingHours, data)
+			if err != nil {
+				return err
+			}
+			today := reporting.GetTodayReport(config.WorkingHours, data)
+			return render(reporting.UnitConfirmation{Message: fmt.Sprintf("Time tracking started at %s", result.Start.Format(time.TimeOnly)), UnitResult: result, Today: &today})
 		},
 	}
 
 	var stopCmd = &cobra.Command{
-		Use:   "stop [time] [comment]",
-		Short: "Stop time tracking for a unit of work",
-		Args:  cobra.MaximumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			commands.StopCommand(args, config.WorkingHours, data)
-			reporting.PrintTodayReport(config.WorkingHours, data)
+		Use:         "stop [time] [comment]",
+		Annotations: map[string]string{mutatingAnnotation: "true"},
+		Short:       "Stop time tracking for a unit of work",
+		Long: `Stop time tracking for the running unit of work, now or at a time in the configured time zone,
+given like the time of 'start'.`,
+		Example: "  aeontrac stop\n  aeontrac stop -10m\n  aeontrac stop @now",
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := commands.StopCommand(args, config.WorkingHours, data)
+			if err != nil {
+				return err
+			}
+			today := reporting.GetTodayReport(config.WorkingHours, data)
+			return render(reporting.UnitConfirmation{Message: fmt.Sprintf("Time tracking stopped at %s after %s", result.Stop.Format(time.TimeOnly), reporting.StatusDuration(result.Duration.Duration).HMS()), UnitResult: result, Today: &today})
 		},
 	}
 
 	var addCmd = &cobra.Command{
-		Use:   "add [startTime] [stopTime]",
-		Short: "Add a time work unit",
-		Args:  cobra.ExactArgs(2),
-		Run: func(cmd *cobra.Command, args []string) {
-			commands.AddTimeWorkUnitCommand(args, config.WorkingHours, data)
+		Use:         "add [day] [startTime] [stopTime]",
+		Annotations: map[string]string{mutatingAnnotation: "true"},
+		Short:       "Add a time work unit",
+		Long: `Add a completed unit of work, with times given like the time of 'start'.
+A stop time of day is on the day of the start time, the day may be given once for both times.`,
+		Example: "  aeontrac add 08:30 12:00\n  aeontrac add yesterday 08:30 12:00\n  aeontrac add \"last friday 13:00\" 17:00",
+		Args:    cobra.RangeArgs(2, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := commands.AddTimeWorkUnitCommand(args, config.WorkingHours, data)
+			if err != nil {
+				return err
+			}
+			return render(reporting.UnitConfirmation{Message: fmt.Sprintf("Added %s of work on %s", result.Duration, result.DayKey), UnitResult: result})
 		},
 	}
 
 	var quarterlyReportCmd = &cobra.Command{
-		Use:   "qrep",
-		Short: "Add a time work unit",
+		Use:         "qrep",
+		Annotations: map[string]string{readOnlyAnnotation: "true"},
+		Short:       "Add a time work unit",
+		Args:        cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return render(reporting.GetQuarterlyReport(data))
+		},
+	}
+
+	var statusFormat string
+	var statusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show whether time is tracked and the hours of today, for shell prompts and status bars",
+		Long: "Show whether time is tracked and the hours of today in one line for shell prompts and status bars. The time\n" +
+			"tracking data is read without the lock and never saved. --format takes a built-in format (" +
+			strings.Join(reporting.StatusFormatNames(), ", ") + ")\n" +
+			"or a Go template with the fields .Running, .State, .Start, .Elapsed, .Comment, .Projects, .Today, .Target,\n" +
+			".Remaining, .Overtime and .Percentage, durations have the methods .Clock, .HMS and .Minutes.",
+		Example:     "  aeontrac status --format waybar\n  aeontrac status --format '{{if .Running}}{{.Elapsed.Clock}}{{end}}'",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Only the running unit and today are read, the status line runs often
+			config, data, _, err := appcore.LoadDaysReadOnly(func(now time.Time) (string, string) {
+				return now.Format(time.DateOnly), now.Format(time.DateOnly)
+			})
+			if err != nil {
+				return err
+			}
+			status := reporting.GetStatus(config.WorkingHours, data, time.Now().In(config.WorkingHours.Location()))
+			if statusFormat == "" {
+				return render(status)
+			}
+			line, err := status.Format(statusFormat)
+			if err != nil {
+				return err
+			}
+			fmt.Println(line)
+			return nil
+		},
+	}
+	statusCmd.Flags().StringVarP(&statusFormat, "format", "f", "", "Built-in format or Go template of the status line")
+
+	var tuiCmd = &cobra.Command{
+		Use:   "tui",
+		Short: "Show a full-screen dashboard of the running unit, today, this week and the upcoming public holidays",
+		Long: "Show a full-screen dashboard of the running unit, the timeline of today, the hours of this week and the public\n" +
+			"holidays of the next days. Units of work are started, stopped, switched, commented and edited with keys, the\n" +
+			"dashboard is updated when the time tracking data is changed by other processes, like the API.",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDashboard()
+		},
+	}
+
+	var force, journalChanged bool
+	var undoCmd = &cobra.Command{
+		Use:   "undo",
+		Short: "Undo the last operation on the time tracking data",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entry, err := operations.Undo(data, force)
+			if err != nil {
+				return err
+			}
+			journalChanged = true
+			return render(output.Messagef("Undone: %s", describeEntry(entry)))
+		},
+	}
+	undoCmd.Flags().BoolVarP(&force, "force", "f", false, "Undo even if the time tracking data has been changed since")
+
+	var redoCmd = &cobra.Command{
+		Use:   "redo",
+		Short: "Redo the last undone operation on the time tracking data",
 		Args:  cobra.ExactArgs(0),
-		Run: func(cmd *cobra.Command, args []string) {
-			reporting.PrintQuarterlyReport(config.WorkingHours, data)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entry, err := operations.Redo(data, force)
+			if err != nil {
+				return err
+			}
+			journalChanged = true
+			return render(output.Messagef("Redone: %s", describeEntry(entry)))
+		},
+	}
+	redoCmd.Flags().BoolVarP(&force, "force", "f", false, "Redo even if the time tracking data has been changed since")
+
+	var limit int
+	var historyCmd = &cobra.Command{
+		Use:         "history",
+		Annotations: map[string]string{readOnlyAnnotation: "true"},
+		Short:       "List the recent operations on the time tracking data",
+		Args:        cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, applied := operations.Recent(limit)
+			return render(newHistoryResult(entries, applied))
+		},
+	}
+	historyCmd.Flags().IntVarP(&limit, "limit", "n", 10, "Number of operations to list, 0 lists all")
+
+	var backupCmd = &cobra.Command{
+		Use:   "backup",
+		Short: "Manage the backups of the time tracking data",
+	}
+
+	var backupListCmd = &cobra.Command{
+		Use:         "list",
+		Short:       "List the backups of the time tracking data",
+		Annotations: map[string]string{readOnlyAnnotation: "true"},
+		Args:        cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backups, err := repositories.ListBackups(dataFolder)
+			if err != nil {
+				return fmt.Errorf("error listing backups: %w", err)
+			}
+			return render(newBackupListResult(backups))
+		},
+	}
+
+	var backupRestoreCmd = &cobra.Command{
+		Use:         "restore [id|latest]",
+		Short:       "Restore the time tracking data from a backup",
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{mutatingAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			restored, err := repositories.LoadBackup(dataFolder, args[0], validator.New(), key)
+			if err != nil {
+				return fmt.Errorf("error loading backup: %w", err)
+			}
+			data.Days = restored.Days
+			data.CurrentRunningUnit = restored.CurrentRunningUnit
+			return render(output.Messagef("Time tracking data restored from backup %s", args[0]))
+		},
+	}
+	backupCmd.AddCommand(backupListCmd, backupRestoreCmd)
+
+	var unlockCmd = &cobra.Command{
+		Use:         "unlock",
+		Short:       "Remove a stale lock on the time tracking data",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lockedFolder, err := appcore.DataFolder()
+			if err != nil {
+				return err
+			}
+			holder, err := filelock.CurrentHolder(lockedFolder)
+			if errors.Is(err, os.ErrNotExist) {
+				return render(output.Messagef("The time tracking data is not locked."))
+			}
+			if err == nil && !filelock.IsStale(holder) && !force {
+				return fmt.Errorf("the time tracking data is locked by %s, which is still running, use --force to remove the lock anyway", holder)
+			}
+			if err = filelock.ForceRelease(lockedFolder); err != nil {
+				return fmt.Errorf("error removing lock: %w", err)
+			}
+			return render(output.Messagef("Lock removed."))
+		},
+	}
+	unlockCmd.Flags().BoolVarP(&force, "force", "f", false, "Remove the lock even if its holder is still running")
+
+	var storageCmd = &cobra.Command{
+		Use:   "storage",
+		Short: "Manage the storage backend of the time tracking data",
+	}
+
+	var backend string
+	var storageMigrateCmd = &cobra.Command{
+		Use:         "migrate",
+		Short:       "Move the time tracking data to another storage backend",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := appcore.MigrateStorage(backend, force); err != nil {
+				return err
+			}
+			return render(output.Messagef("Time tracking data migrated to the %s backend.", backend))
+		},
+	}
+	storageMigrateCmd.Flags().StringVar(&backend, "to", "", "Target storage backend, json, sqlite or eventlog")
+	_ = storageMigrateCmd.MarkFlagRequired("to")
+	storageMigrateCmd.Flags().BoolVarP(&force, "force", "f", false, "Replace existing data of the target backend")
+
+	var dryRun bool
+	var storageUpgradeCmd = &cobra.Command{
+		Use:         "upgrade",
+		Short:       "Upgrade the time tracking data to the current schema version",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			upgrade, err := appcore.UpgradeStorage(dryRun)
+			if err != nil {
+				return err
+			}
+			changes := upgrade.Changes
+			if changes == nil {
+				changes = []string{}
+			}
+			return render(upgradeResult{FromVersion: upgrade.FromVersion, ToVersion: upgrade.ToVersion, Required: upgrade.Required(),
+				DryRun: dryRun, Changes: changes, BackupID: upgrade.BackupID})
+		},
+	}
+	storageUpgradeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the changes without upgrading the time tracking data")
+	storageCmd.AddCommand(storageMigrateCmd, storageUpgradeCmd)
+
+	var keyFile string
+	var vaultCmd = &cobra.Command{
+		Use:   "vault",
+		Short: "Manage the encryption of the time tracking data",
+	}
+
+	var vaultEncryptCmd = &cobra.Command{
+		Use:         "encrypt",
+		Short:       "Encrypt the time tracking data, its backups and the operation journal",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			newKey, err := newVaultKey(keyFile)
+			if err != nil {
+				return err
+			}
+			if err = appcore.EncryptVault(newKey); err != nil {
+				return err
+			}
+			return render(output.Messagef("Time tracking data encrypted with a %s.", newKey.Source()))
+		},
+	}
+	vaultEncryptCmd.Flags().StringVar(&keyFile, "keyfile", "", "Encrypt with the content of a keyfile instead of a passphrase")
+
+	var vaultDecryptCmd = &cobra.Command{
+		Use:         "decrypt",
+		Short:       "Decrypt the time tracking data, its backups and the operation journal",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := appcore.DecryptVault(); err != nil {
+				return err
+			}
+			return render(output.Messagef("Time tracking data decrypted."))
+		},
+	}
+
+	var vaultRekeyCmd = &cobra.Command{
+		Use:         "rekey",
+		Short:       "Encrypt the time tracking data with a new passphrase or keyfile",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The current key is resolved before asking for the new one
+			if _, err := appcore.VaultKey(); err != nil {
+				return err
+			}
+			newKey, err := newVaultKey(keyFile)
+			if err != nil {
+				return err
+			}
+			if err = appcore.RekeyVault(newKey); err != nil {
+				return err
+			}
+			return render(output.Messagef("Time tracking data encrypted with the new %s.", newKey.Source()))
+		},
+	}
+	vaultRekeyCmd.Flags().StringVar(&keyFile, "keyfile", "", "Encrypt with the content of a keyfile instead of a passphrase")
+	vaultCmd.AddCommand(vaultEncryptCmd, vaultDecryptCmd, vaultRekeyCmd)
+
+	var asOf string
+	var showCmd = &cobra.Command{
+		Use:         "show [day|unit]",
+		Annotations: map[string]string{readOnlyAnnotation: "true"},
+		Short:       "Show the units and hours of a day, by default of today, or a unit of work",
+		Long: "Show the units and hours of a day, by default of today, or a unit of work by a unique prefix of its ID\n" +
+			"as listed by 'log'. With --as-of, the time tracking data is rebuilt as it was at that time from the event log,\n" +
+			"which requires the eventlog storage backend.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shown, dayKey := data, time.Now().Format(time.DateOnly)
+			var asOfTime *time.Time
+			if asOf != "" {
+				parsed, err := parseAsOf(asOf)
+				if err != nil {
+					return err
+				}
+				if shown, err = appcore.LoadAppAsOf(config, dataFolder, parsed); err != nil {
+					return err
+				}
+				asOfTime, dayKey = &parsed, parsed.Format(time.DateOnly)
+			}
+			if len(args) > 0 {
+				day, err := commands.ParseDay(args[0], time.Now().In(config.WorkingHours.Location()))
+				if err != nil {
+					entry, unitErr := commands.FindUnit(args[0], shown)
+					if commands.KindOf(unitErr) == commands.KindNotFound {
+						return &commands.Error{Kind: commands.KindNotFound, Err: fmt.Errorf("%s is neither a day like 2006-01-02 nor the ID of a unit of work", args[0])}
+					}
+					if unitErr != nil {
+						return unitErr
+					}
+					return render(reporting.UnitDetail{UnitEntry: entry, AsOf: asOfTime})
+				}
+				dayKey = day.Format(time.DateOnly)
+			}
+			report := reporting.GetDayReport(dayKey, shown)
+			report.AsOf = asOfTime
+			return render(report)
+		},
+	}
+	showCmd.Flags().StringVar(&asOf, "as-of", "", "Show the data as it was at this time, YYYY-MM-DD[THH:MM:SS] in local time")
+
+	var editDayCmd = &cobra.Command{
+		Use:   "edit-day [day]",
+		Short: "Edit the units of a day, by default of today, in $VISUAL or $EDITOR",
+		Long: "Edit the units of a day, by default of today, as YAML in $VISUAL or $EDITOR. The day is validated and its hours\n" +
+			"are recalculated when the file is saved, an invalid day reopens the editor with the errors as comments.",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, _, err := appcore.LoadConfig()
+			if err != nil {
+				return err
+			}
+			day := time.Now().In(config.WorkingHours.Location())
+			if len(args) > 0 {
+				if day, err = commands.ParseDay(args[0], day); err != nil {
+					return err
+				}
+			}
+			dayKey := day.Format(time.DateOnly)
+			err = editDay(dayKey)
+			if errors.Is(err, errUnchanged) {
+				return render(output.Messagef("%s is unchanged.", dayKey))
+			}
+			if err != nil {
+				return fmt.Errorf("%s is not saved:\n%w", dayKey, err)
+			}
+			return render(output.Messagef("%s has been saved.", dayKey))
+		},
+	}
+
+	var logFrom, logTo, logProject, logType string
+	var logCmd = &cobra.Command{
+		Use:         "log",
+		Annotations: map[string]string{readOnlyAnnotation: "true"},
+		Short:       "List the units of work with their short IDs",
+		Long: "List the units of work with the short prefixes of their IDs, which commands taking a unit accept.\n" +
+			"Days are given like 2006-01-02, yesterday or \"last friday\", projects are tagged in comments like +aeontrac.",
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now().In(config.WorkingHours.Location())
+			filter := commands.LogFilter{Project: logProject, Type: logType}
+			var err error
+			if logFrom != "" {
+				if filter.From, err = commands.ParseDay(logFrom, now); err != nil {
+					return &commands.Error{Kind: commands.KindInvalidInput, Err: fmt.Errorf("--from: %w", err)}
+				}
+			}
+			if logTo != "" {
+				if filter.To, err = commands.ParseDay(logTo, now); err != nil {
+					return &commands.Error{Kind: commands.KindInvalidInput, Err: fmt.Errorf("--to: %w", err)}
+				}
+			}
+			entries := commands.LogCommand(filter, data)
+			if entries == nil {
+				entries = []commands.UnitEntry{}
+			}
+			return render(reporting.UnitLog(entries))
+		},
+	}
+	logCmd.Flags().StringVar(&logFrom, "from", "", "First day of the units")
+	logCmd.Flags().StringVar(&logTo, "to", "", "Last day of the units")
+	logCmd.Flags().StringVar(&logProject, "project", "", "Only units whose comment tags the project, like +aeontrac")
+	logCmd.Flags().StringVar(&logType, "type", "", "Only units of the type, work or compensatory")
+
+	var eventLimit int
+	var eventsCmd = &cobra.Command{
+		Use:         "events",
+		Annotations: map[string]string{readOnlyAnnotation: "true"},
+		Short:       "List the recent events of the event log",
+		Args:        cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			events, err := appcore.ListEvents(config, dataFolder)
+			if err != nil {
+				return err
+			}
+			if eventLimit > 0 && len(events) > eventLimit {
+				events = events[len(events)-eventLimit:]
+			}
+			if events == nil {
+				events = []eventlog.Event{}
+			}
+			return render(eventsResult(events))
+		},
+	}
+	eventsCmd.Flags().IntVarP(&eventLimit, "limit", "n", 20, "Number of events to list, 0 lists all")
+
+	var baseBackup, strategy string
+	var mergeCmd = &cobra.Command{
+		Use:   "merge <other-vault>",
+		Short: "Merge the time tracking data of another machine into the local data",
+		Long: "Merge the units of another data file or data folder into the local data, matching units by their ID.\n" +
+			"Without --base, units missing on one side are added. With --base, the ID of a backup which is the common\n" +
+			"ancestor of both sides, changes and deletions made on only one side are taken over.\n" +
+			"Units changed on both sides and overlapping units of different sides are resolved with --strategy.",
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{mutatingAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolve, err := mergeResolver(strategy)
+			if err != nil {
+				return err
+			}
+			other, err := loadOtherVault(args[0], key)
+			if err != nil {
+				return fmt.Errorf("error loading %s: %w", args[0], err)
+			}
+			var base *models.AeonVault
+			if baseBackup != "" {
+				loaded, err := repositories.LoadBackup(dataFolder, baseBackup, validator.New(), key)
+				if err != nil {
+					return fmt.Errorf("error loading base backup: %w", err)
+				}
+				base = &loaded
+			}
+			result, err := merge.Merge(data, &other, base, resolve, config.WorkingHours)
+			if err != nil {
+				return err
+			}
+			return render(newMergeResult(args[0], result))
+		},
+	}
+	mergeCmd.Flags().StringVar(&baseBackup, "base", "", "ID of the backup which is the common ancestor of both sides, for a three-way merge")
+	mergeCmd.Flags().StringVar(&strategy, "strategy", strategyAsk, "Resolution of conflicts: local, other, both or ask")
+
+	// remainingErr fails a command after the time tracking data has been saved, so its changes are kept
+	var remainingErr error
+	var repair bool
+	var fsckCmd = &cobra.Command{
+		Use:         "fsck",
+		Short:       "Check the time tracking data for inconsistencies",
+		Long:        "Check the time tracking data for inconsistencies and list every problem, --repair fixes the problems which are safe to fix.",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{mutatingAnnotation: "true", withoutValidationAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problems := []integrity.Problem{}
+			if repair {
+				problems = append(problems, integrity.Repair(data, config.WorkingHours)...)
+			} else {
+				problems = append(problems, integrity.Check(data, config.WorkingHours)...)
+			}
+			unrepaired := len(integrity.Unrepaired(problems))
+			switch {
+			case unrepaired == 0:
+				// No problems, or all of them have been repaired
+			case repair:
+				remainingErr = fmt.Errorf("%d of %d problems could not be repaired", unrepaired, len(problems))
+			default:
+				repairable := 0
+				for _, problem := range problems {
+					if problem.Repairable {
+						repairable++
+					}
+				}
+				remainingErr = fmt.Errorf("%d problems found, %d can be repaired with --repair", len(problems), repairable)
+			}
+			return render(fsckResult{Problems: problems, Repaired: len(problems) - unrepaired})
+		},
+	}
+	fsckCmd.Flags().BoolVar(&repair, "repair", false, "Repair the problems which are safe to repair")
+
+	var diffJSON bool
+	var diffCmd = &cobra.Command{
+		Use:         "diff <a> <b>",
+		Annotations: map[string]string{readOnlyAnnotation: "true"},
+		Short:       "Show the differences between two states of the time tracking data",
+		Long: "Show the added, removed and modified units, changed hours and changed holiday and vacation flags from a to b, per day.\n" +
+			"a and b are data files or data folders, backup IDs, latest for the latest backup, or current for the current data.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var sides [2]*models.AeonVault
+			for i, source := range args {
+				loaded, err := loadDiffVault(source, data, dataFolder, key)
+				if err != nil {
+					return fmt.Errorf("error loading %s: %w", source, err)
+				}
+				sides[i] = loaded
+			}
+			result := diff.Compare(sides[0], sides[1])
+			if result.Days == nil {
+				result.Days = []diff.DayChange{}
+			}
+			if diffJSON {
+				format = output.JSON
+			}
+			return render(result)
+		},
+	}
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false, "Print the differences as JSON, like --output json")
+	_ = diffCmd.Flags().MarkDeprecated("json", "use --output json instead")
+
+	var profileCmd = &cobra.Command{
+		Use:   "profile",
+		Short: "Manage the profiles, each with its own configuration and time tracking data",
+	}
+
+	var profileListCmd = &cobra.Command{
+		Use:         "list",
+		Short:       "List the profiles, marking the active one",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles, err := appcore.ListProfiles()
+			if err != nil {
+				return err
+			}
+			active, err := appcore.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			result := make(profileListResult, len(profiles))
+			for i, profile := range profiles {
+				result[i] = profileEntry{Name: profile, Active: profile == active}
+			}
+			return render(result)
+		},
+	}
+
+	var profileCreateCmd = &cobra.Command{
+		Use:         "create <name>",
+		Short:       "Create a profile with the default configuration",
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := appcore.CreateProfile(args[0]); err != nil {
+				return err
+			}
+			return render(output.Messagef("Profile %s created.", args[0]))
+		},
+	}
+
+	var profileUseCmd = &cobra.Command{
+		Use:         "use <name>",
+		Short:       "Use a profile for all following commands",
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := appcore.UseProfile(args[0]); err != nil {
+				return err
+			}
+			return render(output.Messagef("Using profile %s.", args[0]))
+		},
+	}
+
+	var maxDay, maxWeek time.Duration
+	var profileReportCmd = &cobra.Command{
+		Use:         "report",
+		Short:       "Show the hours worked today, this week and this month across all profiles",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles, err := appcore.LoadProfiles()
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			hours := make([]reporting.ProfileHours, 0, len(profiles))
+			for _, profile := range profiles {
+				hours = append(hours, reporting.WorkedHours(profile.Name, profile.Data, now))
+			}
+			return render(reporting.NewCombinedReport(hours, maxDay, maxWeek))
+		},
+	}
+	profileReportCmd.Flags().DurationVar(&maxDay, "max-day", 10*time.Hour, "Maximum working time per day across all profiles, 0 disables the check")
+	profileReportCmd.Flags().DurationVar(&maxWeek, "max-week", 48*time.Hour, "Maximum working time per week across all profiles, 0 disables the check")
+	profileCmd.AddCommand(profileListCmd, profileCreateCmd, profileUseCmd, profileReportCmd)
+
+	var configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Show, change and validate the configuration of the active profile",
+	}
+
+	var showOrigin bool
+	var configShowCmd = &cobra.Command{
+		Use:         "show",
+		Short:       "Show all settings with their values, including the environment overrides",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, origins, err := appcore.LoadConfig()
+			if err != nil {
+				return err
+			}
+			settings := make(settingsResult, 0, len(configuration.Keys()))
+			for _, key := range configuration.Keys() {
+				value, err := configuration.GetValue(config, key)
+				if err != nil {
+					return err
+				}
+				s := setting{Key: key, Value: value}
+				if showOrigin {
+					s.Origin = origins[key]
+				}
+				settings = append(settings, s)
+			}
+			return render(settings)
+		},
+	}
+	configShowCmd.Flags().BoolVar(&showOrigin, "origin", false, "Show where each value comes from: the default, the configuration file or an environment variable")
+
+	var configGetCmd = &cobra.Command{
+		Use:         "get <key>",
+		Short:       "Print the value of a setting",
+		Args:        cobra.ExactArgs(1),
+		ValidArgs:   configuration.Keys(),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, _, err := appcore.LoadConfig()
+			if err != nil {
+				return err
+			}
+			value, err := configuration.GetValue(config, args[0])
+			if err != nil {
+				return err
+			}
+			return render(settingValue{Key: args[0], Value: value})
+		},
+	}
+
+	var configSetCmd = &cobra.Command{
+		Use:         "set <key> <value>",
+		Short:       "Change a setting, the configuration is validated before it is saved",
+		Example:     "  config set working_hours.work_day 7h30m\n  config set working_hours.start_time 08:00\n  config set public_holidays.region DE-BY",
+		Args:        cobra.ExactArgs(2),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var value string
+			err := appcore.UpdateConfig(func(config *configuration.Config) error {
+				if err := configuration.SetValue(config, args[0], args[1]); err != nil {
+					return err
+				}
+				var err error
+				value, err = configuration.GetValue(config, args[0])
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			printEnvOverride(args[0])
+			return render(setting{Key: args[0], Value: value})
+		},
+	}
+
+	var configUnsetCmd = &cobra.Command{
+		Use:         "unset <key>",
+		Short:       "Reset a setting to its default value",
+		Args:        cobra.ExactArgs(1),
+		ValidArgs:   configuration.Keys(),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var value string
+			err := appcore.UpdateConfig(func(config *configuration.Config) error {
+				if err := configuration.UnsetValue(config, args[0]); err != nil {
+					return err
+				}
+				var err error
+				value, err = configuration.GetValue(config, args[0])
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			printEnvOverride(args[0])
+			return render(setting{Key: args[0], Value: value})
+		},
+	}
+
+	var configValidateCmd = &cobra.Command{
+		Use:         "validate",
+		Short:       "Check the configuration file for unknown and invalid settings",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFile, err := appcore.ValidateConfigFile()
+			if err != nil {
+				return fmt.Errorf("%s is invalid:\n%w", configFile, err)
+			}
+			return render(output.Messagef("%s is valid.", configFile))
+		},
+	}
+
+	var configEditCmd = &cobra.Command{
+		Use:         "edit",
+		Short:       "Edit the configuration file in $VISUAL or $EDITOR, it is validated before it is saved",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFile, err := appcore.ConfigFile()
+			if err != nil {
+				return err
+			}
+			edited, err := editConfig(configFile)
+			if errors.Is(err, errUnchanged) {
+				return render(output.Messagef("The configuration is unchanged."))
+			}
+			if err != nil {
+				return fmt.Errorf("the configuration is not saved:\n%w", err)
+			}
+			if err = appcore.ReplaceConfig(edited); err != nil {
+				return err
+			}
+			return render(output.Messagef("The configuration has been saved."))
 		},
 	}
+	configCmd.AddCommand(configShowCmd, configGetCmd, configSetCmd, configUnsetCmd, configValidateCmd, configEditCmd)
 
-	rootCmd.AddCommand(startCmd, stopCmd, addCmd, quarterlyReportCmd /*, offCmd, vacCmd, reportCmd*/)
+	var exportProfileCmd = &cobra.Command{
+		Use:         "export-profile <file>",
+		Short:       "Export the configuration and all time tracking data to a portable archive",
+		Long:        "Export the configuration, the time tracking data, its backups and journal to a gzip compressed tar archive with a manifest of checksums.",
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifest, err := appcore.ExportProfile(args[0])
+			if err != nil {
+				return err
+			}
+			return render(archiveResult{Message: fmt.Sprintf("Profile with %d files exported to %s", len(manifest.Files), args[0]), Archive: args[0], Files: len(manifest.Files)})
+		},
+	}
+
+	var importProfileCmd = &cobra.Command{
+		Use:         "import-profile <file>",
+		Short:       "Import the configuration and all time tracking data from an archive of export-profile",
+		Long:        "Import the configuration and all time tracking data from an archive of export-profile. The archive is verified and loaded before anything is replaced, the replaced profile is kept as archive in the data folder.",
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{withoutVaultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifest, previous, err := appcore.ImportProfile(args[0], force)
+			if err != nil {
+				return err
+			}
+			return render(archiveResult{Message: fmt.Sprintf("Profile with %d files imported from %s", len(manifest.Files), args[0]), Archive: args[0], Files: len(manifest.Files), Replaced: previous})
+		},
+	}
+	importProfileCmd.Flags().BoolVarP(&force, "force", "f", false, "Replace existing time tracking data")
+
+	rootCmd.AddCommand(startCmd, stopCmd, addCmd /*, offCmd, vacCmd, reportCmd*/)
 	for _, subCmd := range rootCmd.Commands() {
-		subCmd.Flags().StringVarP(&data.CommandComment, "comment", "c", "", "Comment for the unit of work, in quotes")
+		subCmd.Flags().StringVarP(&comment, "comment", "c", "", "Comment for the unit of work, in quotes")
 	}
+	rootCmd.AddCommand(quarterlyReportCmd, statusCmd, undoCmd, redoCmd, historyCmd, backupCmd, unlockCmd, storageCmd, vaultCmd, fsckCmd, mergeCmd, showCmd, logCmd, editDayCmd, tuiCmd, eventsCmd, diffCmd, exportProfileCmd, importProfileCmd, profileCmd, configCmd)
 
-	if err := rootCmd.Execute(); err != nil {
+	rootCmd.SetArgs(relativeTimeArgs(rootCmd, os.Args[1:]))
+	executedCmd, err := rootCmd.ExecuteC()
+	if err != nil && !executedCmd.SilenceUsage {
+		// The command has not run, its arguments or flags are invalid
+		return usageError(err)
+	}
+	if err != nil {
 		return err
 	}
+	if data == nil || executedCmd.Annotations[readOnlyAnnotation] == "true" {
+		// Neither help nor commands without vault or read-only commands change the time tracking data
+		return nil
+	}
 
-	err = repositories.SaveAeonVault(dataFolder, *data)
-	if err != nil {
-		return fmt.Errorf("error saving time tracking data: %w", err)
+	if executedCmd.Annotations[mutatingAnnotation] == "true" && executedCmd.Annotations[withoutValidationAnnotation] != "true" {
+		if err = repositories.ValidateAeonVault(data, validator.New()); err != nil {
+			return fmt.Errorf("the changes are not saved: %w", err)
+		}
+	}
+	operation := strings.TrimSpace(executedCmd.CommandPath())
+	data.CommandOperation = operation
+	if err = appcore.SaveApp(config, data, dataFolder); err != nil {
+		return err
 	}
 
-	return nil
-}
\ No newline at end of file
+	if executedCmd.Annotations[mutatingAnnotation] == "true" {
+		err = operations.Record(journal.SourceCLI, operation, strings.Join(executedCmd.Flags().Args(), " "), before, data)
+		if err != nil {
+			return fmt.Errorf("error recording operation: %w", err)
+		}
+		journalChanged = true
+	}
+	if journalChanged {
+		if err = journal.Save(dataFolder, operations, key); err != nil {
+			return fmt.Errorf("error saving operation journal: %w", err)
+		}
+	}
+
+	return remainingErr
+}
+
+// parseAsOf parses a point in time in local time, a day without time means the end of that day.
+func parseAsOf(value string) (time.Time, error) {
+	if day, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	for _, layout := range []string{time.DateOnly + "T" + time.TimeOnly, time.DateTime} {
+		if asOf, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return asOf, nil
+		}
+	}
+	if asOf, err := time.Parse(time.RFC3339, value); err == nil {
+		return asOf, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %s, use the format YYYY-MM-DD or YYYY-MM-DDTHH:MM:SS", value)
+}
+
+// describeEntry returns a short human-readable description of a journal entry.
+func describeEntry(entry journal.Entry) string {
+	if entry.Arguments == "" {
+		return entry.Operation
+	}
+	return entry.Operation + " " + entry.Arguments
+}
+
+// timeArgCommands are the commands whose positional arguments are times, which may be relative like -10m
+var timeArgCommands = map[string]bool{"start": true, "stop": true, "add": true}
+
+// relativeTimeArgs rewrites the positional time arguments like -10m of start, stop and add to now-10m, which cobra
+// would take for flags. The values of flags, like --comment -5m, are kept.
+func relativeTimeArgs(rootCmd *cobra.Command, args []string) []string {
+	cmd, _, err := rootCmd.Find(args)
+	if err != nil || !timeArgCommands[cmd.Name()] {
+		return args
+	}
+	rewritten := make([]string, len(args))
+	copy(rewritten, args)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			// Arguments after -- are positional anyway
+			return rewritten
+		case isRelativeTime(arg):
+			rewritten[i] = "now" + arg
+		case flagTakesValue(cmd, arg):
+			i++
+		}
+	}
+	return rewritten
+}
+
+// isRelativeTime reports whether an argument is a negative duration like -10m
+func isRelativeTime(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' || arg[1] < '0' || arg[1] > '9' {
+		return false
+	}
+	_, err := time.ParseDuration(arg)
+	return err == nil
+}
+
+// flagTakesValue reports whether an argument is a flag of the command whose value is the next argument
+func flagTakesValue(cmd *cobra.Command, arg string) bool {
+	var flag *pflag.Flag
+	if name, ok := strings.CutPrefix(arg, "--"); ok {
+		if strings.Contains(name, "=") {
+			return false
+		}
+		flag = cmd.Flag(name)
+	} else if len(arg) == 2 && arg[0] == '-' {
+		if flag = cmd.Flags().ShorthandLookup(arg[1:]); flag == nil {
+			flag = cmd.InheritedFlags().ShorthandLookup(arg[1:])
+		}
+	}
+	return flag != nil && flag.NoOptDefVal == ""
+}
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jame-developer/aeontrac/configuration"
+)
+
+// editConfig opens the configuration file in the editor and returns the edited configuration once it is valid
+func editConfig(configFile string) (*configuration.Config, error) {
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return editUntilValid(content, "aeontrac-config-*"+filepath.Ext(configFile), func(edited []byte) (*configuration.Config, error) {
+		return configuration.ParseConfig(edited, configuration.FileFormat(configFile))
+	}, nil)
+}
+
+// printEnvOverride notes that the environment overrides a setting saved in the configuration file
+func printEnvOverride(key string) {
+	if envName := configuration.EnvName(key); os.Getenv(envName) != "" {
+		fmt.Fprintf(os.Stderr, "Note: %s overrides this setting.\n", envName)
+	}
+}
@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"errors"
+	"os"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
+)
+
+// currentVault refers to the current time tracking data in the arguments of diff.
+const currentVault = "current"
+
+// loadDiffVault loads a side of a diff: the current time tracking data, a data file or data folder, or a backup by its ID or latest.
+func loadDiffVault(source string, current *models.AeonVault, dataFolder string, key *vaultcrypt.Key) (*models.AeonVault, error) {
+	if source == currentVault {
+		return current, nil
+	}
+	if _, err := os.Stat(source); err == nil {
+		loaded, err := loadOtherVault(source, key)
+		return &loaded, err
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	loaded, err := repositories.LoadBackup(dataFolder, source, validator.New(), key)
+	return &loaded, err
+}
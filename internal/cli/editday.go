@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/internal/appcore"
+	"github.com/jame-developer/aeontrac/pkg/commands"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/filelock"
+	"github.com/jame-developer/aeontrac/pkg/journal"
+	"github.com/jame-developer/aeontrac/pkg/models"
+)
+
+// editDay opens a day in the editor as YAML and saves it once it is valid.
+// The lock is not held while the editor is open, the day is only saved if no other process has changed it meanwhile.
+func editDay(dayKey string) error {
+	config, data, err := loadLocked()
+	if err != nil {
+		return err
+	}
+	doc, err := commands.NewDayDocument(dayKey, data, config.WorkingHours.Location())
+	if err != nil {
+		return err
+	}
+	content, err := doc.YAML(dayKey, data, config.WorkingHours.Location())
+	if err != nil {
+		return err
+	}
+	before, err := dayState(dayKey, data)
+	if err != nil {
+		return err
+	}
+	edited, err := editUntilValid(content, "aeontrac-day-"+dayKey+"-*.yaml", func(edited []byte) (commands.DayDocument, error) {
+		doc, err := commands.ParseDayDocument(edited)
+		if err != nil {
+			return doc, err
+		}
+		// The loaded data is only used to check the changes, they are saved to the data loaded under the lock
+		return doc, commands.EditDayCommand(dayKey, doc, config.WorkingHours, data)
+	}, commentErrors)
+	if err != nil {
+		return err
+	}
+
+	return appcore.UpdateApp(journal.SourceCLI, "edit-day", dayKey, func(config *configuration.Config, data *models.AeonVault) error {
+		current, err := dayState(dayKey, data)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(before, current) {
+			return &commands.Error{Kind: commands.KindConflict, Err: fmt.Errorf("%w, edit it again", aeonerrors.ErrDayChanged)}
+		}
+		return commands.EditDayCommand(dayKey, edited, config.WorkingHours, data)
+	})
+}
+
+// loadLocked loads the configuration and time tracking data while holding the lock on the data folder
+func loadLocked() (*configuration.Config, *models.AeonVault, error) {
+	lock, err := appcore.LockApp()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func(lock *filelock.Lock) {
+		_ = lock.Release()
+	}(lock)
+	config, data, _, err := appcore.LoadApp()
+	return config, data, err
+}
+
+// dayState returns the JSON of a day and whether its unit is running, to detect changes of other processes
+func dayState(dayKey string, data *models.AeonVault) ([]byte, error) {
+	running := data.CurrentRunningUnit != nil && data.CurrentRunningUnit.DayKey == dayKey
+	return json.Marshal(struct {
+		Day     *models.AeonDay
+		Running bool
+	}{data.Days[dayKey], running})
+}
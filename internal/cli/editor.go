@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// defaultEditor is used if neither VISUAL nor EDITOR name an editor
+const defaultEditor = "vi"
+
+// errUnchanged is returned if the file was not changed in the editor
+var errUnchanged = errors.New("nothing has been changed")
+
+// editFile opens a file in the editor of VISUAL or EDITOR and waits until it is closed.
+// The editor may be a command with arguments, like "code --wait".
+func editFile(path string) error {
+	editor := strings.Fields(firstNonEmpty(os.Getenv("VISUAL"), os.Getenv("EDITOR"), defaultEditor))
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error running the editor %s: %w", editor[0], err)
+	}
+	return nil
+}
+
+// Comments added by commentErrors, the header and one line per error
+const (
+	errorCommentHeader = "# The changes are invalid, fix them or save the file unchanged to cancel.\n"
+	errorCommentPrefix = "# ERROR: "
+)
+
+// editUntilValid opens a temporary copy of the content in the editor until parse accepts the edited content.
+// Without annotate, it asks on the terminal whether to edit again after every error. With annotate, it reopens the
+// editor with the edited content annotated with the error, until the content is saved unchanged.
+// It returns errUnchanged if the content was not changed.
+func editUntilValid[T any](content []byte, pattern string, parse func([]byte) (T, error), annotate func([]byte, error) []byte) (T, error) {
+	var result T
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return result, err
+	}
+	defer func(path string) {
+		_ = os.Remove(path)
+	}(file.Name())
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return result, err
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	var lastErr error
+	for {
+		if err = editFile(file.Name()); err != nil {
+			return result, err
+		}
+		edited, err := os.ReadFile(file.Name())
+		if err != nil {
+			return result, err
+		}
+		if bytes.Equal(edited, content) && lastErr != nil {
+			// Saving the annotated content unchanged cancels the edit
+			return result, lastErr
+		}
+		if bytes.Equal(edited, content) {
+			return result, errUnchanged
+		}
+		if result, err = parse(edited); err == nil {
+			return result, nil
+		}
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return result, err
+		}
+		if annotate != nil {
+			content, lastErr = annotate(edited, err), err
+			if err = os.WriteFile(file.Name(), content, 0600); err != nil {
+				return result, err
+			}
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%v\nEdit again? [Y/n] ", err)
+		answer, readErr := reader.ReadString('\n')
+		if readErr != nil || strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "n") {
+			return result, err
+		}
+	}
+}
+
+// commentErrors replaces the error comments at the start of the content with comments on the error, one line per error
+func commentErrors(content []byte, err error) []byte {
+	lines := strings.SplitAfter(string(content), "\n")
+	for len(lines) > 0 && (lines[0] == errorCommentHeader || strings.HasPrefix(lines[0], errorCommentPrefix)) {
+		lines = lines[1:]
+	}
+	var annotated strings.Builder
+	annotated.WriteString(errorCommentHeader)
+	for _, line := range strings.Split(err.Error(), "\n") {
+		annotated.WriteString(errorCommentPrefix + line + "\n")
+	}
+	annotated.WriteString(strings.Join(lines, ""))
+	return []byte(annotated.String())
+}
+
+// firstNonEmpty returns the first value which is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/jame-developer/aeontrac/pkg/commands"
+)
+
+// Exit codes of the CLI, scripts can tell why a command failed without parsing its message
+const (
+	ExitInternal     = 1
+	ExitInvalidInput = 2
+	ExitConflict     = 3
+	ExitInvalidData  = 4
+	ExitUnavailable  = 5
+	ExitNotFound     = 6
+)
+
+// exitCodes maps the kinds of command errors to exit codes
+var exitCodes = map[commands.Kind]int{
+	commands.KindInternal:     ExitInternal,
+	commands.KindInvalidInput: ExitInvalidInput,
+	commands.KindConflict:     ExitConflict,
+	commands.KindInvalidData:  ExitInvalidData,
+	commands.KindUnavailable:  ExitUnavailable,
+	commands.KindNotFound:     ExitNotFound,
+}
+
+// ExitCode returns the exit code for an error returned by Run, 0 for nil.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return exitCodes[commands.KindOf(err)]
+}
+
+// usageError marks errors of arguments and flags, which cobra returns before a command runs
+func usageError(err error) error {
+	var commandErr *commands.Error
+	if errors.As(err, &commandErr) {
+		return err
+	}
+	return &commands.Error{Kind: commands.KindInvalidInput, Err: err}
+}
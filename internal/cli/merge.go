@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jame-developer/aeontrac/internal/appcore"
+	"github.com/jame-developer/aeontrac/pkg/merge"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
+	"golang.org/x/term"
+)
+
+// strategyAsk resolves merge conflicts by asking the user for every conflict.
+const strategyAsk = "ask"
+
+// loadOtherVault loads the time tracking data to merge from a data file or a data folder.
+// Encrypted data is decrypted with the key of the local data, the environment or the passphrase prompt.
+func loadOtherVault(path string, key *vaultcrypt.Key) (models.AeonVault, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return models.AeonVault{}, err
+	}
+	var encrypted bool
+	if info.IsDir() {
+		encrypted, err = repositories.IsAeonVaultEncrypted(path)
+	} else {
+		encrypted, err = repositories.IsAeonVaultFileEncrypted(path)
+	}
+	if err != nil {
+		return models.AeonVault{}, err
+	}
+	if encrypted && key == nil {
+		if key, err = appcore.KeyFromEnvironment(); err != nil {
+			return models.AeonVault{}, err
+		}
+	}
+	if encrypted && key == nil {
+		passphrase, err := readPassword(fmt.Sprintf("Passphrase of %s: ", path), appcore.PassphraseEnv)
+		if err != nil {
+			return models.AeonVault{}, err
+		}
+		if key, err = vaultcrypt.NewPassphraseKey(passphrase); err != nil {
+			return models.AeonVault{}, err
+		}
+	}
+	if info.IsDir() {
+		return repositories.LoadAeonVaultWithKey(path, validator.New(), key)
+	}
+	return repositories.LoadAeonVaultFile(path, validator.New(), key)
+}
+
+// mergeResolver returns the resolver of the named strategy, ask resolves every conflict on the terminal.
+// Without terminal, ask fails on the first conflict.
+func mergeResolver(strategy string) (merge.Resolver, error) {
+	if strategy != strategyAsk {
+		return merge.StrategyResolver(strategy)
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		// Merges without conflicts do not need a terminal
+		return func(merge.Conflict) (merge.Resolution, error) {
+			return merge.KeepLocal, fmt.Errorf("cannot ask for the resolution without a terminal, use --strategy %s, %s or %s",
+				merge.StrategyLocal, merge.StrategyOther, merge.StrategyBoth)
+		}, nil
+	}
+	reader := bufio.NewReader(os.Stdin)
+	return func(conflict merge.Conflict) (merge.Resolution, error) {
+		fmt.Fprintln(os.Stderr, conflict)
+		for {
+			fmt.Fprint(os.Stderr, "Keep [l]ocal, [o]ther or [b]oth, or [a]bort? ")
+			answer, err := reader.ReadString('\n')
+			if err != nil {
+				return merge.KeepLocal, err
+			}
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "l", "local":
+				return merge.KeepLocal, nil
+			case "o", "other":
+				return merge.KeepOther, nil
+			case "b", "both":
+				return merge.KeepBoth, nil
+			case "a", "abort":
+				return merge.KeepLocal, errors.New("merge aborted")
+			}
+		}
+	}, nil
+}
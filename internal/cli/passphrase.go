@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jame-developer/aeontrac/internal/appcore"
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
+	"golang.org/x/term"
+)
+
+// newPassphraseEnv is the environment variable holding the new passphrase for encrypt and rekey, for use without terminal.
+const newPassphraseEnv = "AEONTRAC_NEW_PASSPHRASE"
+
+// promptPassphrase asks for the passphrase of the encrypted time tracking data on the terminal.
+func promptPassphrase() (string, error) {
+	return readPassword("Passphrase: ", appcore.PassphraseEnv)
+}
+
+// newVaultKey returns the key to encrypt the time tracking data with, read from the keyfile if one is provided.
+// Otherwise the new passphrase is taken from the environment or asked for twice on the terminal.
+func newVaultKey(keyFile string) (*vaultcrypt.Key, error) {
+	if keyFile != "" {
+		return vaultcrypt.LoadKeyFile(keyFile)
+	}
+	if passphrase := os.Getenv(newPassphraseEnv); passphrase != "" {
+		return vaultcrypt.NewPassphraseKey(passphrase)
+	}
+	passphrase, err := readPassword("New passphrase: ", newPassphraseEnv)
+	if err != nil {
+		return nil, err
+	}
+	confirmation, err := readPassword("Repeat new passphrase: ", newPassphraseEnv)
+	if err != nil {
+		return nil, err
+	}
+	if passphrase != confirmation {
+		return nil, errors.New("the passphrases do not match")
+	}
+	return vaultcrypt.NewPassphraseKey(passphrase)
+}
+
+// readPassword reads a line from the terminal without echoing it, without terminal the environment variable has to be used instead
+func readPassword(prompt, env string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("cannot ask for the passphrase without a terminal, set %s", env)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
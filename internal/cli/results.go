@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jame-developer/aeontrac/pkg/eventlog"
+	"github.com/jame-developer/aeontrac/pkg/integrity"
+	"github.com/jame-developer/aeontrac/pkg/journal"
+	"github.com/jame-developer/aeontrac/pkg/merge"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+)
+
+// The results of the commands which are not reports, each renders itself as text like the commands printed it before
+type (
+	// historyEntry is an operation of the journal, as listed by history
+	historyEntry struct {
+		ID        int       `json:"id"`
+		Timestamp time.Time `json:"timestamp"`
+		Source    string    `json:"source"`
+		Operation string    `json:"operation"`
+		Arguments string    `json:"arguments,omitempty"`
+		Undone    bool      `json:"undone"`
+	}
+	historyResult []historyEntry
+
+	// backupEntry is a backup of the time tracking data, as listed by backup list
+	backupEntry struct {
+		ID      string    `json:"id"`
+		Created time.Time `json:"created"`
+		Size    int64     `json:"size"`
+		Path    string    `json:"path"`
+	}
+	backupListResult []backupEntry
+
+	// upgradeResult is the result of storage upgrade
+	upgradeResult struct {
+		FromVersion int      `json:"from_version"`
+		ToVersion   int      `json:"to_version"`
+		Required    bool     `json:"required"`
+		DryRun      bool     `json:"dry_run"`
+		Changes     []string `json:"changes"`
+		BackupID    string   `json:"backup_id,omitempty"`
+	}
+
+	// fsckResult lists the problems found by fsck, Repaired is the number of repaired problems
+	fsckResult struct {
+		Problems []integrity.Problem `json:"problems"`
+		Repaired int                 `json:"repaired"`
+	}
+
+	// mergeConflict is a conflict of a merge and how it has been resolved
+	mergeConflict struct {
+		Day        string `json:"day"`
+		Kind       string `json:"kind"`
+		Conflict   string `json:"conflict"`
+		Resolution string `json:"resolution"`
+	}
+	// mergeResult summarizes the changes of merge
+	mergeResult struct {
+		Source      string          `json:"source"`
+		Added       int             `json:"added"`
+		Updated     int             `json:"updated"`
+		Deleted     int             `json:"deleted"`
+		Conflicts   []mergeConflict `json:"conflicts"`
+		ChangedDays []string        `json:"changed_days"`
+	}
+
+	// eventsResult lists events of the event log
+	eventsResult []eventlog.Event
+
+	// profileEntry is a profile, as listed by profile list
+	profileEntry struct {
+		Name   string `json:"name"`
+		Active bool   `json:"active"`
+	}
+	profileListResult []profileEntry
+
+	// setting is a setting of the configuration, Origin is only set by config show --origin
+	setting struct {
+		Key    string `json:"key"`
+		Value  string `json:"value"`
+		Origin string `json:"origin,omitempty"`
+	}
+	settingsResult []setting
+	// settingValue is a setting of which config get prints only the value
+	settingValue setting
+
+	// archiveResult is the result of export-profile and import-profile, Replaced is the archive of the replaced profile
+	archiveResult struct {
+		Message  string `json:"message"`
+		Archive  string `json:"archive"`
+		Files    int    `json:"files"`
+		Replaced string `json:"replaced,omitempty"`
+	}
+)
+
+// newHistoryResult returns the recent entries of the journal, applied reports whether each entry is applied
+func newHistoryResult(entries []journal.Entry, applied []bool) historyResult {
+	result := make(historyResult, len(entries))
+	for i, entry := range entries {
+		result[i] = historyEntry{ID: entry.ID, Timestamp: entry.Timestamp, Source: entry.Source, Operation: entry.Operation, Arguments: entry.Arguments, Undone: !applied[i]}
+	}
+	return result
+}
+
+func (r historyResult) RenderText(w io.Writer) error {
+	if len(r) == 0 {
+		_, err := fmt.Fprintln(w, "No operations recorded.")
+		return err
+	}
+	var text strings.Builder
+	for _, entry := range r {
+		state := ""
+		if entry.Undone {
+			state = "\t(undone)"
+		}
+		description := describeEntry(journal.Entry{Operation: entry.Operation, Arguments: entry.Arguments})
+		fmt.Fprintf(&text, "%4d\t%s\t%s\t%s%s\n", entry.ID, entry.Timestamp.Format(time.DateTime), entry.Source, description, state)
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// newBackupListResult returns the backups as result of backup list
+func newBackupListResult(backups []repositories.Backup) backupListResult {
+	result := make(backupListResult, len(backups))
+	for i, backup := range backups {
+		result[i] = backupEntry{ID: backup.ID, Created: backup.Created, Size: backup.Size, Path: backup.Path}
+	}
+	return result
+}
+
+func (r backupListResult) RenderText(w io.Writer) error {
+	if len(r) == 0 {
+		_, err := fmt.Fprintln(w, "No backups found.")
+		return err
+	}
+	var text strings.Builder
+	text.WriteString("ID\t\tCreated\t\t\tSize\n")
+	for _, backup := range r {
+		fmt.Fprintf(&text, "%s\t%s\t%d\n", backup.ID, backup.Created.Format(time.DateTime), backup.Size)
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+func (r upgradeResult) RenderText(w io.Writer) error {
+	var text strings.Builder
+	switch {
+	case !r.Required:
+		fmt.Fprintf(&text, "The time tracking data is up to date (schema version %d).\n", r.ToVersion)
+	case r.DryRun:
+		fmt.Fprintf(&text, "The time tracking data would be upgraded from schema version %d to %d:\n", r.FromVersion, r.ToVersion)
+	default:
+		fmt.Fprintf(&text, "The time tracking data was upgraded from schema version %d to %d, the original is kept as backup %s:\n", r.FromVersion, r.ToVersion, r.BackupID)
+	}
+	for _, change := range r.Changes {
+		fmt.Fprintf(&text, "  - %s\n", change)
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+func (r fsckResult) RenderText(w io.Writer) error {
+	if len(r.Problems) == 0 {
+		_, err := fmt.Fprintln(w, "No problems found.")
+		return err
+	}
+	var text strings.Builder
+	for _, problem := range r.Problems {
+		fmt.Fprintln(&text, problem)
+	}
+	if r.Repaired == len(r.Problems) {
+		fmt.Fprintf(&text, "%d problems repaired.\n", r.Repaired)
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// newMergeResult returns the summary of a merge of the other data from source
+func newMergeResult(source string, result merge.Result) mergeResult {
+	summary := mergeResult{Source: source, Added: result.Added, Updated: result.Updated, Deleted: result.Deleted,
+		Conflicts: make([]mergeConflict, len(result.Conflicts)), ChangedDays: result.ChangedDays}
+	for i, conflict := range result.Conflicts {
+		summary.Conflicts[i] = mergeConflict{Day: conflict.DayKey, Kind: conflict.Kind, Conflict: conflict.String(), Resolution: conflict.Resolution.String()}
+	}
+	if summary.ChangedDays == nil {
+		summary.ChangedDays = []string{}
+	}
+	return summary
+}
+
+func (r mergeResult) RenderText(w io.Writer) error {
+	var text strings.Builder
+	for _, conflict := range r.Conflicts {
+		fmt.Fprintf(&text, "%s: %s\n", conflict.Conflict, conflict.Resolution)
+	}
+	fmt.Fprintf(&text, "Merged %s: %d units added, %d updated, %d deleted, %d conflicts, %d days changed\n",
+		r.Source, r.Added, r.Updated, r.Deleted, len(r.Conflicts), len(r.ChangedDays))
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+func (r eventsResult) RenderText(w io.Writer) error {
+	var text strings.Builder
+	for _, event := range r {
+		fmt.Fprintf(&text, "%6d\t%s\t%s\t%s\n", event.Sequence, event.Time.Local().Format(time.DateTime), event.Operation, event)
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+func (r profileListResult) RenderText(w io.Writer) error {
+	var text strings.Builder
+	for _, profile := range r {
+		marker := " "
+		if profile.Active {
+			marker = "*"
+		}
+		fmt.Fprintf(&text, "%s %s\n", marker, profile.Name)
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+func (r settingsResult) RenderText(w io.Writer) error {
+	var text strings.Builder
+	for _, s := range r {
+		if s.Origin != "" {
+			fmt.Fprintf(&text, "%-30s %-45s %s\n", s.Key, s.Value, s.Origin)
+		} else {
+			fmt.Fprintf(&text, "%-30s %s\n", s.Key, s.Value)
+		}
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+func (s setting) RenderText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s = %s\n", s.Key, s.Value)
+	return err
+}
+
+func (s settingValue) RenderText(w io.Writer) error {
+	_, err := fmt.Fprintln(w, s.Value)
+	return err
+}
+
+func (r archiveResult) RenderText(w io.Writer) error {
+	if r.Replaced != "" {
+		if _, err := fmt.Fprintf(w, "The replaced profile has been kept in %s\n", r.Replaced); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, r.Message)
+	return err
+}
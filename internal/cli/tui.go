@@ -0,0 +1,470 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fsnotify/fsnotify"
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/internal/appcore"
+	"github.com/jame-developer/aeontrac/pkg/commands"
+	"github.com/jame-developer/aeontrac/pkg/filelock"
+	"github.com/jame-developer/aeontrac/pkg/journal"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/reporting"
+)
+
+const (
+	// holidayDays is the number of days for which the dashboard lists the public holidays
+	holidayDays = 30
+	// changeDebounce collects the changes of files saved together into one reload
+	changeDebounce = 100 * time.Millisecond
+)
+
+var (
+	titleStyle   = lipgloss.NewStyle().Bold(true)
+	dimStyle     = lipgloss.NewStyle().Faint(true)
+	runningStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("2"))
+	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	boxStyle     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+)
+
+// prompt is an action of the dashboard which asks for a comment before it runs
+type prompt int
+
+const (
+	noPrompt prompt = iota
+	startPrompt
+	switchPrompt
+	commentPrompt
+)
+
+// Messages of the dashboard
+type (
+	// tickMsg updates the running unit every second
+	tickMsg time.Time
+	// changedMsg reports that the time tracking data has been changed by any process
+	changedMsg struct{}
+	// loadedMsg carries the reloaded time tracking data
+	loadedMsg struct {
+		config *configuration.Config
+		data   *models.AeonVault
+		err    error
+	}
+	// warningMsg is a warning of loading the time tracking data
+	warningMsg string
+	// watchFailedMsg reports an error of watching the data folder
+	watchFailedMsg struct{ err error }
+	// doneMsg reports the result of an action
+	doneMsg struct {
+		message string
+		err     error
+	}
+)
+
+// dashboard is the full-screen terminal UI of tui. It reads the time tracking data without the lock and reloads it
+// whenever a file of the data folder changes, actions run a locked load-modify-save cycle like the API.
+type dashboard struct {
+	config  *configuration.Config
+	data    *models.AeonVault
+	watcher *fsnotify.Watcher
+	width   int
+	prompt  prompt
+	input   textinput.Model
+	message string
+	failed  bool
+}
+
+// runDashboard shows the dashboard until it is quit
+func runDashboard() error {
+	config, data, dataFolder, err := appcore.LoadDaysReadOnly(dashboardDays)
+	if err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error watching the time tracking data: %w", err)
+	}
+	defer func(watcher *fsnotify.Watcher) {
+		_ = watcher.Close()
+	}(watcher)
+	m := dashboard{config: config, data: data, watcher: watcher, input: textinput.New()}
+	if err = watcher.Add(dataFolder); err != nil {
+		m.message, m.failed = fmt.Sprintf("Changes of other processes are not shown: %v", err), true
+	}
+	program := tea.NewProgram(m, tea.WithAltScreen())
+	// Warnings written to the terminal would break the screen, they are shown like the errors of actions
+	defer func(warnings io.Writer) {
+		appcore.Warnings = warnings
+	}(appcore.Warnings)
+	appcore.Warnings = programWarnings{program: program}
+	_, err = program.Run()
+	return err
+}
+
+func (m dashboard) Init() tea.Cmd {
+	return tea.Batch(tick(), waitForChange(m.watcher))
+}
+
+func (m dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case tickMsg:
+		return m, tick()
+	case changedMsg:
+		return m, tea.Batch(reload, waitForChange(m.watcher))
+	case loadedMsg:
+		if msg.err != nil {
+			m.message, m.failed = msg.err.Error(), true
+			return m, nil
+		}
+		m.config, m.data = msg.config, msg.data
+	case warningMsg:
+		m.message, m.failed = string(msg), true
+	case watchFailedMsg:
+		m.message, m.failed = msg.err.Error(), true
+		return m, waitForChange(m.watcher)
+	case doneMsg:
+		m.message, m.failed = msg.message, msg.err != nil
+		if msg.err != nil {
+			m.message = msg.err.Error()
+		}
+		return m, reload
+	case tea.KeyMsg:
+		if m.prompt != noPrompt {
+			return m.updatePrompt(msg)
+		}
+		return m.updateKey(msg)
+	}
+	return m, nil
+}
+
+// updateKey runs the action of a key
+func (m dashboard) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "s":
+		return m.ask(startPrompt, "")
+	case "w":
+		return m.ask(switchPrompt, "")
+	case "c":
+		return m.ask(commentPrompt, reporting.GetStatus(m.config.WorkingHours, m.data, m.now()).Comment)
+	case "x":
+		return m, update("stop", "", "Time tracking stopped.", func(config *configuration.Config, data *models.AeonVault) error {
+			_, err := commands.StopCommand(nil, config.WorkingHours, data)
+			return err
+		})
+	case "e":
+		dayKey := m.now().Format(time.DateOnly)
+		return m, tea.Exec(editDayExec(dayKey), func(err error) tea.Msg {
+			if errors.Is(err, errUnchanged) {
+				return doneMsg{message: dayKey + " is unchanged."}
+			}
+			if err != nil {
+				return doneMsg{err: fmt.Errorf("%s is not saved: %w", dayKey, err)}
+			}
+			return doneMsg{message: dayKey + " has been saved."}
+		})
+	case "r":
+		return m, reload
+	}
+	return m, nil
+}
+
+// ask shows the input of a comment for an action
+func (m dashboard) ask(p prompt, value string) (tea.Model, tea.Cmd) {
+	m.prompt = p
+	m.input.Prompt = map[prompt]string{startPrompt: "Start with comment: ", switchPrompt: "Switch to comment: ", commentPrompt: "Comment: "}[p]
+	m.input.SetValue(value)
+	m.input.CursorEnd()
+	return m, m.input.Focus()
+}
+
+// updatePrompt edits the comment and runs the action once it is confirmed
+func (m dashboard) updatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.prompt = noPrompt
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		comment := strings.TrimSpace(m.input.Value())
+		p := m.prompt
+		m.prompt = noPrompt
+		m.input.Blur()
+		switch p {
+		case startPrompt:
+			return m, update("start", comment, "Time tracking started.", func(config *configuration.Config, data *models.AeonVault) error {
+				data.CommandComment = comment
+				_, err := commands.StartCommand(nil, config.WorkingHours, data)
+				return err
+			})
+		case switchPrompt:
+			return m, update("switch", comment, "Switched to a new unit of work.", func(config *configuration.Config, data *models.AeonVault) error {
+				data.CommandComment = comment
+				_, err := commands.SwitchCommand(nil, config.WorkingHours, data)
+				return err
+			})
+		default:
+			return m, update("comment", comment, "Comment changed.", func(config *configuration.Config, data *models.AeonVault) error {
+				_, err := commands.CommentCommand(comment, data)
+				return err
+			})
+		}
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m dashboard) View() string {
+	now := m.now()
+	status := reporting.GetStatus(m.config.WorkingHours, m.data, now)
+	today := reporting.GetTodayReport(m.config.WorkingHours, m.data)
+	week := reporting.GetWeekReport(m.config.WorkingHours, m.data, now)
+
+	var view strings.Builder
+	fmt.Fprintf(&view, "%s  %s\n\n", titleStyle.Render("aeontrac"), now.Format("Monday, 2006-01-02 15:04:05"))
+	if status.Running {
+		fmt.Fprintf(&view, "%s since %s  %s\n", runningStyle.Render("⏱ "+status.Elapsed.HMS()), status.Start.In(now.Location()).Format("15:04"), status.Comment)
+	} else {
+		view.WriteString(dimStyle.Render("⏸ No unit of work is running") + "\n")
+	}
+	fmt.Fprintf(&view, "Today %s", status.Today.HMS())
+	if status.Target > 0 {
+		fmt.Fprintf(&view, " of %s, %s left, %d%%", status.Target.HMS(), status.Remaining.HMS(), status.Percentage())
+	}
+	view.WriteString("\n")
+
+	panels := []string{boxStyle.Render(todayPanel(today, 48)), boxStyle.Render(weekPanel(week))}
+	if m.width > 0 && m.width < lipgloss.Width(panels[0])+lipgloss.Width(panels[1]) {
+		view.WriteString(lipgloss.JoinVertical(lipgloss.Left, panels...))
+	} else {
+		view.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, panels...))
+	}
+	view.WriteString("\n")
+	view.WriteString(boxStyle.Render(holidayPanel(reporting.UpcomingHolidays(holidayDays, m.data))) + "\n")
+
+	if m.prompt != noPrompt {
+		view.WriteString(m.input.View() + "\n")
+	} else {
+		view.WriteString(dimStyle.Render("s start  x stop  w switch  c comment  e edit today  r reload  q quit") + "\n")
+	}
+	message := m.message
+	if m.width > 1 {
+		message = truncate(message, m.width)
+	}
+	if m.failed {
+		message = errorStyle.Render(message)
+	}
+	view.WriteString(message)
+	return view.String()
+}
+
+// now returns the current time in the configured time zone
+func (m dashboard) now() time.Time {
+	return time.Now().In(m.config.WorkingHours.Location())
+}
+
+// todayPanel lists the units of today below their timeline of the width
+func todayPanel(today reporting.TodayReport, width int) string {
+	var panel strings.Builder
+	panel.WriteString(titleStyle.Render("Today") + "\n")
+	if len(today.Units) == 0 {
+		panel.WriteString(dimStyle.Render("No time tracked today."))
+		return panel.String()
+	}
+	panel.WriteString(timeline(today.Units, width) + "\n\n")
+	for _, unit := range today.Units {
+		line := fmt.Sprintf("%s – %s  %s  %s", unit.Start[:5], unit.Stop[:5], unit.Duration, unit.Comment)
+		if unit.Running {
+			line = runningStyle.Render(line)
+		}
+		panel.WriteString(truncate(line, width) + "\n")
+	}
+	fmt.Fprintf(&panel, "\nTotal %s  Overtime %s", today.TotalHours, today.Overtime)
+	return panel.String()
+}
+
+// timeline draws the units of today over the hours from the first start to the last stop, with the hours below
+func timeline(units []reporting.TodayReportUnit, width int) string {
+	type span struct {
+		from, to int
+		running  bool
+	}
+	spans := make([]span, 0, len(units))
+	first, last := 24*60, 0
+	for _, unit := range units {
+		from, to := minuteOfDay(unit.Start), minuteOfDay(unit.Stop)
+		if to < from {
+			// The unit ends on the next day
+			to = 24 * 60
+		}
+		spans = append(spans, span{from: from, to: to, running: unit.Running})
+		first, last = min(first, from), max(last, to)
+	}
+	first, last = first/60*60, min((last+59)/60*60, 24*60)
+	if last <= first {
+		last = first + 60
+	}
+	var bar strings.Builder
+	for i := 0; i < width; i++ {
+		from, to := first+i*(last-first)/width, first+(i+1)*(last-first)/width
+		cell := dimStyle.Render("·")
+		for _, s := range spans {
+			if s.from < to && s.to > from {
+				cell = "█"
+				if s.running {
+					cell = runningStyle.Render("█")
+				}
+			}
+		}
+		bar.WriteString(cell)
+	}
+	start, end := fmt.Sprintf("%02d:00", first/60), fmt.Sprintf("%02d:00", last/60)
+	return bar.String() + "\n" + dimStyle.Render(start+strings.Repeat(" ", max(width-len(start)-len(end), 1))+end)
+}
+
+// minuteOfDay returns the minute of the day of a time like 15:04:05
+func minuteOfDay(value string) int {
+	parsed, err := time.Parse(time.TimeOnly, value)
+	if err != nil {
+		return 0
+	}
+	return parsed.Hour()*60 + parsed.Minute()
+}
+
+// weekPanel lists the hours of the days of the week
+func weekPanel(week reporting.WeekReport) string {
+	var panel strings.Builder
+	panel.WriteString(titleStyle.Render("Week "+week.Week) + "\n")
+	for _, day := range week.Days {
+		line := fmt.Sprintf("%s %s  %s  %9s", day.Weekday[:3], day.Day[5:], day.TotalHours, day.OvertimeHours)
+		switch {
+		case day.PublicHoliday:
+			line += "  " + day.PublicHolidayName
+		case day.VacationDay:
+			line += "  vacation"
+		}
+		switch {
+		case day.Today:
+			line = titleStyle.Render(line)
+		case day.WeekEnd || day.PublicHoliday || day.VacationDay:
+			line = dimStyle.Render(line)
+		}
+		panel.WriteString(line + "\n")
+	}
+	fmt.Fprintf(&panel, "\nTotal %s  Overtime %s", week.TotalHours, week.OvertimeHours)
+	return panel.String()
+}
+
+// holidayPanel lists the upcoming public holidays
+func holidayPanel(holidays []string) string {
+	title := titleStyle.Render(fmt.Sprintf("Public holidays of the next %d days", holidayDays))
+	if len(holidays) == 0 {
+		return title + "\n" + dimStyle.Render("None")
+	}
+	return title + "\n" + strings.Join(holidays, "\n")
+}
+
+// truncate shortens a line to the width
+func truncate(line string, width int) string {
+	if runes := []rune(line); len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return line
+}
+
+// tick updates the dashboard in a second
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// reload loads the time tracking data again
+func reload() tea.Msg {
+	config, data, _, err := appcore.LoadDaysReadOnly(dashboardDays)
+	return loadedMsg{config: config, data: data, err: err}
+}
+
+// dashboardDays returns the days shown by the dashboard, from the Monday of this week to the last day of the public holidays
+func dashboardDays(now time.Time) (fromDayKey, toDayKey string) {
+	monday := now.AddDate(0, 0, -(int(now.Weekday())+6)%7)
+	return monday.Format(time.DateOnly), now.AddDate(0, 0, holidayDays).Format(time.DateOnly)
+}
+
+// waitForChange waits for a change of a file of the data folder, apart from the lock and temporary files
+func waitForChange(watcher *fsnotify.Watcher) tea.Cmd {
+	return func() tea.Msg {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return nil
+				}
+				if event.Has(fsnotify.Chmod) || strings.HasPrefix(filepath.Base(event.Name), ".") ||
+					event.Name == filelock.FilePath(filepath.Dir(event.Name)) {
+					continue
+				}
+				// Files saved together, like the data and the journal, are reloaded once
+				drain := time.After(changeDebounce)
+				for draining := true; draining; {
+					select {
+					case <-watcher.Events:
+					case <-drain:
+						draining = false
+					}
+				}
+				return changedMsg{}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return nil
+				}
+				return watchFailedMsg{err: fmt.Errorf("error watching the time tracking data: %w", err)}
+			}
+		}
+	}
+}
+
+// update runs an action in a locked load-modify-save cycle, recorded in the journal like the commands of the CLI
+func update(operation, arguments, message string, action func(config *configuration.Config, data *models.AeonVault) error) tea.Cmd {
+	return func() tea.Msg {
+		if err := appcore.UpdateApp(journal.SourceTUI, operation, arguments, action); err != nil {
+			return doneMsg{err: err}
+		}
+		return doneMsg{message: message}
+	}
+}
+
+// programWarnings shows the warnings written to it in the dashboard
+type programWarnings struct {
+	program *tea.Program
+}
+
+func (w programWarnings) Write(p []byte) (int, error) {
+	// Sending blocks while the program waits for the editor, which may be the writer
+	go w.program.Send(warningMsg(strings.TrimSpace(string(p))))
+	return len(p), nil
+}
+
+// editDayExec edits a day in the editor while the dashboard has released the terminal
+type editDayExec string
+
+func (e editDayExec) Run() error {
+	return editDay(string(e))
+}
+
+// The editor uses the terminal of the process, like edit-day
+func (e editDayExec) SetStdin(io.Reader)  {}
+func (e editDayExec) SetStdout(io.Writer) {}
+func (e editDayExec) SetStderr(io.Writer) {}
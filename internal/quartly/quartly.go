@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"sort"
 	"time"
 )
 
@@ -53,9 +54,9 @@ func Run() {
 	startOfCurrentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
 	startOfTwoMonthsBefore := startOfCurrentMonth.AddDate(0, -2, 0)
 
-	// Initialize maps to store total and overtime hours per week
-	weekHours := make(map[int]time.Duration)
-	weekOvertime := make(map[int]time.Duration)
+	// Initialize maps to store total and overtime hours per week, keyed by ISO year and week, e.g. 2024-W01
+	weekHours := make(map[string]time.Duration)
+	weekOvertime := make(map[string]time.Duration)
 
 	// Parse the dates and aggregate hours per week
 	for dateStr, day := range aeonDays.AeonDays {
@@ -80,16 +81,26 @@ func Run() {
 			overtimeHours = 0
 		}
 
-		// Aggregate hours by week number
-		weekHours[day.IsoWeekNumber] += totalHours
-		weekOvertime[day.IsoWeekNumber] += overtimeHours
+		// Aggregate hours by week, the ISO year keeps weeks of different years apart
+		isoYear, isoWeek := date.ISOWeek()
+		week := fmt.Sprintf("%d-W%02d", isoYear, isoWeek)
+		weekHours[week] += totalHours
+		weekOvertime[week] += overtimeHours
 	}
 
+	// Sort the weeks, their format sorts chronologically
+	weeks := make([]string, 0, len(weekHours))
+	for week := range weekHours {
+		weeks = append(weeks, week)
+	}
+	sort.Strings(weeks)
+
 	// Print total and overtime hours per week
 	fmt.Println("Week Number | Total Hours | Overtime Hours")
 	fmt.Println("-----------------------------------------")
-	for week, total := range weekHours {
+	for _, week := range weeks {
+		total := weekHours[week]
 		overtime := weekOvertime[week]
-		fmt.Printf("Week %d      | %v | %v\n", week, total, overtime)
+		fmt.Printf("%-11s | %v | %v\n", week, total, overtime)
 	}
 }
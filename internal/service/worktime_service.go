@@ -1,84 +1,50 @@
 package service
 
 import (
-	"errors"
+	"fmt"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/internal/appcore"
+	"github.com/jame-developer/aeontrac/pkg/commands"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/journal"
 	"github.com/jame-developer/aeontrac/pkg/models"
 	"github.com/jame-developer/aeontrac/pkg/repositories"
-	"github.com/jame-developer/aeontrac/internal/appcore"
 )
 
-// AddWorkTimeEntry is a placeholder function for adding a work time entry.
+// AddWorkTimeEntry adds a completed unit of work like the add command, errors of the time tracking rules are returned as *commands.Error.
 func AddWorkTimeEntry(request models.WorkTimeRequest) (*models.AeonUnit, error) {
-	// Load the app core to get config, vault and dataFolder
-	config, vault, dataFolder, err := appcore.LoadApp()
-	if err != nil {
-		return nil, err
-	}
-
-	_ = config // config is not used here but loaded for completeness
-
 	// Validate start and stop times
 	startTime, err := time.Parse(time.RFC3339, request.Start)
 	if err != nil {
-		return nil, errors.New("invalid start time format")
+		return nil, fmt.Errorf("invalid start time format: %w", aeonerrors.ErrInvalidTime)
 	}
 	stopTime, err := time.Parse(time.RFC3339, request.Stop)
 	if err != nil {
-		return nil, errors.New("invalid stop time format")
+		return nil, fmt.Errorf("invalid stop time format: %w", aeonerrors.ErrInvalidTime)
 	}
 	if !stopTime.After(startTime) {
-		return nil, errors.New("stop time must be after start time")
-	}
-
-	// Find or create the AeonDay for the given date
-	day, exists := vault.Days[request.Date]
-	if !exists {
-		day = &models.AeonDay{
-			Units: make(map[uuid.UUID]models.AeonUnit),
-		}
-		vault.Days[request.Date] = day
-	}
-
-	// Create a new AeonUnit
-	newID := uuid.New()
-	duration := stopTime.Sub(startTime)
-	newUnit := models.AeonUnit{
-		Start:    &startTime,
-		Stop:     &stopTime,
-		Duration: &models.AeonDuration{Duration: duration},
-		Type:     "WORK",
-		Comment:  request.Comment,
-	}
-
-	// Add the new unit to the day's units map
-	day.Units[newID] = newUnit
-
-	// Recalculate TotalHours and OvertimeHours
-	var totalDuration time.Duration
-	for _, unit := range day.Units {
-		if unit.Duration != nil {
-			totalDuration += unit.Duration.Duration
-		}
+		return nil, fmt.Errorf("stop time must be after start time: %w", aeonerrors.ErrStopTimeBeforeStartTime)
 	}
-	day.TotalHours = &models.AeonDuration{Duration: totalDuration}
 
-	// Calculate overtime as total - 8h if positive
-	eightHours := 8 * time.Hour
-	overtime := totalDuration - eightHours
-	if overtime < 0 {
-		overtime = 0
+	if startTime.Format(time.DateOnly) != request.Date {
+		return nil, fmt.Errorf("start time must be on the date %s: %w", request.Date, aeonerrors.ErrInvalidTime)
 	}
-	day.OvertimeHours = &models.AeonDuration{Duration: overtime}
 
-	// Save the vault
-	err = repositories.SaveAeonVault(dataFolder, *vault)
+	var result commands.UnitResult
+	// Load, change and save the vault while holding the lock on the data folder
+	err = appcore.UpdateApp(journal.SourceAPI, "worktime", request.Start+" "+request.Stop, func(config *configuration.Config, vault *models.AeonVault) error {
+		// Add the unit like the add command, which checks for overlaps and recalculates the hours of the day
+		vault.CommandComment = request.Comment
+		var err error
+		result, err = commands.AddTimeWorkUnitCommand([]string{request.Start, request.Stop}, config.WorkingHours, vault)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Return the new unit
+	newUnit := repositories.NewAeonUnit(&result.Start, result.Stop, request.Comment, result.Duration, repositories.WorkType)
 	return &newUnit, nil
-}
\ No newline at end of file
+}
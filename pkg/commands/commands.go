@@ -2,72 +2,155 @@ package commands
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/jame-developer/aeontrac/configuration"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
 	"github.com/jame-developer/aeontrac/pkg/models"
 	"github.com/jame-developer/aeontrac/pkg/tracking"
-	"os"
-	"time"
 )
 
-// StopCommand stops time tracking
-func StopCommand(args []string, workingHoursConfig configuration.WorkingHoursConfig, a *models.AeonVault) {
-	stopTime, err := parseTimeParam(args, 0)
+// UnitResult describes the unit of work a command started, stopped or added, and the hours of its day afterwards.
+type UnitResult struct {
+	DayKey        string               `json:"day"`
+	UnitID        *uuid.UUID           `json:"unit_id,omitempty"`
+	Start         time.Time            `json:"start"`
+	Stop          *time.Time           `json:"stop,omitempty"`
+	Duration      *models.AeonDuration `json:"duration,omitempty"`
+	TotalHours    *models.AeonDuration `json:"total_hours,omitempty"`
+	OvertimeHours *models.AeonDuration `json:"overtime_hours,omitempty"`
+}
+
+// StopCommand stops time tracking.
+// Errors are returned as *Error instead of exiting, so that callers holding the lock on the data folder can release it.
+func StopCommand(args []string, workingHoursConfig configuration.WorkingHoursConfig, a *models.AeonVault) (UnitResult, error) {
+	stopTime, err := parseTimeParam(args, 0, currentTime(workingHoursConfig))
 	if err != nil {
-		fmt.Println("Error parsing start time:", err)
-		os.Exit(1)
+		return UnitResult{}, commandError("error parsing stop time", err)
 	}
+	running := a.CurrentRunningUnit
 	err = tracking.StopTracking(&stopTime, workingHoursConfig, a)
 	if err != nil {
-		fmt.Printf("Error stopping time tracking: %v\n", err)
-		os.Exit(1)
+		return UnitResult{}, commandError("error stopping time tracking", err)
 	}
-	fmt.Println("Time tracking stopped")
+	return unitResult(a, running.DayKey, running.UnitID), nil
 }
 
 // StartCommand starts time tracking
-func StartCommand(args []string, a *models.AeonVault) {
-	startTime, err := parseTimeParam(args, 0)
+func StartCommand(args []string, workingHoursConfig configuration.WorkingHoursConfig, a *models.AeonVault) (UnitResult, error) {
+	startTime, err := parseTimeParam(args, 0, currentTime(workingHoursConfig))
 	if err != nil {
-		fmt.Println("Error parsing start time:", err)
-		os.Exit(1)
+		return UnitResult{}, commandError("error parsing start time", err)
 	}
-	err = tracking.StartTracking(&startTime, "", a)
+	err = tracking.StartTracking(&startTime, a.CommandComment, a)
 	if err != nil {
-		fmt.Println("Error starting time tracking:", err)
-		os.Exit(1)
+		return UnitResult{}, commandError("error starting time tracking", err)
 	}
+	return unitResult(a, a.CurrentRunningUnit.DayKey, a.CurrentRunningUnit.UnitID), nil
 }
 
-func AddTimeWorkUnitCommand(args []string, workingHoursConfig configuration.WorkingHoursConfig, a *models.AeonVault) {
-	startTime, err := parseTimeParam(args, 0)
+// SwitchCommand stops the running unit of work and starts a new one at the same time, now if no time is provided.
+// The new unit has the comment of the command.
+func SwitchCommand(args []string, workingHoursConfig configuration.WorkingHoursConfig, a *models.AeonVault) (UnitResult, error) {
+	switchTime, err := parseTimeParam(args, 0, currentTime(workingHoursConfig))
 	if err != nil {
-		fmt.Println("Error parsing start time:", err)
-		os.Exit(1)
+		return UnitResult{}, commandError("error parsing switch time", err)
+	}
+	if err = tracking.StopTracking(&switchTime, workingHoursConfig, a); err != nil {
+		return UnitResult{}, commandError("error stopping time tracking", err)
+	}
+	if err = tracking.StartTracking(&switchTime, a.CommandComment, a); err != nil {
+		return UnitResult{}, commandError("error starting time tracking", err)
+	}
+	return unitResult(a, a.CurrentRunningUnit.DayKey, a.CurrentRunningUnit.UnitID), nil
+}
+
+// CommentCommand replaces the comment of the running unit of work.
+func CommentCommand(comment string, a *models.AeonVault) (UnitResult, error) {
+	running := a.CurrentRunningUnit
+	if running == nil {
+		return UnitResult{}, commandError("error commenting the running unit", aeonerrors.ErrNoUnitOfWorkRunning)
+	}
+	day, ok := a.Days[running.DayKey]
+	if !ok {
+		return UnitResult{}, commandError("error commenting the running unit", aeonerrors.ErrUnitNotFound)
+	}
+	unit, ok := day.Units[running.UnitID]
+	if !ok {
+		return UnitResult{}, commandError("error commenting the running unit", aeonerrors.ErrUnitNotFound)
+	}
+	unit.Comment = comment
+	day.Units[running.UnitID] = unit
+	return unitResult(a, running.DayKey, running.UnitID), nil
+}
+
+// AddTimeWorkUnitCommand adds a completed unit of work from the start and stop time in args.
+// With three args, the first is the day of both times, like "yesterday 08:30 12:00".
+// A stop time of day without a day is on the day of the start time.
+func AddTimeWorkUnitCommand(args []string, workingHoursConfig configuration.WorkingHoursConfig, a *models.AeonVault) (UnitResult, error) {
+	if len(args) == 3 {
+		args = []string{args[0] + " " + args[1], args[0] + " " + args[2]}
 	}
-	stopTime, err := parseTimeParam(args, 1)
+	if len(args) != 2 {
+		return UnitResult{}, &Error{Kind: KindInvalidInput, Err: fmt.Errorf("a start and a stop time are required, got %d arguments", len(args))}
+	}
+	now := currentTime(workingHoursConfig)
+	startTime, err := ParseTime(args[0], now)
+	if err != nil {
+		return UnitResult{}, commandError("error parsing start time", err)
+	}
+	stopTime, err := parseTime(args[1], now, startTime)
 	if err != nil {
-		fmt.Println("Error parsing stop time:", err)
-		os.Exit(1)
+		return UnitResult{}, commandError("error parsing stop time", err)
 	}
-	err = tracking.AddTimeWorkUnit(&startTime, &stopTime, "", workingHoursConfig, a)
+	err = tracking.AddTimeWorkUnit(&startTime, &stopTime, a.CommandComment, workingHoursConfig, a)
 	if err != nil {
-		fmt.Println("Error adding time off:", err)
-		os.Exit(1)
+		return UnitResult{}, commandError("error adding time work unit", err)
 	}
+	dayKey := startTime.Format(time.DateOnly)
+	day := a.Days[dayKey]
+	return UnitResult{
+		DayKey:        dayKey,
+		Start:         startTime,
+		Stop:          &stopTime,
+		Duration:      &models.AeonDuration{Duration: stopTime.Sub(startTime)},
+		TotalHours:    day.TotalHours,
+		OvertimeHours: day.OvertimeHours,
+	}, nil
 }
 
-// parseTimeParam parses a time parameter from the command line arguments
-func parseTimeParam(args []string, expectedPos int) (time.Time, error) {
-	paramTime := time.Now()
-	if len(args) >= expectedPos+1 {
-		parsedTime, err := time.Parse(time.DateOnly+"T"+time.TimeOnly, args[expectedPos])
-		if err != nil {
-			return time.Time{}, fmt.Errorf("error parsing time ('%s'): %v", args[expectedPos], err)
-		}
-		paramTime = parsedTime
+// unitResult returns the result of a command for a unit of the vault
+func unitResult(a *models.AeonVault, dayKey string, unitID uuid.UUID) UnitResult {
+	day := a.Days[dayKey]
+	unit := day.Units[unitID]
+	return UnitResult{
+		DayKey:        dayKey,
+		UnitID:        &unitID,
+		Start:         *unit.Start,
+		Stop:          unit.Stop,
+		Duration:      unit.Duration,
+		TotalHours:    day.TotalHours,
+		OvertimeHours: day.OvertimeHours,
 	}
+}
 
-	return paramTime, nil
+// commandError describes why a command failed and classifies the cause
+func commandError(reason string, err error) *Error {
+	return &Error{Kind: KindOf(err), Err: fmt.Errorf("%s: %w", reason, err)}
+}
+
+// parseTimeParam parses a time parameter from the command line arguments, now if it is missing
+func parseTimeParam(args []string, expectedPos int, now time.Time) (time.Time, error) {
+	if len(args) < expectedPos+1 {
+		return now, nil
+	}
+	return ParseTime(args[expectedPos], now)
+}
+
+// currentTime returns the current time in the configured time zone
+func currentTime(workingHoursConfig configuration.WorkingHoursConfig) time.Time {
+	return time.Now().In(workingHoursConfig.Location())
 }
 
 // parseTimeDuration parses a time duration parameter from the command line arguments
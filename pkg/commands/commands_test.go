@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jame-developer/aeontrac/configuration"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/models"
+)
+
+var testWorkingHoursConfig = configuration.WorkingHoursConfig{
+	Enabled:  true,
+	WorkDay:  &models.AeonDuration{Duration: 8 * time.Hour},
+	WorkWeek: &models.AeonDuration{Duration: 40 * time.Hour},
+	TimeZone: "UTC",
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected Kind
+	}{
+		{name: "CommandError", err: &Error{Kind: KindInvalidInput, Err: errors.New("bad flag")}, expected: KindInvalidInput},
+		{name: "WrappedAeonError", err: fmt.Errorf("error starting: %w", aeonerrors.ErrUnitOfWorkRunning), expected: KindConflict},
+		{name: "LockTimeout", err: aeonerrors.ErrLockTimeout, expected: KindUnavailable},
+		{name: "InvalidData", err: fmt.Errorf("the changes are not saved: %w", aeonerrors.ErrInvalidData), expected: KindInvalidData},
+		{name: "OtherError", err: errors.New("disk full"), expected: KindInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, KindOf(tt.err))
+		})
+	}
+}
+
+func TestStartCommand(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		running       bool
+		expectedKind  Kind
+		expectedError error
+	}{
+		{name: "Started", args: []string{"2023-01-01T10:00:00"}},
+		{name: "InvalidTime", args: []string{"10 o'clock"}, expectedKind: KindInvalidInput, expectedError: aeonerrors.ErrInvalidTime},
+		{name: "TimeInFuture", args: []string{time.Now().Add(time.Hour).Format(time.DateOnly + "T" + time.TimeOnly)}, expectedKind: KindInvalidInput, expectedError: aeonerrors.ErrTimeInFuture},
+		{name: "AlreadyRunning", args: []string{"2023-01-01T10:00:00"}, running: true, expectedKind: KindConflict, expectedError: aeonerrors.ErrUnitOfWorkRunning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &models.AeonVault{Days: make(map[string]*models.AeonDay)}
+			if tt.running {
+				_, err := StartCommand([]string{"2022-12-31T10:00:00"}, testWorkingHoursConfig, a)
+				require.NoError(t, err)
+			}
+
+			result, err := StartCommand(tt.args, testWorkingHoursConfig, a)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Equal(t, tt.expectedKind, KindOf(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "2023-01-01", result.DayKey)
+			assert.Equal(t, a.CurrentRunningUnit.UnitID, *result.UnitID)
+			assert.Equal(t, time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC), result.Start)
+			assert.Nil(t, result.Stop)
+		})
+	}
+}
+
+func TestStopCommand(t *testing.T) {
+	a := &models.AeonVault{Days: make(map[string]*models.AeonDay)}
+	_, err := StopCommand([]string{"2023-01-01T12:30:00"}, testWorkingHoursConfig, a)
+	assert.ErrorIs(t, err, aeonerrors.ErrNoUnitOfWorkRunning)
+	assert.Equal(t, KindConflict, KindOf(err))
+
+	started, err := StartCommand([]string{"2023-01-01T10:00:00"}, testWorkingHoursConfig, a)
+	require.NoError(t, err)
+
+	result, err := StopCommand([]string{"2023-01-01T12:30:00"}, testWorkingHoursConfig, a)
+
+	require.NoError(t, err)
+	assert.Equal(t, started.UnitID, result.UnitID)
+	assert.Equal(t, time.Date(2023, 1, 1, 12, 30, 0, 0, time.UTC), *result.Stop)
+	assert.Equal(t, 150*time.Minute, result.Duration.Duration)
+	assert.Nil(t, a.CurrentRunningUnit)
+}
+
+func TestSwitchCommand(t *testing.T) {
+	a := &models.AeonVault{Days: make(map[string]*models.AeonDay)}
+	_, err := SwitchCommand([]string{"2023-01-01T12:30:00"}, testWorkingHoursConfig, a)
+	assert.ErrorIs(t, err, aeonerrors.ErrNoUnitOfWorkRunning)
+	assert.Equal(t, KindConflict, KindOf(err))
+
+	started, err := StartCommand([]string{"2023-01-01T10:00:00"}, testWorkingHoursConfig, a)
+	require.NoError(t, err)
+	a.CommandComment = "+aeontrac review"
+
+	result, err := SwitchCommand([]string{"2023-01-01T12:30:00"}, testWorkingHoursConfig, a)
+
+	require.NoError(t, err)
+	assert.NotEqual(t, started.UnitID, result.UnitID)
+	assert.Equal(t, a.CurrentRunningUnit.UnitID, *result.UnitID)
+	assert.Equal(t, time.Date(2023, 1, 1, 12, 30, 0, 0, time.UTC), result.Start)
+	assert.Equal(t, "+aeontrac review", a.Days["2023-01-01"].Units[*result.UnitID].Comment)
+	stopped := a.Days["2023-01-01"].Units[*started.UnitID]
+	assert.Equal(t, 150*time.Minute, stopped.Duration.Duration)
+}
+
+func TestCommentCommand(t *testing.T) {
+	a := &models.AeonVault{Days: make(map[string]*models.AeonDay)}
+	_, err := CommentCommand("review", a)
+	assert.ErrorIs(t, err, aeonerrors.ErrNoUnitOfWorkRunning)
+	assert.Equal(t, KindConflict, KindOf(err))
+
+	started, err := StartCommand([]string{"2023-01-01T10:00:00"}, testWorkingHoursConfig, a)
+	require.NoError(t, err)
+
+	result, err := CommentCommand("+aeontrac review", a)
+
+	require.NoError(t, err)
+	assert.Equal(t, started.UnitID, result.UnitID)
+	assert.Equal(t, "+aeontrac review", a.Days["2023-01-01"].Units[*result.UnitID].Comment)
+	assert.NotNil(t, a.CurrentRunningUnit)
+}
+
+func TestAddTimeWorkUnitCommand(t *testing.T) {
+	a := &models.AeonVault{Days: make(map[string]*models.AeonDay)}
+
+	result, err := AddTimeWorkUnitCommand([]string{"2023-01-01T10:00:00", "2023-01-01T11:00:00"}, testWorkingHoursConfig, a)
+
+	require.NoError(t, err)
+	assert.Equal(t, "2023-01-01", result.DayKey)
+	assert.Equal(t, time.Hour, result.Duration.Duration)
+
+	result, err = AddTimeWorkUnitCommand([]string{"2023-01-02", "08:30", "12:00"}, testWorkingHoursConfig, a)
+
+	require.NoError(t, err)
+	assert.Equal(t, "2023-01-02", result.DayKey)
+	assert.Equal(t, time.Date(2023, 1, 2, 12, 0, 0, 0, time.UTC), *result.Stop)
+
+	_, err = AddTimeWorkUnitCommand([]string{"2023-01-01T10:00:00", "11 o'clock"}, testWorkingHoursConfig, a)
+	assert.ErrorIs(t, err, aeonerrors.ErrInvalidTime)
+	assert.Equal(t, KindInvalidInput, KindOf(err))
+}
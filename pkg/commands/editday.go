@@ -0,0 +1,309 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/integrity"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/jame-developer/aeontrac/pkg/tracking"
+)
+
+// DayDocument is a day of the time tracking data as a person edits it, with local times and readable durations.
+// The calendar fields and the hours of the day are derived from its date and units.
+type DayDocument struct {
+	VacationDay       bool           `yaml:"vacation_day"`
+	PublicHoliday     bool           `yaml:"public_holiday"`
+	PublicHolidayName string         `yaml:"public_holiday_name,omitempty"`
+	Units             []UnitDocument `yaml:"units"`
+}
+
+// UnitDocument is a unit of work of a DayDocument. A unit without ID is added, a unit without stop and duration is running.
+type UnitDocument struct {
+	ID    string `yaml:"id,omitempty"`
+	Start string `yaml:"start"`
+	Stop  string `yaml:"stop,omitempty"`
+	// Duration like 1h30m replaces the stop time, with a stop time it must match
+	Duration string `yaml:"duration,omitempty"`
+	Type     string `yaml:"type"`
+	Comment  string `yaml:"comment,omitempty"`
+}
+
+// NewDayDocument returns the document of a day with the units ordered by their start, times are in the location.
+func NewDayDocument(dayKey string, a *models.AeonVault, location *time.Location) (DayDocument, error) {
+	day, err := dayOf(dayKey, a)
+	if err != nil {
+		return DayDocument{}, err
+	}
+	doc := DayDocument{VacationDay: day.VacationDay, PublicHoliday: day.PublicHoliday, PublicHolidayName: day.PublicHolidayName, Units: []UnitDocument{}}
+	shortIDs := ShortIDs(a)
+	unitIDs := make([]uuid.UUID, 0, len(day.Units))
+	for unitID, unit := range day.Units {
+		if unit.Start != nil {
+			unitIDs = append(unitIDs, unitID)
+		}
+	}
+	sort.Slice(unitIDs, func(i, j int) bool {
+		return day.Units[unitIDs[i]].Start.Before(*day.Units[unitIDs[j]].Start)
+	})
+	for _, unitID := range unitIDs {
+		unit := day.Units[unitID]
+		unitDoc := UnitDocument{ID: shortIDs[unitID], Start: documentTime(*unit.Start, dayKey, location), Type: unit.Type, Comment: unit.Comment}
+		if unit.Stop != nil {
+			unitDoc.Stop = documentTime(*unit.Stop, dayKey, location)
+		}
+		doc.Units = append(doc.Units, unitDoc)
+	}
+	return doc, nil
+}
+
+// YAML returns the document as YAML, with comments on the day and how to edit it. The durations of the units
+// are comments on their stop times, so that changing a time does not contradict them.
+func (doc DayDocument) YAML(dayKey string, a *models.AeonVault, location *time.Location) ([]byte, error) {
+	date, err := time.ParseInLocation(time.DateOnly, dayKey, location)
+	if err != nil {
+		return nil, &Error{Kind: KindInvalidInput, Err: fmt.Errorf("%s is not a day like 2006-01-02", dayKey)}
+	}
+	var node yaml.Node
+	if err = node.Encode(doc); err != nil {
+		return nil, err
+	}
+	if units := mappingValue(&node, "units"); units != nil {
+		now := time.Now().In(location)
+		for i, unitNode := range units.Content {
+			unit, err := documentUnit(doc.Units[i], now, date)
+			if stop := mappingValue(unitNode, "stop"); stop != nil && err == nil && unit.Duration != nil {
+				stop.LineComment = readableDuration(unit.Duration.Duration)
+			}
+		}
+	}
+	var content bytes.Buffer
+	encoder := yaml.NewEncoder(&content)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	var header bytes.Buffer
+	fmt.Fprintf(&header, "# %s (%s)", dayKey, date.Weekday())
+	if day, ok := a.Days[dayKey]; ok && day.TotalHours != nil {
+		fmt.Fprintf(&header, ", total %s", readableDuration(day.TotalHours.Duration))
+		if day.OvertimeHours != nil {
+			fmt.Fprintf(&header, ", overtime %s", readableDuration(day.OvertimeHours.Duration))
+		}
+	}
+	header.WriteString("\n# Times are local times of day like 08:30, a unit without stop is running. A duration like\n" +
+		"# 'duration: 1h30m' may replace the stop. Remove a unit to delete it, add a unit without id to create it.\n" +
+		"# The hours of the day are recalculated when the file is saved.\n")
+	return append(header.Bytes(), content.Bytes()...), nil
+}
+
+// ParseDayDocument parses a day document written by DayDocument.YAML, unknown fields are errors.
+func ParseDayDocument(content []byte) (DayDocument, error) {
+	var doc DayDocument
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return DayDocument{}, &Error{Kind: KindInvalidInput, Err: err}
+	}
+	return doc, nil
+}
+
+// EditDayCommand replaces the day with the edited document. The day is only changed if the units are valid,
+// do not overlap and start on the day; otherwise all problems are returned, naming the units by their position.
+func EditDayCommand(dayKey string, doc DayDocument, workingHoursConfig configuration.WorkingHoursConfig, a *models.AeonVault) error {
+	original, err := dayOf(dayKey, a)
+	if err != nil {
+		return err
+	}
+	location := workingHoursConfig.Location()
+	date, _ := time.ParseInLocation(time.DateOnly, dayKey, location)
+	now := time.Now().In(location)
+
+	day := *original
+	calendar := repositories.NewAoenDay(date)
+	day.IsoWeekNumber, day.IsoWeekDay, day.WeekEnd = calendar.IsoWeekNumber, calendar.IsoWeekDay, calendar.WeekEnd
+	day.VacationDay, day.PublicHoliday, day.PublicHolidayName = doc.VacationDay, doc.PublicHoliday, doc.PublicHolidayName
+	day.Units = make(map[uuid.UUID]models.AeonUnit, len(doc.Units))
+	labels := map[uuid.UUID]string{}
+	var running *models.AeonCurrentRunningUnit
+	var errs []error
+	for i, unitDoc := range doc.Units {
+		label := fmt.Sprintf("units[%d]", i)
+		unitID, err := documentUnitID(unitDoc.ID, original)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s.id: %w", label, err))
+			continue
+		}
+		if other, ok := labels[unitID]; ok {
+			errs = append(errs, fmt.Errorf("%s.id: is the ID of %s as well", label, other))
+			continue
+		}
+		labels[unitID] = label
+		unit, err := documentUnit(unitDoc, now, date)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s.%w", label, err))
+			continue
+		}
+		if unit.Stop == nil {
+			running = &models.AeonCurrentRunningUnit{DayKey: dayKey, UnitID: unitID}
+			if a.CurrentRunningUnit != nil && a.CurrentRunningUnit.DayKey != dayKey {
+				errs = append(errs, fmt.Errorf("%s.stop: is required, unit %s of %s is running", label, a.CurrentRunningUnit.UnitID, a.CurrentRunningUnit.DayKey))
+			}
+		}
+		day.Units[unitID] = unit
+	}
+	if len(errs) > 0 {
+		return &Error{Kind: KindInvalidInput, Err: errors.Join(errs...)}
+	}
+	tracking.RecalculateDay(&day, workingHoursConfig)
+
+	v := validator.New()
+	repositories.RegisterValidations(v)
+	var validationErr *repositories.ValidationError
+	if err = repositories.ValidateDay(dayKey, &day, v); errors.As(err, &validationErr) {
+		label := "day"
+		if validationErr.UnitID != nil {
+			label = labels[*validationErr.UnitID]
+		}
+		errs = append(errs, fmt.Errorf("%s: %s %s", label, validationErr.Field, validationErr.Reason))
+	} else if err != nil {
+		return err
+	}
+	edited := &models.AeonVault{Days: map[string]*models.AeonDay{dayKey: &day}, CurrentRunningUnit: running}
+	for _, problem := range integrity.Check(edited, workingHoursConfig) {
+		message := problem.Message
+		for unitID, label := range labels {
+			message = strings.ReplaceAll(message, "unit "+unitID.String(), label)
+		}
+		label := "day"
+		if problem.UnitID != uuid.Nil {
+			label = labels[problem.UnitID]
+		}
+		errs = append(errs, fmt.Errorf("%s: %s", label, message))
+	}
+	if len(errs) > 0 {
+		return &Error{Kind: KindInvalidInput, Err: errors.Join(errs...)}
+	}
+
+	a.Days[dayKey] = &day
+	if running != nil || (a.CurrentRunningUnit != nil && a.CurrentRunningUnit.DayKey == dayKey) {
+		a.CurrentRunningUnit = running
+	}
+	return nil
+}
+
+// dayOf returns a day of the vault, a valid day which has not been created yet is empty
+func dayOf(dayKey string, a *models.AeonVault) (*models.AeonDay, error) {
+	if day, ok := a.Days[dayKey]; ok {
+		return day, nil
+	}
+	date, err := time.Parse(time.DateOnly, dayKey)
+	if err != nil {
+		return nil, &Error{Kind: KindInvalidInput, Err: fmt.Errorf("%s is not a day like 2006-01-02", dayKey)}
+	}
+	return repositories.NewAoenDay(date), nil
+}
+
+// documentUnitID returns the ID of a unit of the day by a prefix, or a new ID for an added unit
+func documentUnitID(prefix string, day *models.AeonDay) (uuid.UUID, error) {
+	if prefix == "" {
+		return uuid.New(), nil
+	}
+	dayVault := &models.AeonVault{Days: map[string]*models.AeonDay{"": day}}
+	_, unitID, err := ResolveUnit(prefix, dayVault)
+	if KindOf(err) == KindNotFound {
+		return uuid.Nil, fmt.Errorf("no unit of the day has the ID %s, remove the id to add a unit", prefix)
+	}
+	return unitID, err
+}
+
+// documentUnit returns the unit of a unit document, times of day are on the date
+func documentUnit(unitDoc UnitDocument, now, date time.Time) (models.AeonUnit, error) {
+	unit := models.AeonUnit{Type: strings.ToUpper(unitDoc.Type), Comment: unitDoc.Comment}
+	if unit.Type == "" {
+		unit.Type = repositories.WorkType
+	}
+	if unitDoc.Start == "" {
+		return unit, errors.New("start: is required")
+	}
+	start, err := parseTime(unitDoc.Start, now, date)
+	if err != nil {
+		return unit, fmt.Errorf("start: %w", err)
+	}
+	unit.Start = &start
+	var duration *time.Duration
+	if unitDoc.Duration != "" {
+		d, err := time.ParseDuration(strings.ReplaceAll(unitDoc.Duration, " ", ""))
+		if err != nil {
+			return unit, errors.New("duration: must be a duration like 1h30m")
+		}
+		duration = &d
+	}
+	switch {
+	case unitDoc.Stop != "":
+		stop, err := parseTime(unitDoc.Stop, now, date)
+		if err != nil {
+			return unit, fmt.Errorf("stop: %w", err)
+		}
+		if duration != nil && *duration != stop.Sub(start) {
+			return unit, fmt.Errorf("duration: does not match stop - start of %s, remove the stop or the duration", readableDuration(stop.Sub(start)))
+		}
+		unit.Stop = &stop
+	case duration != nil:
+		stop := start.Add(*duration)
+		unit.Stop = &stop
+	}
+	if unit.Stop != nil {
+		unit.Duration = &models.AeonDuration{Duration: unit.Stop.Sub(start)}
+	}
+	return unit, nil
+}
+
+// mappingValue returns the value of a key of a YAML mapping node, nil if the node has no such key
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		node = node.Content[0]
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// documentTime formats a time as time of day in the location, with the date if it is not on the day
+func documentTime(t time.Time, dayKey string, location *time.Location) string {
+	t = t.In(location)
+	layout := "15:04"
+	if t.Second() != 0 {
+		layout = time.TimeOnly
+	}
+	if t.Format(time.DateOnly) != dayKey {
+		layout = time.DateOnly + " " + layout
+	}
+	return t.Format(layout)
+}
+
+// readableDuration formats a duration without zero minutes and seconds, like 1h30m or 8h
+func readableDuration(d time.Duration) string {
+	text := d.Round(time.Second).String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
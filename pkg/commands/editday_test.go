@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jame-developer/aeontrac/pkg/models"
+)
+
+const editDayTestUnitID = "0ccc98e9-5195-4440-987b-b5101ebe15dc"
+
+func TestDayDocumentRoundTrip(t *testing.T) {
+	a := newUnitsTestVault([]string{editDayTestUnitID}, "+aeontrac review")
+
+	doc, err := NewDayDocument("2024-03-01", a, time.UTC)
+	require.NoError(t, err)
+	content, err := doc.YAML("2024-03-01", a, time.UTC)
+	require.NoError(t, err)
+	parsed, err := ParseDayDocument(content)
+	require.NoError(t, err)
+
+	assert.Equal(t, []UnitDocument{{ID: "0ccc98e", Start: "09:00", Stop: "10:00", Type: "WORK", Comment: "+aeontrac review"}}, doc.Units)
+	assert.Contains(t, string(content), "# 2024-03-01 (Friday)")
+	assert.Contains(t, string(content), `stop: "10:00" # 1h`)
+	assert.Equal(t, doc, parsed)
+}
+
+func TestParseDayDocumentUnknownField(t *testing.T) {
+	_, err := ParseDayDocument([]byte("units:\n  - start: \"09:00\"\n    end: \"10:00\"\n"))
+
+	require.Error(t, err)
+	assert.Equal(t, KindInvalidInput, KindOf(err))
+}
+
+func TestEditDayCommand(t *testing.T) {
+	tests := []struct {
+		name          string
+		units         []UnitDocument
+		expectedTotal time.Duration
+		expectedUnits int
+		expectedError string
+	}{
+		{
+			name: "ChangeTimesAndAddUnitByDuration",
+			units: []UnitDocument{
+				{ID: "0ccc98e", Start: "08:00", Stop: "12:00", Type: "work"},
+				{Start: "13:00", Duration: "1h 30m", Comment: "+aeontrac"},
+			},
+			expectedTotal: 5*time.Hour + 30*time.Minute,
+			expectedUnits: 2,
+		},
+		{
+			name:          "RemoveUnit",
+			units:         []UnitDocument{},
+			expectedUnits: 0,
+		},
+		{
+			name: "Overlap",
+			units: []UnitDocument{
+				{ID: "0ccc98e", Start: "09:00", Stop: "11:00"},
+				{Start: "10:00", Stop: "12:00"},
+			},
+			expectedError: "the unit overlaps units[",
+		},
+		{
+			name:          "UnknownID",
+			units:         []UnitDocument{{ID: "7a819d2", Start: "09:00", Stop: "10:00"}},
+			expectedError: "units[0].id: no unit of the day has the ID 7a819d2",
+		},
+		{
+			name:          "DurationDoesNotMatch",
+			units:         []UnitDocument{{Start: "09:00", Stop: "10:00", Duration: "2h"}},
+			expectedError: "units[0].duration: does not match stop - start of 1h",
+		},
+		{
+			name:          "InvalidStart",
+			units:         []UnitDocument{{Start: "9 o'clock", Stop: "10:00"}},
+			expectedError: "units[0].start: ",
+		},
+		{
+			name:          "StopBeforeStart",
+			units:         []UnitDocument{{Start: "10:00", Stop: "09:00"}},
+			expectedError: "units[0]: ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newUnitsTestVault([]string{editDayTestUnitID})
+			original := a.Days["2024-03-01"]
+
+			err := EditDayCommand("2024-03-01", DayDocument{Units: tt.units}, testWorkingHoursConfig, a)
+
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, KindInvalidInput, KindOf(err))
+				assert.Contains(t, err.Error(), tt.expectedError)
+				assert.Same(t, original, a.Days["2024-03-01"])
+				return
+			}
+			require.NoError(t, err)
+			day := a.Days["2024-03-01"]
+			assert.Len(t, day.Units, tt.expectedUnits)
+			require.NotNil(t, day.TotalHours)
+			assert.Equal(t, tt.expectedTotal, day.TotalHours.Duration)
+			if tt.expectedUnits > 0 {
+				assert.Equal(t, "WORK", day.Units[uuid.MustParse(editDayTestUnitID)].Type)
+			}
+		})
+	}
+}
+
+func TestEditDayCommandRunningUnit(t *testing.T) {
+	a := newUnitsTestVault([]string{editDayTestUnitID})
+
+	err := EditDayCommand("2024-03-01", DayDocument{Units: []UnitDocument{{ID: "0ccc98e", Start: "09:00"}}}, testWorkingHoursConfig, a)
+
+	require.NoError(t, err)
+	assert.Equal(t, &models.AeonCurrentRunningUnit{DayKey: "2024-03-01", UnitID: uuid.MustParse(editDayTestUnitID)}, a.CurrentRunningUnit)
+	assert.Nil(t, a.Days["2024-03-01"].Units[uuid.MustParse(editDayTestUnitID)].Stop)
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"errors"
+
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+)
+
+// Kind classifies why a command failed, the CLI maps it to an exit code and the API to an HTTP status.
+type Kind int
+
+const (
+	// KindInternal is an unexpected failure, like a file which cannot be written
+	KindInternal Kind = iota
+	// KindInvalidInput are arguments which cannot be used, like a time which cannot be parsed
+	KindInvalidInput
+	// KindConflict is a command the state of the time tracking data does not allow, like starting a second unit of work
+	KindConflict
+	// KindInvalidData is time tracking data which fails the validation
+	KindInvalidData
+	// KindUnavailable is time tracking data which cannot be accessed now, because another process holds the lock or the key is missing
+	KindUnavailable
+	// KindNotFound is a unit of work which does not exist
+	KindNotFound
+)
+
+// kindNames are the names of the kinds in logs
+var kindNames = map[Kind]string{
+	KindInternal:     "internal",
+	KindInvalidInput: "invalid input",
+	KindConflict:     "conflict",
+	KindInvalidData:  "invalid data",
+	KindUnavailable:  "unavailable",
+	KindNotFound:     "not found",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// kinds classifies the errors of the time tracking rules and of the access to the time tracking data
+var kinds = map[aeonerrors.AeonError]Kind{
+	aeonerrors.ErrInvalidTime:              KindInvalidInput,
+	aeonerrors.ErrAmbiguousUnitID:          KindInvalidInput,
+	aeonerrors.ErrUnitNotFound:             KindNotFound,
+	aeonerrors.ErrTimeInFuture:             KindInvalidInput,
+	aeonerrors.ErrStopTimeBeforeStartTime:  KindInvalidInput,
+	aeonerrors.ErrUnitOfWorkRunning:        KindConflict,
+	aeonerrors.ErrNoUnitOfWorkRunning:      KindConflict,
+	aeonerrors.ErrTimeWithinCompletedUnit:  KindConflict,
+	aeonerrors.ErrCompensationOnNonWorkDay: KindConflict,
+	aeonerrors.ErrNothingToUndo:            KindConflict,
+	aeonerrors.ErrNothingToRedo:            KindConflict,
+	aeonerrors.ErrJournalConflict:          KindConflict,
+	aeonerrors.ErrDayChanged:               KindConflict,
+	aeonerrors.ErrInvalidData:              KindInvalidData,
+	aeonerrors.ErrUnsupportedSchemaVersion: KindInvalidData,
+	aeonerrors.ErrLockTimeout:              KindUnavailable,
+	aeonerrors.ErrStaleLock:                KindUnavailable,
+	aeonerrors.ErrVaultLocked:              KindUnavailable,
+	aeonerrors.ErrWrongKey:                 KindUnavailable,
+}
+
+// Error is the error of a command with its kind.
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the cause, so the errors of the time tracking rules can be matched with errors.Is.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the kind of an error: the kind of the first command error in its chain, or the kind of its AeonError.
+// All other errors are internal.
+func KindOf(err error) Kind {
+	var commandError *Error
+	if errors.As(err, &commandError) {
+		return commandError.Kind
+	}
+	var aeonError aeonerrors.AeonError
+	if errors.As(err, &aeonError) {
+		if kind, ok := kinds[aeonError]; ok {
+			return kind
+		}
+	}
+	return KindInternal
+}
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+)
+
+// dateTimeLayouts are the layouts of points in time with a date, without an offset they are in the location of now
+var dateTimeLayouts = []string{
+	time.RFC3339,
+	time.DateOnly + "T" + time.TimeOnly,
+	time.DateOnly + "T15:04",
+	time.DateTime,
+	time.DateOnly + " 15:04",
+}
+
+// clockLayouts are the layouts of times of day, the hour may have a single digit
+var clockLayouts = []string{time.TimeOnly, "15:04"}
+
+// weekdays are the names of the days of the week and their abbreviations
+var weekdays = map[string]time.Weekday{}
+
+func init() {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		weekdays[name] = day
+		weekdays[name[:3]] = day
+	}
+}
+
+// ParseTime parses the point in time of a command argument in the location of now. It accepts
+//   - now, @now or an offset to now like -10m, +1h30m or @now-10m
+//   - a time of day today like 9:15 or 17:30:00
+//   - a day and a time of day like "yesterday 08:30", "friday 13:00", "last friday 13:00" or "2024-03-01 08:30",
+//     a weekday is the last one up to today, "last" excludes today
+//   - a date and time like 2024-03-01T08:30:00, or with an offset like 2024-03-01T08:30:00+01:00
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	return parseTime(value, now, now)
+}
+
+// ParseDay parses a day like the day of ParseTime, like today, yesterday, "last friday" or 2024-03-01,
+// and returns its start in the location of now.
+func ParseDay(value string, now time.Time) (time.Time, error) {
+	day, ok := parseDay(strings.Fields(strings.ToLower(value)), now)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: '%s' is not a day like yesterday, \"last friday\" or 2006-01-02", aeonerrors.ErrInvalidTime, value)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, now.Location()), nil
+}
+
+// parseTime parses a point in time like ParseTime, a time of day without a day is on the day of today
+func parseTime(value string, now, today time.Time) (time.Time, error) {
+	fields := strings.Fields(strings.ToLower(value))
+	if len(fields) == 0 {
+		return time.Time{}, invalidTime(value)
+	}
+	if parsed, ok := parseRelative(strings.Join(fields, ""), now); ok {
+		return parsed, nil
+	}
+	for _, layout := range dateTimeLayouts {
+		if parsed, err := time.ParseInLocation(layout, strings.TrimSpace(value), now.Location()); err == nil {
+			return parsed, nil
+		}
+	}
+	day := today
+	if len(fields) > 1 {
+		var ok bool
+		if day, ok = parseDay(fields[:len(fields)-1], now); !ok {
+			return time.Time{}, invalidTime(value)
+		}
+	}
+	for _, layout := range clockLayouts {
+		if clock, err := time.Parse(layout, fields[len(fields)-1]); err == nil {
+			return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, now.Location()), nil
+		}
+	}
+	return time.Time{}, invalidTime(value)
+}
+
+// parseRelative parses now and offsets to now
+func parseRelative(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimPrefix(value, "@")
+	if value == "now" {
+		return now, true
+	}
+	value = strings.TrimPrefix(value, "now")
+	if !strings.HasPrefix(value, "-") && !strings.HasPrefix(value, "+") {
+		return time.Time{}, false
+	}
+	offset, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return now.Add(offset), true
+}
+
+// parseDay parses the day of a point in time relative to now
+func parseDay(fields []string, now time.Time) (time.Time, bool) {
+	last := false
+	if len(fields) == 2 && fields[0] == "last" {
+		last = true
+		fields = fields[1:]
+	}
+	if len(fields) != 1 {
+		return time.Time{}, false
+	}
+	if weekday, ok := weekdays[fields[0]]; ok {
+		daysBack := (int(now.Weekday()) - int(weekday) + 7) % 7
+		if daysBack == 0 && last {
+			daysBack = 7
+		}
+		return now.AddDate(0, 0, -daysBack), true
+	}
+	if last {
+		return time.Time{}, false
+	}
+	switch fields[0] {
+	case "today":
+		return now, true
+	case "yesterday":
+		return now.AddDate(0, 0, -1), true
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), true
+	}
+	day, err := time.ParseInLocation(time.DateOnly, fields[0], now.Location())
+	return day, err == nil
+}
+
+// invalidTime describes the points in time ParseTime accepts
+func invalidTime(value string) error {
+	return fmt.Errorf("%w: '%s' is not a time like 9:15, -10m, \"yesterday 08:30\" or 2006-01-02T15:04:05", aeonerrors.ErrInvalidTime, value)
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+)
+
+func TestParseTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	// A Wednesday
+	now := time.Date(2024, 3, 13, 14, 45, 30, 0, berlin)
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Time
+		invalid  bool
+	}{
+		{name: "Now", value: "now", expected: now},
+		{name: "AtNow", value: "@now", expected: now},
+		{name: "NegativeOffset", value: "-10m", expected: now.Add(-10 * time.Minute)},
+		{name: "PositiveOffset", value: "+1h30m", expected: now.Add(90 * time.Minute)},
+		{name: "OffsetToNow", value: "@now-2h", expected: now.Add(-2 * time.Hour)},
+		{name: "OffsetToNowWithoutAt", value: "now-15m", expected: now.Add(-15 * time.Minute)},
+		{name: "TimeOfDay", value: "9:15", expected: time.Date(2024, 3, 13, 9, 15, 0, 0, berlin)},
+		{name: "TimeOfDayWithSeconds", value: "17:30:15", expected: time.Date(2024, 3, 13, 17, 30, 15, 0, berlin)},
+		{name: "Today", value: "today 08:00", expected: time.Date(2024, 3, 13, 8, 0, 0, 0, berlin)},
+		{name: "Yesterday", value: "yesterday 08:30", expected: time.Date(2024, 3, 12, 8, 30, 0, 0, berlin)},
+		{name: "Tomorrow", value: "Tomorrow 08:30", expected: time.Date(2024, 3, 14, 8, 30, 0, 0, berlin)},
+		{name: "Weekday", value: "friday 13:00", expected: time.Date(2024, 3, 8, 13, 0, 0, 0, berlin)},
+		{name: "LastWeekday", value: "last friday 13:00", expected: time.Date(2024, 3, 8, 13, 0, 0, 0, berlin)},
+		{name: "WeekdayIsToday", value: "wed 13:00", expected: time.Date(2024, 3, 13, 13, 0, 0, 0, berlin)},
+		{name: "LastWeekdayIsToday", value: "last wednesday 13:00", expected: time.Date(2024, 3, 6, 13, 0, 0, 0, berlin)},
+		{name: "DateAndTimeOfDay", value: "2024-03-01 08:30", expected: time.Date(2024, 3, 1, 8, 30, 0, 0, berlin)},
+		{name: "DateTime", value: "2024-03-01T08:30:00", expected: time.Date(2024, 3, 1, 8, 30, 0, 0, berlin)},
+		{name: "DateTimeWithoutSeconds", value: "2024-03-01T08:30", expected: time.Date(2024, 3, 1, 8, 30, 0, 0, berlin)},
+		{name: "DateTimeWithOffset", value: "2024-03-01T08:30:00Z", expected: time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)},
+		{name: "DaylightSavingTime", value: "2024-04-01 08:30", expected: time.Date(2024, 4, 1, 6, 30, 0, 0, time.UTC)},
+		{name: "Empty", value: " ", invalid: true},
+		{name: "InvalidTimeOfDay", value: "25:00", invalid: true},
+		{name: "InvalidDay", value: "someday 08:00", invalid: true},
+		{name: "LastWithoutWeekday", value: "last yesterday 08:00", invalid: true},
+		{name: "DayWithoutTime", value: "yesterday", invalid: true},
+		{name: "InvalidOffset", value: "-10 minutes", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseTime(tt.value, now)
+
+			if tt.invalid {
+				assert.ErrorIs(t, err, aeonerrors.ErrInvalidTime)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(parsed), "expected %s, got %s", tt.expected, parsed)
+		})
+	}
+}
+
+func TestParseDay(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2024, 3, 13, 14, 45, 30, 0, time.UTC)
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Time
+		invalid  bool
+	}{
+		{name: "Today", value: "today", expected: time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)},
+		{name: "Yesterday", value: "yesterday", expected: time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{name: "LastWeekday", value: "last monday", expected: time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{name: "Date", value: "2024-02-29", expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "TimeOfDay", value: "08:00", invalid: true},
+		{name: "UnitID", value: "7a819d2", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseDay(tt.value, now)
+
+			if tt.invalid {
+				assert.ErrorIs(t, err, aeonerrors.ErrInvalidTime)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, parsed)
+		})
+	}
+}
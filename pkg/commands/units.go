@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/models"
+)
+
+// MinShortIDLength is the minimum length of short unit IDs, they are longer if the prefix is shared by several units.
+const MinShortIDLength = 7
+
+// UnitEntry is a unit of work with its ID and day.
+type UnitEntry struct {
+	ID       uuid.UUID            `json:"id"`
+	ShortID  string               `json:"short_id"`
+	DayKey   string               `json:"day"`
+	Start    time.Time            `json:"start"`
+	Stop     *time.Time           `json:"stop,omitempty"`
+	Duration *models.AeonDuration `json:"duration,omitempty"`
+	Type     string               `json:"type"`
+	Comment  string               `json:"comment,omitempty"`
+	Projects []string             `json:"projects,omitempty"`
+	Running  bool                 `json:"running"`
+}
+
+// LogFilter selects the units of work listed by LogCommand, zero values select all units.
+type LogFilter struct {
+	// From and To are the first and last day of the units, in the location of the time tracking
+	From, To time.Time
+	// Project is a project tagged in the comment, like +aeontrac
+	Project string
+	// Type is the type of the units, WORK or COMPENSATORY
+	Type string
+}
+
+// LogCommand lists the units of work which match the filter, ordered by their start.
+func LogCommand(filter LogFilter, a *models.AeonVault) []UnitEntry {
+	shortIDs := ShortIDs(a)
+	var entries []UnitEntry
+	for dayKey, day := range a.Days {
+		if !filter.From.IsZero() && dayKey < filter.From.Format(time.DateOnly) {
+			continue
+		}
+		if !filter.To.IsZero() && dayKey > filter.To.Format(time.DateOnly) {
+			continue
+		}
+		for unitID, unit := range day.Units {
+			if unit.Start == nil || (filter.Type != "" && !strings.EqualFold(unit.Type, filter.Type)) {
+				continue
+			}
+			entry := unitEntry(dayKey, unitID, unit, shortIDs[unitID])
+			if filter.Project != "" && !hasProject(entry.Projects, filter.Project) {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Start.Before(entries[j].Start)
+	})
+	return entries
+}
+
+// FindUnit returns the unit of work whose ID starts with the prefix, see ResolveUnit.
+func FindUnit(prefix string, a *models.AeonVault) (UnitEntry, error) {
+	dayKey, unitID, err := ResolveUnit(prefix, a)
+	if err != nil {
+		return UnitEntry{}, err
+	}
+	unit := a.Days[dayKey].Units[unitID]
+	if unit.Start == nil {
+		return UnitEntry{}, &Error{Kind: KindInvalidData, Err: fmt.Errorf("%w: unit %s has no start", aeonerrors.ErrInvalidData, unitID)}
+	}
+	return unitEntry(dayKey, unitID, unit, ShortIDs(a)[unitID]), nil
+}
+
+// ResolveUnit returns the day and ID of the unit of work whose ID starts with the prefix, like git resolves short commit IDs.
+// A prefix matching no unit is ErrUnitNotFound, a prefix matching several units is ErrAmbiguousUnitID.
+func ResolveUnit(prefix string, a *models.AeonVault) (string, uuid.UUID, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return "", uuid.Nil, &Error{Kind: KindInvalidInput, Err: errors.New("the unit ID is empty")}
+	}
+	var dayKey string
+	var matches []uuid.UUID
+	for key, day := range a.Days {
+		for unitID := range day.Units {
+			if strings.HasPrefix(unitID.String(), prefix) {
+				dayKey = key
+				matches = append(matches, unitID)
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", uuid.Nil, &Error{Kind: KindNotFound, Err: fmt.Errorf("%w: %s", aeonerrors.ErrUnitNotFound, prefix)}
+	case 1:
+		return dayKey, matches[0], nil
+	}
+	candidates := make([]string, len(matches))
+	for i, unitID := range matches {
+		candidates[i] = unitID.String()
+	}
+	sort.Strings(candidates)
+	return "", uuid.Nil, &Error{Kind: KindInvalidInput, Err: fmt.Errorf("%w: %s matches %s", aeonerrors.ErrAmbiguousUnitID, prefix, strings.Join(candidates, ", "))}
+}
+
+// ShortIDs returns the shortest prefix of each unit ID which no other unit shares, at least MinShortIDLength long.
+func ShortIDs(a *models.AeonVault) map[uuid.UUID]string {
+	var ids []string
+	for _, day := range a.Days {
+		for unitID := range day.Units {
+			ids = append(ids, unitID.String())
+		}
+	}
+	// A prefix is unique if it differs from both neighbours in sorted order
+	sort.Strings(ids)
+	shortIDs := make(map[uuid.UUID]string, len(ids))
+	for i, id := range ids {
+		length := MinShortIDLength
+		if i > 0 {
+			length = max(length, commonPrefixLength(id, ids[i-1])+1)
+		}
+		if i < len(ids)-1 {
+			length = max(length, commonPrefixLength(id, ids[i+1])+1)
+		}
+		shortIDs[uuid.MustParse(id)] = id[:min(length, len(id))]
+	}
+	return shortIDs
+}
+
+// Projects returns the projects tagged in a comment with a leading +, like "+aeontrac review", without the +.
+func Projects(comment string) []string {
+	var projects []string
+	for _, word := range strings.Fields(comment) {
+		if len(word) > 1 && word[0] == '+' {
+			projects = append(projects, word[1:])
+		}
+	}
+	return projects
+}
+
+// unitEntry returns the entry of a unit of work
+func unitEntry(dayKey string, unitID uuid.UUID, unit models.AeonUnit, shortID string) UnitEntry {
+	return UnitEntry{
+		ID:       unitID,
+		ShortID:  shortID,
+		DayKey:   dayKey,
+		Start:    *unit.Start,
+		Stop:     unit.Stop,
+		Duration: unit.Duration,
+		Type:     unit.Type,
+		Comment:  unit.Comment,
+		Projects: Projects(unit.Comment),
+		Running:  unit.Stop == nil,
+	}
+}
+
+// hasProject reports whether the project is one of the projects, ignoring case
+func hasProject(projects []string, project string) bool {
+	project = strings.TrimPrefix(project, "+")
+	for _, p := range projects {
+		if strings.EqualFold(p, project) {
+			return true
+		}
+	}
+	return false
+}
+
+// commonPrefixLength returns the length of the common prefix of two strings
+func commonPrefixLength(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
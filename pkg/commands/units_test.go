@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/models"
+)
+
+// newUnitsTestVault returns a vault with a unit of each ID on consecutive days, starting at 2024-03-01
+func newUnitsTestVault(ids []string, comments ...string) *models.AeonVault {
+	a := &models.AeonVault{Days: map[string]*models.AeonDay{}}
+	for i, id := range ids {
+		start := time.Date(2024, 3, 1+i, 9, 0, 0, 0, time.UTC)
+		stop := start.Add(time.Hour)
+		unit := models.AeonUnit{Start: &start, Stop: &stop, Duration: &models.AeonDuration{Duration: time.Hour}, Type: "WORK"}
+		if i < len(comments) {
+			unit.Comment = comments[i]
+		}
+		a.Days[start.Format(time.DateOnly)] = &models.AeonDay{Units: map[uuid.UUID]models.AeonUnit{uuid.MustParse(id): unit}}
+	}
+	return a
+}
+
+func TestShortIDs(t *testing.T) {
+	a := newUnitsTestVault([]string{
+		"0ccc98e9-5195-4440-987b-b5101ebe15dc",
+		"0ccc98e1-4195-4440-987b-b5101ebe15dc",
+		"7a819d2f-99db-446b-ac8a-3f616a73f989",
+	})
+
+	shortIDs := ShortIDs(a)
+
+	assert.Equal(t, map[uuid.UUID]string{
+		uuid.MustParse("0ccc98e9-5195-4440-987b-b5101ebe15dc"): "0ccc98e9",
+		uuid.MustParse("0ccc98e1-4195-4440-987b-b5101ebe15dc"): "0ccc98e1",
+		uuid.MustParse("7a819d2f-99db-446b-ac8a-3f616a73f989"): "7a819d2",
+	}, shortIDs)
+}
+
+func TestResolveUnit(t *testing.T) {
+	a := newUnitsTestVault([]string{
+		"0ccc98e9-5195-4440-987b-b5101ebe15dc",
+		"0ccc98e1-4195-4440-987b-b5101ebe15dc",
+		"7a819d2f-99db-446b-ac8a-3f616a73f989",
+	})
+	tests := []struct {
+		name          string
+		prefix        string
+		expectedDay   string
+		expectedID    string
+		expectedKind  Kind
+		expectedError error
+	}{
+		{name: "UniquePrefix", prefix: "7a", expectedDay: "2024-03-03", expectedID: "7a819d2f-99db-446b-ac8a-3f616a73f989"},
+		{name: "UpperCase", prefix: "0CCC98E1", expectedDay: "2024-03-02", expectedID: "0ccc98e1-4195-4440-987b-b5101ebe15dc"},
+		{name: "FullID", prefix: "0ccc98e9-5195-4440-987b-b5101ebe15dc", expectedDay: "2024-03-01", expectedID: "0ccc98e9-5195-4440-987b-b5101ebe15dc"},
+		{name: "AmbiguousPrefix", prefix: "0ccc", expectedKind: KindInvalidInput, expectedError: aeonerrors.ErrAmbiguousUnitID},
+		{name: "UnknownPrefix", prefix: "ff", expectedKind: KindNotFound, expectedError: aeonerrors.ErrUnitNotFound},
+		{name: "Empty", prefix: " ", expectedKind: KindInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dayKey, unitID, err := ResolveUnit(tt.prefix, a)
+
+			if tt.expectedKind != KindInternal {
+				require.Error(t, err)
+				if tt.expectedError != nil {
+					assert.ErrorIs(t, err, tt.expectedError)
+				}
+				assert.Equal(t, tt.expectedKind, KindOf(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedDay, dayKey)
+			assert.Equal(t, tt.expectedID, unitID.String())
+		})
+	}
+}
+
+func TestLogCommand(t *testing.T) {
+	a := newUnitsTestVault([]string{
+		"0ccc98e9-5195-4440-987b-b5101ebe15dc",
+		"0ccc98e1-4195-4440-987b-b5101ebe15dc",
+		"7a819d2f-99db-446b-ac8a-3f616a73f989",
+	}, "+aeontrac review", "meeting", "+AeonTrac +docs")
+	tests := []struct {
+		name     string
+		filter   LogFilter
+		expected []string
+	}{
+		{name: "All", expected: []string{"0ccc98e9", "0ccc98e1", "7a819d2"}},
+		{name: "From", filter: LogFilter{From: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)}, expected: []string{"0ccc98e1", "7a819d2"}},
+		{name: "FromTo", filter: LogFilter{From: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)}, expected: []string{"0ccc98e1"}},
+		{name: "Project", filter: LogFilter{Project: "+aeontrac"}, expected: []string{"0ccc98e9", "7a819d2"}},
+		{name: "Type", filter: LogFilter{Type: "compensatory"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var shortIDs []string
+			for _, entry := range LogCommand(tt.filter, a) {
+				shortIDs = append(shortIDs, entry.ShortID)
+			}
+			assert.Equal(t, tt.expected, shortIDs)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+// Package diff compares two states of the time tracking data per day and per unit.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/pkg/models"
+)
+
+// Kinds of changes
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Modified = "modified"
+)
+
+type (
+	// Result lists the changed days, ordered by day
+	Result struct {
+		Days        []DayChange  `json:"days"`
+		RunningUnit *FieldChange `json:"running_unit,omitempty"`
+	}
+	// DayChange describes the changes of a single day, the changed fields of added and removed days are not listed
+	DayChange struct {
+		DayKey string        `json:"day_key"`
+		Kind   string        `json:"kind"`
+		Fields []FieldChange `json:"fields,omitempty"`
+		Units  []UnitChange  `json:"units,omitempty"`
+	}
+	// FieldChange is a changed field of a day, named like its JSON field
+	FieldChange struct {
+		Name   string `json:"name"`
+		Before string `json:"before"`
+		After  string `json:"after"`
+	}
+	// UnitChange is an added, removed or modified unit, Before is not set for added and After not for removed units
+	UnitChange struct {
+		Kind   string           `json:"kind"`
+		UnitID uuid.UUID        `json:"unit_id"`
+		Before *models.AeonUnit `json:"before,omitempty"`
+		After  *models.AeonUnit `json:"after,omitempty"`
+	}
+)
+
+// Compare returns the changes from the before data to the after data.
+func Compare(before, after *models.AeonVault) Result {
+	var result Result
+	for _, dayKey := range dayKeys(before, after) {
+		beforeDay, inBefore := before.Days[dayKey]
+		afterDay, inAfter := after.Days[dayKey]
+		change := DayChange{DayKey: dayKey, Kind: Modified}
+		switch {
+		case !inBefore:
+			change.Kind, beforeDay = Added, &models.AeonDay{}
+		case !inAfter:
+			change.Kind, afterDay = Removed, &models.AeonDay{}
+		default:
+			change.Fields = compareFields(beforeDay, afterDay)
+		}
+		change.Units = compareUnits(beforeDay, afterDay)
+		if change.Kind != Modified || len(change.Fields) > 0 || len(change.Units) > 0 {
+			result.Days = append(result.Days, change)
+		}
+	}
+	if beforeRunning, afterRunning := describeRunningUnit(before.CurrentRunningUnit), describeRunningUnit(after.CurrentRunningUnit); beforeRunning != afterRunning {
+		result.RunningUnit = &FieldChange{Name: "current_running_unit", Before: beforeRunning, After: afterRunning}
+	}
+	return result
+}
+
+// Empty reports whether nothing has changed.
+func (r Result) Empty() bool {
+	return len(r.Days) == 0 && r.RunningUnit == nil
+}
+
+// String describes the changes, a line per changed field and unit below a line per day.
+func (r Result) String() string {
+	if r.Empty() {
+		return "No differences."
+	}
+	var description strings.Builder
+	for _, day := range r.Days {
+		description.WriteString(day.String())
+	}
+	if r.RunningUnit != nil {
+		fmt.Fprintln(&description, r.RunningUnit)
+	}
+	return strings.TrimSuffix(description.String(), "\n")
+}
+
+// String describes the day in a line, followed by a line per changed field and unit.
+func (d DayChange) String() string {
+	var description strings.Builder
+	fmt.Fprintf(&description, "%s %s\n", d.DayKey, d.Kind)
+	for _, field := range d.Fields {
+		fmt.Fprintf(&description, "  %s\n", field)
+	}
+	for _, unit := range d.Units {
+		fmt.Fprintf(&description, "  %s\n", unit)
+	}
+	return description.String()
+}
+
+// String describes the field change in a single line.
+func (f FieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", f.Name, f.Before, f.After)
+}
+
+// String describes the unit change in a single line, marked with +, - or ~.
+func (u UnitChange) String() string {
+	switch u.Kind {
+	case Added:
+		return "+ " + describeUnit(u.UnitID, u.After)
+	case Removed:
+		return "- " + describeUnit(u.UnitID, u.Before)
+	}
+	return "~ " + describeUnit(u.UnitID, u.Before) + " -> " + describeVersion(u.After)
+}
+
+// compareFields returns the changed fields of a day, without its units
+func compareFields(before, after *models.AeonDay) []FieldChange {
+	fields := []FieldChange{
+		{Name: "total_hours", Before: describeDuration(before.TotalHours), After: describeDuration(after.TotalHours)},
+		{Name: "overtime_hours", Before: describeDuration(before.OvertimeHours), After: describeDuration(after.OvertimeHours)},
+		{Name: "public_holiday", Before: strconv.FormatBool(before.PublicHoliday), After: strconv.FormatBool(after.PublicHoliday)},
+		{Name: "public_holiday_name", Before: before.PublicHolidayName, After: after.PublicHolidayName},
+		{Name: "vacation_day", Before: strconv.FormatBool(before.VacationDay), After: strconv.FormatBool(after.VacationDay)},
+		{Name: "week_end", Before: strconv.FormatBool(before.WeekEnd), After: strconv.FormatBool(after.WeekEnd)},
+	}
+	return slices.DeleteFunc(fields, func(field FieldChange) bool {
+		return field.Before == field.After
+	})
+}
+
+// compareUnits returns the added, removed and modified units of a day, ordered by start time of their latest version
+func compareUnits(before, after *models.AeonDay) []UnitChange {
+	var changes []UnitChange
+	for unitID, beforeUnit := range before.Units {
+		afterUnit, ok := after.Units[unitID]
+		switch {
+		case !ok:
+			changes = append(changes, UnitChange{Kind: Removed, UnitID: unitID, Before: &beforeUnit})
+		case !equalJSON(beforeUnit, afterUnit):
+			changes = append(changes, UnitChange{Kind: Modified, UnitID: unitID, Before: &beforeUnit, After: &afterUnit})
+		}
+	}
+	for unitID, afterUnit := range after.Units {
+		if _, ok := before.Units[unitID]; !ok {
+			changes = append(changes, UnitChange{Kind: Added, UnitID: unitID, After: &afterUnit})
+		}
+	}
+	slices.SortFunc(changes, func(a, b UnitChange) int {
+		if startA, startB := a.start(), b.start(); startA != startB {
+			return strings.Compare(startA, startB)
+		}
+		return strings.Compare(a.UnitID.String(), b.UnitID.String())
+	})
+	return changes
+}
+
+// start returns the start of the latest version of the unit, for sorting
+func (u UnitChange) start() string {
+	unit := u.After
+	if unit == nil {
+		unit = u.Before
+	}
+	if unit.Start == nil {
+		return ""
+	}
+	return unit.Start.UTC().Format("2006-01-02T15:04:05.000000000")
+}
+
+func describeUnit(unitID uuid.UUID, unit *models.AeonUnit) string {
+	return unitID.String()[:8] + " " + describeVersion(unit)
+}
+
+// describeVersion describes the times, type and comment of a unit
+func describeVersion(unit *models.AeonUnit) string {
+	var description strings.Builder
+	if unit.Start != nil {
+		description.WriteString(unit.Start.Format("15:04:05"))
+	}
+	description.WriteString("-")
+	if unit.Stop != nil {
+		description.WriteString(unit.Stop.Format("15:04:05"))
+	}
+	description.WriteString(" " + unit.Type)
+	if unit.Comment != "" {
+		fmt.Fprintf(&description, " %q", unit.Comment)
+	}
+	return description.String()
+}
+
+func describeDuration(duration *models.AeonDuration) string {
+	if duration == nil {
+		return "none"
+	}
+	return duration.String()
+}
+
+func describeRunningUnit(runningUnit *models.AeonCurrentRunningUnit) string {
+	if runningUnit == nil {
+		return "none"
+	}
+	return runningUnit.DayKey + " " + runningUnit.UnitID.String()
+}
+
+// equalJSON reports whether both values have the same JSON encoding
+func equalJSON(a, b any) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
+
+// dayKeys returns the sorted keys of the days of both data
+func dayKeys(before, after *models.AeonVault) []string {
+	var keys []string
+	for dayKey := range before.Days {
+		keys = append(keys, dayKey)
+	}
+	for dayKey := range after.Days {
+		if _, ok := before.Days[dayKey]; !ok {
+			keys = append(keys, dayKey)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,126 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDayKey = "2024-01-02"
+
+var (
+	firstID  = uuid.MustParse("11111111-1111-4111-8111-111111111111")
+	secondID = uuid.MustParse("22222222-2222-4222-8222-222222222222")
+)
+
+// newUnit returns a work unit on Tuesday, 2024-01-02 between the provided hours
+func newUnit(fromHour, toHour int) models.AeonUnit {
+	start := time.Date(2024, 1, 2, fromHour, 0, 0, 0, time.UTC)
+	stop := time.Date(2024, 1, 2, toHour, 0, 0, 0, time.UTC)
+	return models.AeonUnit{Start: &start, Stop: &stop, Duration: &models.AeonDuration{Duration: stop.Sub(start)}, Type: "WORK"}
+}
+
+// newVault returns a vault with the provided units on Tuesday, 2024-01-02, and their total hours
+func newVault(units map[uuid.UUID]models.AeonUnit) *models.AeonVault {
+	day := &models.AeonDay{IsoWeekNumber: 1, IsoWeekDay: 2, Units: map[uuid.UUID]models.AeonUnit{}, TotalHours: &models.AeonDuration{}}
+	for unitID, unit := range units {
+		day.Units[unitID] = unit
+		day.TotalHours.Duration += unit.Duration.Duration
+	}
+	return &models.AeonVault{Days: map[string]*models.AeonDay{testDayKey: day}}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   *models.AeonVault
+		after    func() *models.AeonVault
+		expected []string
+	}{
+		{
+			name:     "NoDifferences",
+			before:   newVault(map[uuid.UUID]models.AeonUnit{firstID: newUnit(9, 10)}),
+			after:    func() *models.AeonVault { return newVault(map[uuid.UUID]models.AeonUnit{firstID: newUnit(9, 10)}) },
+			expected: []string{"No differences."},
+		},
+		{
+			name:   "AddedAndRemovedUnits",
+			before: newVault(map[uuid.UUID]models.AeonUnit{firstID: newUnit(9, 10)}),
+			after:  func() *models.AeonVault { return newVault(map[uuid.UUID]models.AeonUnit{secondID: newUnit(8, 10)}) },
+			expected: []string{
+				"2024-01-02 modified",
+				"  total_hours: 1h0m0s -> 2h0m0s",
+				"  + 22222222 08:00:00-10:00:00 WORK",
+				"  - 11111111 09:00:00-10:00:00 WORK",
+			},
+		},
+		{
+			name:   "ModifiedUnit",
+			before: newVault(map[uuid.UUID]models.AeonUnit{firstID: newUnit(9, 10)}),
+			after: func() *models.AeonVault {
+				unit := newUnit(9, 10)
+				unit.Comment = "review"
+				return newVault(map[uuid.UUID]models.AeonUnit{firstID: unit})
+			},
+			expected: []string{
+				"2024-01-02 modified",
+				"  ~ 11111111 09:00:00-10:00:00 WORK -> 09:00:00-10:00:00 WORK \"review\"",
+			},
+		},
+		{
+			name:   "ChangedFlags",
+			before: newVault(nil),
+			after: func() *models.AeonVault {
+				a := newVault(nil)
+				a.Days[testDayKey].VacationDay = true
+				a.Days[testDayKey].PublicHoliday = true
+				a.Days[testDayKey].PublicHolidayName = "Holiday"
+				return a
+			},
+			expected: []string{
+				"2024-01-02 modified",
+				"  public_holiday: false -> true",
+				"  public_holiday_name:  -> Holiday",
+				"  vacation_day: false -> true",
+			},
+		},
+		{
+			name:   "AddedDayAndRunningUnit",
+			before: newVault(nil),
+			after: func() *models.AeonVault {
+				a := newVault(nil)
+				start := time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC)
+				a.Days["2024-01-03"] = &models.AeonDay{Units: map[uuid.UUID]models.AeonUnit{firstID: {Start: &start, Type: "WORK"}}}
+				a.CurrentRunningUnit = &models.AeonCurrentRunningUnit{DayKey: "2024-01-03", UnitID: firstID}
+				return a
+			},
+			expected: []string{
+				"2024-01-03 added",
+				"  + 11111111 09:00:00- WORK",
+				"current_running_unit: none -> 2024-01-03 " + firstID.String(),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Compare(tt.before, tt.after())
+
+			assert.Equal(t, strings.Join(tt.expected, "\n"), result.String())
+			assert.Equal(t, len(tt.expected) == 1 && tt.expected[0] == "No differences.", result.Empty())
+		})
+	}
+}
+
+func TestCompareRemovedDay(t *testing.T) {
+	result := Compare(newVault(map[uuid.UUID]models.AeonUnit{firstID: newUnit(9, 10)}), &models.AeonVault{})
+
+	assert.Equal(t, []DayChange{{
+		DayKey: testDayKey,
+		Kind:   Removed,
+		Units:  []UnitChange{{Kind: Removed, UnitID: firstID, Before: &models.AeonUnit{Start: newUnit(9, 10).Start, Stop: newUnit(9, 10).Stop, Duration: &models.AeonDuration{Duration: time.Hour}, Type: "WORK"}}},
+	}}, result.Days)
+}
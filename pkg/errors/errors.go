@@ -13,4 +13,17 @@ const (
 	ErrNoUnitOfWorkRunning      AeonError = "no unit of work is running"
 	ErrStopTimeBeforeStartTime  AeonError = "stop time cannot be before the start time"
 	ErrCompensationOnNonWorkDay AeonError = "compensation on a non-work day is not allowed"
+	ErrNothingToUndo            AeonError = "there is no operation to undo"
+	ErrNothingToRedo            AeonError = "there is no operation to redo"
+	ErrJournalConflict          AeonError = "the vault has been changed since the operation was recorded"
+	ErrLockTimeout              AeonError = "timed out waiting for the lock on the data folder"
+	ErrStaleLock                AeonError = "the lock on the data folder is stale"
+	ErrUnsupportedSchemaVersion AeonError = "the time tracking data was written by a newer version"
+	ErrVaultLocked              AeonError = "the time tracking data is encrypted and no key has been provided"
+	ErrWrongKey                 AeonError = "the key does not decrypt the time tracking data"
+	ErrInvalidData              AeonError = "the time tracking data is invalid"
+	ErrInvalidTime              AeonError = "the time is invalid"
+	ErrUnitNotFound             AeonError = "no unit of work has this ID"
+	ErrAmbiguousUnitID          AeonError = "the ID prefix matches several units of work"
+	ErrDayChanged               AeonError = "the day has been changed by another process while it was edited"
 )
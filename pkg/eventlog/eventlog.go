@@ -0,0 +1,320 @@
+// Package eventlog describes changes of the time tracking data as typed events, from which the data can be rebuilt.
+package eventlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/pkg/models"
+)
+
+// Types of events
+const (
+	DayCreated         = "DayCreated"
+	DayUpdated         = "DayUpdated"
+	DayRemoved         = "DayRemoved"
+	VacationSet        = "VacationSet"
+	VacationCleared    = "VacationCleared"
+	UnitStarted        = "UnitStarted"
+	UnitStopped        = "UnitStopped"
+	UnitAdded          = "UnitAdded"
+	UnitChanged        = "UnitChanged"
+	UnitRemoved        = "UnitRemoved"
+	RunningUnitChanged = "RunningUnitChanged"
+	YearsInitialized   = "YearsInitialized"
+	SettingSet         = "SettingSet"
+	SettingRemoved     = "SettingRemoved"
+)
+
+// Event is a single change of the time tracking data. Only the fields used by its type are set.
+// Days of events do not contain units, units are changed by unit events.
+// Operation is the operation which made the change, like start, switch or edit-day, all events of a save share it.
+type Event struct {
+	Sequence    int64                          `json:"seq"`
+	Time        time.Time                      `json:"time"`
+	Operation   string                         `json:"operation,omitempty"`
+	Type        string                         `json:"type"`
+	DayKey      string                         `json:"day_key,omitempty"`
+	UnitID      *uuid.UUID                     `json:"unit_id,omitempty"`
+	Unit        *models.AeonUnit               `json:"unit,omitempty"`
+	Day         *models.AeonDay                `json:"day,omitempty"`
+	RunningUnit *models.AeonCurrentRunningUnit `json:"running_unit,omitempty"`
+	Years       []int                          `json:"years,omitempty"`
+	Key         string                         `json:"key,omitempty"`
+	Value       string                         `json:"value,omitempty"`
+}
+
+// String describes the event in a single line.
+func (e Event) String() string {
+	description := e.Type
+	if e.DayKey != "" {
+		description += " " + e.DayKey
+	}
+	if e.UnitID != nil {
+		description += " unit " + e.UnitID.String()
+	}
+	switch e.Type {
+	case RunningUnitChanged:
+		if e.RunningUnit == nil {
+			description += " none"
+		} else {
+			description += fmt.Sprintf(" %s unit %s", e.RunningUnit.DayKey, e.RunningUnit.UnitID)
+		}
+	case YearsInitialized:
+		description += fmt.Sprintf(" %v", e.Years)
+	case SettingSet, SettingRemoved:
+		description += " " + e.Key
+	}
+	return description
+}
+
+// Diff returns the events changing the before data into the after data, a nil before is empty data.
+// Events are ordered by day and unit, their sequence number and time are not set.
+func Diff(before, after *models.AeonVault) []Event {
+	if before == nil {
+		before = &models.AeonVault{}
+	}
+	var events []Event
+	for _, dayKey := range dayKeys(before, after) {
+		beforeDay, inBefore := before.Days[dayKey]
+		afterDay, inAfter := after.Days[dayKey]
+		switch {
+		case !inAfter:
+			events = append(events, Event{Type: DayRemoved, DayKey: dayKey})
+			continue
+		case !inBefore:
+			events = append(events, Event{Type: DayCreated, DayKey: dayKey, Day: withoutUnits(afterDay)})
+			beforeDay = &models.AeonDay{}
+		default:
+			events = append(events, diffDay(dayKey, beforeDay, afterDay)...)
+		}
+		events = append(events, diffUnits(dayKey, beforeDay, afterDay, after.CurrentRunningUnit)...)
+	}
+
+	// The running unit is changed by starting and stopping units, other changes need their own event
+	runningUnit := before.CurrentRunningUnit
+	for _, event := range events {
+		runningUnit = nextRunningUnit(runningUnit, event)
+	}
+	if !sameRunningUnit(runningUnit, after.CurrentRunningUnit) {
+		events = append(events, Event{Type: RunningUnitChanged, RunningUnit: after.CurrentRunningUnit})
+	}
+	if !slices.Equal(before.InitializedYears, after.InitializedYears) {
+		events = append(events, Event{Type: YearsInitialized, Years: slices.Clone(after.InitializedYears)})
+	}
+	for _, key := range settingKeys(before, after) {
+		value, ok := after.Settings[key]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: SettingRemoved, Key: key})
+		case value != before.Settings[key] || !hasSetting(before, key):
+			events = append(events, Event{Type: SettingSet, Key: key, Value: value})
+		}
+	}
+	return events
+}
+
+// Apply applies a single event to the data.
+func Apply(a *models.AeonVault, e Event) error {
+	if a.Days == nil {
+		a.Days = map[string]*models.AeonDay{}
+	}
+	switch e.Type {
+	case DayCreated, DayUpdated:
+		if e.Day == nil {
+			return fmt.Errorf("event %d of type %s has no day", e.Sequence, e.Type)
+		}
+		day := *e.Day
+		day.Units = map[uuid.UUID]models.AeonUnit{}
+		if existing, ok := a.Days[e.DayKey]; ok && existing.Units != nil {
+			day.Units = existing.Units
+		}
+		a.Days[e.DayKey] = &day
+	case DayRemoved:
+		delete(a.Days, e.DayKey)
+	case VacationSet, VacationCleared:
+		day, err := eventDay(a, e)
+		if err != nil {
+			return err
+		}
+		day.VacationDay = e.Type == VacationSet
+	case UnitStarted, UnitStopped, UnitAdded, UnitChanged:
+		day, err := eventDay(a, e)
+		if err != nil {
+			return err
+		}
+		if e.UnitID == nil || e.Unit == nil {
+			return fmt.Errorf("event %d of type %s has no unit", e.Sequence, e.Type)
+		}
+		if day.Units == nil {
+			day.Units = map[uuid.UUID]models.AeonUnit{}
+		}
+		day.Units[*e.UnitID] = *e.Unit
+		a.CurrentRunningUnit = nextRunningUnit(a.CurrentRunningUnit, e)
+	case UnitRemoved:
+		day, err := eventDay(a, e)
+		if err != nil {
+			return err
+		}
+		if e.UnitID == nil {
+			return fmt.Errorf("event %d of type %s has no unit", e.Sequence, e.Type)
+		}
+		delete(day.Units, *e.UnitID)
+		a.CurrentRunningUnit = nextRunningUnit(a.CurrentRunningUnit, e)
+	case RunningUnitChanged:
+		a.CurrentRunningUnit = e.RunningUnit
+	case YearsInitialized:
+		a.InitializedYears = slices.Clone(e.Years)
+	case SettingSet:
+		if a.Settings == nil {
+			a.Settings = map[string]string{}
+		}
+		a.Settings[e.Key] = e.Value
+	case SettingRemoved:
+		delete(a.Settings, e.Key)
+	default:
+		return fmt.Errorf("event %d has the unknown type %s", e.Sequence, e.Type)
+	}
+	return nil
+}
+
+// diffDay returns the events changing the fields of a day, without its units
+func diffDay(dayKey string, before, after *models.AeonDay) []Event {
+	var events []Event
+	beforeFields, afterFields := withoutUnits(before), withoutUnits(after)
+	beforeFields.VacationDay = afterFields.VacationDay
+	if !equalJSON(beforeFields, afterFields) {
+		events = append(events, Event{Type: DayUpdated, DayKey: dayKey, Day: withoutUnits(after)})
+	}
+	if before.VacationDay != after.VacationDay {
+		eventType := VacationCleared
+		if after.VacationDay {
+			eventType = VacationSet
+		}
+		events = append(events, Event{Type: eventType, DayKey: dayKey})
+	}
+	return events
+}
+
+// diffUnits returns the events changing the units of a day
+func diffUnits(dayKey string, before, after *models.AeonDay, runningUnit *models.AeonCurrentRunningUnit) []Event {
+	unitIDs := make([]uuid.UUID, 0, len(before.Units)+len(after.Units))
+	for unitID := range before.Units {
+		unitIDs = append(unitIDs, unitID)
+	}
+	for unitID := range after.Units {
+		if _, ok := before.Units[unitID]; !ok {
+			unitIDs = append(unitIDs, unitID)
+		}
+	}
+	sort.Slice(unitIDs, func(i, j int) bool {
+		return unitIDs[i].String() < unitIDs[j].String()
+	})
+
+	var events []Event
+	for _, unitID := range unitIDs {
+		beforeUnit, inBefore := before.Units[unitID]
+		afterUnit, inAfter := after.Units[unitID]
+		event := Event{DayKey: dayKey, UnitID: &unitID, Unit: &afterUnit}
+		switch {
+		case !inAfter:
+			event.Type, event.Unit = UnitRemoved, nil
+		case !inBefore && afterUnit.Stop == nil && runningUnit != nil && runningUnit.DayKey == dayKey && runningUnit.UnitID == unitID:
+			event.Type = UnitStarted
+		case !inBefore:
+			event.Type = UnitAdded
+		case equalJSON(beforeUnit, afterUnit):
+			continue
+		case beforeUnit.Stop == nil && afterUnit.Stop != nil:
+			event.Type = UnitStopped
+		default:
+			event.Type = UnitChanged
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// nextRunningUnit returns the running unit after the event: started units run, stopped and removed units do not
+func nextRunningUnit(runningUnit *models.AeonCurrentRunningUnit, e Event) *models.AeonCurrentRunningUnit {
+	switch e.Type {
+	case UnitStarted:
+		return &models.AeonCurrentRunningUnit{DayKey: e.DayKey, UnitID: *e.UnitID}
+	case UnitStopped, UnitRemoved:
+		if runningUnit != nil && runningUnit.DayKey == e.DayKey && runningUnit.UnitID == *e.UnitID {
+			return nil
+		}
+	case RunningUnitChanged:
+		return e.RunningUnit
+	}
+	return runningUnit
+}
+
+// eventDay returns the existing day of an event
+func eventDay(a *models.AeonVault, e Event) (*models.AeonDay, error) {
+	day, ok := a.Days[e.DayKey]
+	if !ok {
+		return nil, fmt.Errorf("event %d of type %s refers to the missing day %s", e.Sequence, e.Type, e.DayKey)
+	}
+	return day, nil
+}
+
+// withoutUnits returns a copy of the day without its units
+func withoutUnits(day *models.AeonDay) *models.AeonDay {
+	copied := *day
+	copied.Units = nil
+	return &copied
+}
+
+func sameRunningUnit(a, b *models.AeonCurrentRunningUnit) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func hasSetting(a *models.AeonVault, key string) bool {
+	_, ok := a.Settings[key]
+	return ok
+}
+
+// equalJSON reports whether both values have the same JSON encoding
+func equalJSON(a, b any) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
+
+// dayKeys returns the sorted keys of the days of both data
+func dayKeys(before, after *models.AeonVault) []string {
+	var keys []string
+	for dayKey := range before.Days {
+		keys = append(keys, dayKey)
+	}
+	for dayKey := range after.Days {
+		if _, ok := before.Days[dayKey]; !ok {
+			keys = append(keys, dayKey)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// settingKeys returns the sorted keys of the settings of both data
+func settingKeys(before, after *models.AeonVault) []string {
+	var keys []string
+	for key := range before.Settings {
+		keys = append(keys, key)
+	}
+	for key := range after.Settings {
+		if _, ok := before.Settings[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,151 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testUnitID = uuid.MustParse("6f1c2a4e-0d3b-4b8e-9a55-1f2e3d4c5b6a")
+
+// newTestVault returns a vault with a running unit on Tuesday, 2024-01-02
+func newTestVault() *models.AeonVault {
+	start := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	return &models.AeonVault{
+		Days: map[string]*models.AeonDay{
+			"2024-01-01": {IsoWeekNumber: 1, IsoWeekDay: 1, PublicHoliday: true, PublicHolidayName: "New Year's Day", Units: map[uuid.UUID]models.AeonUnit{}},
+			"2024-01-02": {IsoWeekNumber: 1, IsoWeekDay: 2, Units: map[uuid.UUID]models.AeonUnit{testUnitID: {Start: &start, Type: "WORK"}}},
+		},
+		CurrentRunningUnit: &models.AeonCurrentRunningUnit{DayKey: "2024-01-02", UnitID: testUnitID},
+		InitializedYears:   []int{2024},
+	}
+}
+
+func TestDiffAndApply(t *testing.T) {
+	tests := []struct {
+		name          string
+		change        func(a *models.AeonVault)
+		expectedTypes []string
+	}{
+		{
+			name:          "Unchanged",
+			change:        func(a *models.AeonVault) {},
+			expectedTypes: nil,
+		},
+		{
+			name: "StopUnit",
+			change: func(a *models.AeonVault) {
+				unit := a.Days["2024-01-02"].Units[testUnitID]
+				stop := unit.Start.Add(2 * time.Hour)
+				unit.Stop, unit.Duration = &stop, &models.AeonDuration{Duration: 2 * time.Hour}
+				a.Days["2024-01-02"].Units[testUnitID] = unit
+				a.Days["2024-01-02"].TotalHours = &models.AeonDuration{Duration: 2 * time.Hour}
+				a.CurrentRunningUnit = nil
+			},
+			expectedTypes: []string{DayUpdated, UnitStopped},
+		},
+		{
+			name: "AddUnitOnNewDay",
+			change: func(a *models.AeonVault) {
+				start := time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC)
+				stop := start.Add(time.Hour)
+				a.Days["2024-01-03"] = &models.AeonDay{IsoWeekNumber: 1, IsoWeekDay: 3, Units: map[uuid.UUID]models.AeonUnit{
+					uuid.New(): {Start: &start, Stop: &stop, Duration: &models.AeonDuration{Duration: time.Hour}, Type: "WORK"},
+				}}
+			},
+			expectedTypes: []string{DayCreated, UnitAdded},
+		},
+		{
+			name: "SetVacation",
+			change: func(a *models.AeonVault) {
+				a.Days["2024-01-02"].VacationDay = true
+			},
+			expectedTypes: []string{VacationSet},
+		},
+		{
+			name: "RemoveRunningUnit",
+			change: func(a *models.AeonVault) {
+				delete(a.Days["2024-01-02"].Units, testUnitID)
+				a.CurrentRunningUnit = nil
+			},
+			expectedTypes: []string{UnitRemoved},
+		},
+		{
+			name: "RemoveDayOfRunningUnit",
+			change: func(a *models.AeonVault) {
+				delete(a.Days, "2024-01-02")
+				a.CurrentRunningUnit = nil
+			},
+			expectedTypes: []string{DayRemoved, RunningUnitChanged},
+		},
+		{
+			name: "ChangeSettingsAndYears",
+			change: func(a *models.AeonVault) {
+				a.Settings = map[string]string{"theme": "dark"}
+				a.InitializedYears = []int{2024, 2025}
+			},
+			expectedTypes: []string{YearsInitialized, SettingSet},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after := newTestVault(), newTestVault()
+			tt.change(after)
+
+			events := Diff(before, after)
+
+			var types []string
+			for _, event := range events {
+				types = append(types, event.Type)
+			}
+			assert.Equal(t, tt.expectedTypes, types)
+			for _, event := range events {
+				// Events are stored as JSON, so they are replayed from their encoding
+				encoded, err := json.Marshal(event)
+				require.NoError(t, err)
+				var decoded Event
+				require.NoError(t, json.Unmarshal(encoded, &decoded))
+				require.NoError(t, Apply(before, decoded))
+			}
+			expected, err := json.Marshal(after)
+			require.NoError(t, err)
+			actual, err := json.Marshal(before)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(actual))
+		})
+	}
+}
+
+func TestDiffFromEmpty(t *testing.T) {
+	after := newTestVault()
+	replayed := &models.AeonVault{}
+
+	for _, event := range Diff(nil, after) {
+		require.NoError(t, Apply(replayed, event))
+	}
+
+	assert.Equal(t, after.CurrentRunningUnit, replayed.CurrentRunningUnit)
+	assert.Equal(t, after.Days["2024-01-02"].Units, replayed.Days["2024-01-02"].Units)
+}
+
+func TestApplyInvalidEvents(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+	}{
+		{name: "UnknownType", event: Event{Sequence: 1, Type: "UnitTeleported"}},
+		{name: "MissingDay", event: Event{Sequence: 1, Type: VacationSet, DayKey: "2030-01-01"}},
+		{name: "MissingUnit", event: Event{Sequence: 1, Type: UnitAdded, DayKey: "2024-01-02"}},
+		{name: "DayWithoutFields", event: Event{Sequence: 1, Type: DayCreated, DayKey: "2024-01-03"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, Apply(newTestVault(), tt.event))
+		})
+	}
+}
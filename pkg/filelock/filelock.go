@@ -0,0 +1,121 @@
+// Package filelock provides an advisory lock on a folder, which coordinates processes working on the same files.
+package filelock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+)
+
+const (
+	lockFileName = "aeon_vault.lock"
+	pollInterval = 50 * time.Millisecond
+	// unreadableGracePeriod is the time a holder gets to write its information into a newly created lock file
+	unreadableGracePeriod = 5 * time.Second
+)
+
+type (
+	// Holder describes the process holding a lock.
+	Holder struct {
+		PID      int       `json:"pid"`
+		Hostname string    `json:"hostname"`
+		Acquired time.Time `json:"acquired"`
+	}
+	// Lock represents an acquired lock on a folder.
+	Lock struct {
+		path string
+	}
+)
+
+// String returns a human-readable description of the holder.
+func (h Holder) String() string {
+	return fmt.Sprintf("process %d on %s since %s", h.PID, h.Hostname, h.Acquired.Format(time.DateTime))
+}
+
+// Acquire takes the lock on the provided folder, waiting up to the provided timeout for another holder to release it.
+// If the lock is held by a process of this host which no longer runs, an error wrapping ErrStaleLock is returned.
+func Acquire(folder string, timeout time.Duration) (*Lock, error) {
+	path := filepath.Join(folder, lockFileName)
+	hostname, _ := os.Hostname()
+	deadline := time.Now().Add(timeout)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			holderErr := json.NewEncoder(file).Encode(Holder{PID: os.Getpid(), Hostname: hostname, Acquired: time.Now()})
+			closeErr := file.Close()
+			if err = errors.Join(holderErr, closeErr); err != nil {
+				_ = os.Remove(path)
+				return nil, err
+			}
+			return &Lock{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		holder, holderErr := readHolder(path)
+		if errors.Is(holderErr, os.ErrNotExist) {
+			// The lock has been released in the meantime
+			continue
+		}
+		if holderErr == nil && holder.Hostname == hostname && !processRunning(holder.PID) {
+			return nil, fmt.Errorf("%w: held by %s, which is no longer running (lock file %s)", aeonerrors.ErrStaleLock, holder, path)
+		}
+		if holderErr != nil {
+			if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > unreadableGracePeriod {
+				return nil, fmt.Errorf("%w: the lock file %s is unreadable: %v", aeonerrors.ErrStaleLock, path, holderErr)
+			}
+		}
+		if time.Now().After(deadline) {
+			if holderErr != nil {
+				return nil, fmt.Errorf("%w after %s", aeonerrors.ErrLockTimeout, timeout)
+			}
+			return nil, fmt.Errorf("%w after %s, held by %s", aeonerrors.ErrLockTimeout, timeout, holder)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Release releases the lock.
+func (l *Lock) Release() error {
+	return os.Remove(l.path)
+}
+
+// FilePath returns the path of the lock file of the provided folder.
+func FilePath(folder string) string {
+	return filepath.Join(folder, lockFileName)
+}
+
+// CurrentHolder returns the holder of the lock on the provided folder, an error wrapping os.ErrNotExist is returned if the folder is not locked.
+func CurrentHolder(folder string) (Holder, error) {
+	return readHolder(filepath.Join(folder, lockFileName))
+}
+
+// IsStale reports whether the holder is a process of this host which no longer runs.
+func IsStale(holder Holder) bool {
+	hostname, _ := os.Hostname()
+	return holder.Hostname == hostname && !processRunning(holder.PID)
+}
+
+// ForceRelease removes the lock on the provided folder, regardless of its holder.
+func ForceRelease(folder string) error {
+	return os.Remove(filepath.Join(folder, lockFileName))
+}
+
+// readHolder reads the holder information from the lock file
+func readHolder(path string) (Holder, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Holder{}, err
+	}
+	var holder Holder
+	if err = json.Unmarshal(content, &holder); err != nil {
+		return Holder{}, err
+	}
+	return holder, nil
+}
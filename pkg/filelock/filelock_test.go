@@ -0,0 +1,90 @@
+package filelock
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquire(t *testing.T) {
+	hostname, _ := os.Hostname()
+	tests := []struct {
+		name          string
+		setupFunc     func(folder string)
+		expectedError error
+	}{
+		{
+			name:          "NotLocked",
+			setupFunc:     func(folder string) {},
+			expectedError: nil,
+		},
+		{
+			name: "LockedByRunningProcess",
+			setupFunc: func(folder string) {
+				_, err := Acquire(folder, time.Second)
+				assert.NoError(t, err)
+			},
+			expectedError: aeonerrors.ErrLockTimeout,
+		},
+		{
+			name: "LockedByProcessOfOtherHost",
+			setupFunc: func(folder string) {
+				writeHolder(t, folder, Holder{PID: 1, Hostname: hostname + "-other", Acquired: time.Now()})
+			},
+			expectedError: aeonerrors.ErrLockTimeout,
+		},
+		{
+			name: "LockedByStoppedProcess",
+			setupFunc: func(folder string) {
+				// PIDs are limited far below the maximum int32 value on every supported platform
+				writeHolder(t, folder, Holder{PID: 1<<31 - 1, Hostname: hostname, Acquired: time.Now()})
+			},
+			expectedError: aeonerrors.ErrStaleLock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folder := t.TempDir()
+			tt.setupFunc(folder)
+
+			lock, err := Acquire(folder, 100*time.Millisecond)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			holder, err := CurrentHolder(folder)
+			assert.NoError(t, err)
+			assert.Equal(t, os.Getpid(), holder.PID)
+			assert.NoError(t, lock.Release())
+			_, err = CurrentHolder(folder)
+			assert.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	folder := t.TempDir()
+	lock, err := Acquire(folder, time.Second)
+	assert.NoError(t, err)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_ = lock.Release()
+	}()
+
+	secondLock, err := Acquire(folder, 5*time.Second)
+	assert.NoError(t, err)
+	assert.NoError(t, secondLock.Release())
+}
+
+func writeHolder(t *testing.T, folder string, holder Holder) {
+	content, err := json.Marshal(holder)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, lockFileName), content, 0644))
+}
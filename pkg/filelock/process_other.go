@@ -0,0 +1,15 @@
+//go:build !unix
+
+package filelock
+
+import "os"
+
+// processRunning reports whether a process with the provided PID exists.
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
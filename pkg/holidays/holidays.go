@@ -36,14 +36,20 @@ type (
 	}
 )
 
+// httpClient is used for all requests to the public holidays API, a slow API must not block the application
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
 // LoadHolidays loads the public holidays from the API and saves them to the database
 // It returns the list of public holidays and an error if any.
 // It uses the provided configuration to load the public holidays.
 // It uses the Open Holidays API to load the public holidays. See https://openholidaysapi.org/
 func LoadHolidays(config configuration.PublicHolidaysConfig, year int) (map[string]holidayItem, error) {
-	u, _ := url2.Parse(config.APIURL)
+	u, err := url2.Parse(config.APIURL)
+	if err != nil {
+		return nil, err
+	}
 	u.RawQuery = url2.Values{"countryIsoCode": {config.Country}, "validFrom": {fmt.Sprintf("%d-01-01", year)}, "validTo": {fmt.Sprintf("%d-12-31", year)}}.Encode()
-	var res, err = http.Get(u.String())
+	res, err := httpClient.Get(u.String())
 	if err != nil {
 		return nil, err
 	}
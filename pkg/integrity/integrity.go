@@ -0,0 +1,362 @@
+// Package integrity checks the time tracking data for inconsistencies between fields, which struct validation cannot detect,
+// and repairs those which can be fixed without guessing.
+package integrity
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/jame-developer/aeontrac/pkg/tracking"
+)
+
+// Names of the checks, used to identify the kind of a problem.
+const (
+	CheckDayKey          = "day-key"
+	CheckNullDay         = "null-day"
+	CheckCalendar        = "calendar"
+	CheckRunningUnit     = "running-unit"
+	CheckOpenUnit        = "open-unit"
+	CheckUnitStart       = "unit-start"
+	CheckUnitDay         = "unit-day"
+	CheckStopBeforeStart = "stop-before-start"
+	CheckDuration        = "duration"
+	CheckOverlap         = "overlap"
+	CheckTotalHours      = "total-hours"
+	CheckOvertimeHours   = "overtime-hours"
+)
+
+// Problem describes a single inconsistency of the time tracking data.
+type Problem struct {
+	Check   string    `json:"check"`
+	DayKey  string    `json:"day,omitempty"`
+	UnitID  uuid.UUID `json:"unit_id,omitzero"`
+	Message string    `json:"message"`
+	// Repairable reports whether the problem can be fixed without guessing
+	Repairable bool `json:"repairable"`
+	// Repaired reports whether the problem has been fixed
+	Repaired bool `json:"repaired"`
+}
+
+// String returns the problem as single line, naming the day and unit it affects.
+func (p Problem) String() string {
+	location := "vault"
+	if p.DayKey != "" {
+		location = p.DayKey
+	}
+	if p.UnitID != uuid.Nil {
+		location += " unit " + p.UnitID.String()
+	}
+	state := ""
+	if p.Repaired {
+		state = " (repaired)"
+	} else if p.Repairable {
+		state = " (repairable)"
+	}
+	return fmt.Sprintf("%s: [%s] %s%s", location, p.Check, p.Message, state)
+}
+
+// Check returns all inconsistencies of the time tracking data, sorted by day and unit, without changing the data.
+func Check(a *models.AeonVault, workingHoursConfig configuration.WorkingHoursConfig) []Problem {
+	return check(a, workingHoursConfig, false)
+}
+
+// Repair fixes all repairable inconsistencies of the time tracking data and returns all inconsistencies found,
+// the fixed ones are marked as repaired.
+func Repair(a *models.AeonVault, workingHoursConfig configuration.WorkingHoursConfig) []Problem {
+	return check(a, workingHoursConfig, true)
+}
+
+// Unrepaired returns the problems which have not been repaired.
+func Unrepaired(problems []Problem) []Problem {
+	var unrepaired []Problem
+	for _, problem := range problems {
+		if !problem.Repaired {
+			unrepaired = append(unrepaired, problem)
+		}
+	}
+	return unrepaired
+}
+
+// checker collects the problems of a single check run
+type checker struct {
+	a                  *models.AeonVault
+	workingHoursConfig configuration.WorkingHoursConfig
+	repair             bool
+	problems           []Problem
+	// changedDays holds the days changed by repairs, their hours are recalculated instead of being reported
+	changedDays map[string]bool
+}
+
+func check(a *models.AeonVault, workingHoursConfig configuration.WorkingHoursConfig, repair bool) []Problem {
+	c := &checker{a: a, workingHoursConfig: workingHoursConfig, repair: repair, changedDays: map[string]bool{}}
+	c.checkRunningUnit()
+	for _, dayKey := range sortedDayKeys(a) {
+		c.checkDay(dayKey)
+	}
+	// Days are repaired before units are moved to them, null days which are not repaired have nothing else to check
+	for _, dayKey := range sortedDayKeys(a) {
+		if a.Days[dayKey] == nil {
+			continue
+		}
+		c.checkUnitDays(dayKey)
+	}
+	// Units moved to the day they start on are checked on that day, which may have been created by the move
+	for _, dayKey := range sortedDayKeys(a) {
+		if a.Days[dayKey] == nil {
+			continue
+		}
+		c.checkUnitTimes(dayKey)
+		c.checkOverlaps(dayKey)
+		c.checkTotalHours(dayKey)
+	}
+
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		if c.problems[i].DayKey != c.problems[j].DayKey {
+			return c.problems[i].DayKey < c.problems[j].DayKey
+		}
+		return c.problems[i].UnitID.String() < c.problems[j].UnitID.String()
+	})
+	return c.problems
+}
+
+// report adds a problem, repairable problems are fixed by fix if repairing is enabled
+func (c *checker) report(problem Problem, fix func()) {
+	problem.Repairable = fix != nil
+	if c.repair && fix != nil {
+		fix()
+		problem.Repaired = true
+	}
+	c.problems = append(c.problems, problem)
+}
+
+// reportDayChange adds a problem whose repair changes the hours of the provided days
+func (c *checker) reportDayChange(problem Problem, fix func(), dayKeys ...string) {
+	for _, dayKey := range dayKeys {
+		c.changedDays[dayKey] = true
+	}
+	c.report(problem, fix)
+}
+
+// checkRunningUnit checks that the running unit exists and has not been stopped
+func (c *checker) checkRunningUnit() {
+	running := c.a.CurrentRunningUnit
+	if running == nil {
+		return
+	}
+	clearRunningUnit := func() { c.a.CurrentRunningUnit = nil }
+	day, ok := c.a.Days[running.DayKey]
+	if !ok || day == nil {
+		c.report(Problem{Check: CheckRunningUnit, DayKey: running.DayKey, UnitID: running.UnitID, Message: "the running unit refers to a missing day"}, clearRunningUnit)
+		return
+	}
+	unit, ok := day.Units[running.UnitID]
+	if !ok {
+		c.report(Problem{Check: CheckRunningUnit, DayKey: running.DayKey, UnitID: running.UnitID, Message: "the running unit refers to a missing unit"}, clearRunningUnit)
+		return
+	}
+	if unit.Stop != nil {
+		c.report(Problem{Check: CheckRunningUnit, DayKey: running.DayKey, UnitID: running.UnitID, Message: "the running unit has already been stopped"}, clearRunningUnit)
+	}
+}
+
+// checkDay checks that a day is not null and that its calendar fields match its date
+func (c *checker) checkDay(dayKey string) {
+	day := c.a.Days[dayKey]
+	date, err := time.Parse(time.DateOnly, dayKey)
+	if day == nil {
+		// A null day holds no data, it is replaced by an empty day, or removed if its key is not a date
+		c.report(Problem{Check: CheckNullDay, DayKey: dayKey, Message: "the day is null"}, func() {
+			if err != nil {
+				delete(c.a.Days, dayKey)
+				return
+			}
+			c.a.Days[dayKey] = repositories.NewAoenDay(date)
+		})
+		return
+	}
+	if err != nil {
+		c.report(Problem{Check: CheckDayKey, DayKey: dayKey, Message: "the day key is not a date in the format YYYY-MM-DD"}, nil)
+		return
+	}
+	expected := repositories.NewAoenDay(date)
+	if day.IsoWeekNumber != expected.IsoWeekNumber || day.IsoWeekDay != expected.IsoWeekDay || day.WeekEnd != expected.WeekEnd {
+		c.reportDayChange(Problem{
+			Check:  CheckCalendar,
+			DayKey: dayKey,
+			Message: fmt.Sprintf("ISO week %d, week day %d and weekend %t do not match the date, expected %d, %d and %t",
+				day.IsoWeekNumber, day.IsoWeekDay, day.WeekEnd, expected.IsoWeekNumber, expected.IsoWeekDay, expected.WeekEnd),
+		}, func() {
+			day.IsoWeekNumber, day.IsoWeekDay, day.WeekEnd = expected.IsoWeekNumber, expected.IsoWeekDay, expected.WeekEnd
+		}, dayKey)
+	}
+}
+
+// checkUnitDays checks that all units of a day start on that day
+func (c *checker) checkUnitDays(dayKey string) {
+	day := c.a.Days[dayKey]
+	for _, unitID := range sortedUnitIDs(day) {
+		unit := day.Units[unitID]
+		if unit.Start == nil {
+			c.report(Problem{Check: CheckUnitStart, DayKey: dayKey, UnitID: unitID, Message: "the unit has no start time"}, nil)
+			continue
+		}
+		if startDayKey := unit.Start.Format(time.DateOnly); startDayKey != dayKey {
+			c.reportDayChange(Problem{Check: CheckUnitDay, DayKey: dayKey, UnitID: unitID, Message: "the unit starts on " + startDayKey}, func() {
+				c.moveUnit(dayKey, startDayKey, unitID)
+			}, dayKey, startDayKey)
+		}
+	}
+}
+
+// checkUnitTimes checks the stop time and duration of all units of a day
+func (c *checker) checkUnitTimes(dayKey string) {
+	day := c.a.Days[dayKey]
+	for _, unitID := range sortedUnitIDs(day) {
+		unit := day.Units[unitID]
+		problem := Problem{DayKey: dayKey, UnitID: unitID}
+		running := c.a.CurrentRunningUnit != nil && c.a.CurrentRunningUnit.DayKey == dayKey && c.a.CurrentRunningUnit.UnitID == unitID
+
+		if unit.Start == nil {
+			continue
+		}
+		if unit.Stop == nil {
+			if !running {
+				problem.Check, problem.Message = CheckOpenUnit, "the unit has no stop time but is not running"
+				c.report(problem, nil)
+			}
+			if unit.Duration != nil {
+				problem.Check, problem.Message = CheckDuration, "the unit has a duration but no stop time"
+				c.reportDayChange(problem, func() {
+					unit.Duration = nil
+					day.Units[unitID] = unit
+				}, dayKey)
+			}
+			continue
+		}
+		if unit.Stop.Before(*unit.Start) {
+			problem.Check, problem.Message = CheckStopBeforeStart, fmt.Sprintf("the unit stops at %s before it starts at %s", unit.Stop.Format(time.DateTime), unit.Start.Format(time.DateTime))
+			c.report(problem, nil)
+			continue
+		}
+		expected := unit.Stop.Sub(*unit.Start)
+		if unit.Duration == nil || unit.Duration.Duration != expected {
+			actual := "no duration"
+			if unit.Duration != nil {
+				actual = "duration " + unit.Duration.String()
+			}
+			problem.Check, problem.Message = CheckDuration, fmt.Sprintf("%s does not match stop - start of %s", actual, expected)
+			c.reportDayChange(problem, func() {
+				unit.Duration = &models.AeonDuration{Duration: expected}
+				day.Units[unitID] = unit
+			}, dayKey)
+		}
+	}
+}
+
+// checkOverlaps checks that the completed units of a day do not overlap
+func (c *checker) checkOverlaps(dayKey string) {
+	day := c.a.Days[dayKey]
+	unitIDs := sortedUnitIDs(day)
+	for i, unitID := range unitIDs {
+		unit := day.Units[unitID]
+		if unit.Start == nil || unit.Stop == nil {
+			continue
+		}
+		for _, otherID := range unitIDs[i+1:] {
+			other := day.Units[otherID]
+			if other.Start == nil || other.Stop == nil {
+				continue
+			}
+			if unit.Start.Before(*other.Stop) && other.Start.Before(*unit.Stop) {
+				c.report(Problem{Check: CheckOverlap, DayKey: dayKey, UnitID: unitID, Message: "the unit overlaps unit " + otherID.String()}, nil)
+			}
+		}
+	}
+}
+
+// checkTotalHours checks that the total and overtime hours of a day match the durations of its units
+func (c *checker) checkTotalHours(dayKey string) {
+	day := c.a.Days[dayKey]
+	recalculated := *day
+	tracking.RecalculateDay(&recalculated, c.workingHoursConfig)
+	if c.changedDays[dayKey] {
+		// Hours of days changed by repairs are recalculated without reporting another problem
+		if c.repair {
+			day.TotalHours, day.OvertimeHours = recalculated.TotalHours, recalculated.OvertimeHours
+		}
+		return
+	}
+	if actual := durationOf(day.TotalHours); actual != recalculated.TotalHours.Duration {
+		c.report(Problem{
+			Check:   CheckTotalHours,
+			DayKey:  dayKey,
+			Message: fmt.Sprintf("total hours %s do not match the units of the day, expected %s", actual, recalculated.TotalHours.Duration),
+		}, func() {
+			day.TotalHours = recalculated.TotalHours
+		})
+	}
+	// Days created in advance have no overtime until units are tracked on them
+	if actual := durationOf(day.OvertimeHours); actual != recalculated.OvertimeHours.Duration && (actual != 0 || len(day.Units) > 0) {
+		c.report(Problem{
+			Check:   CheckOvertimeHours,
+			DayKey:  dayKey,
+			Message: fmt.Sprintf("overtime hours %s do not match the units of the day, expected %s", actual, recalculated.OvertimeHours.Duration),
+		}, func() {
+			day.OvertimeHours = recalculated.OvertimeHours
+		})
+	}
+}
+
+// durationOf returns the duration, which is zero if it is not set
+func durationOf(duration *models.AeonDuration) time.Duration {
+	if duration == nil {
+		return 0
+	}
+	return duration.Duration
+}
+
+// moveUnit moves a unit to the day it starts on, creating the day if needed
+func (c *checker) moveUnit(fromDayKey, toDayKey string, unitID uuid.UUID) {
+	from := c.a.Days[fromDayKey]
+	unit := from.Units[unitID]
+	to, ok := c.a.Days[toDayKey]
+	if !ok || to == nil {
+		to = repositories.NewAoenDay(*unit.Start)
+		c.a.Days[toDayKey] = to
+	}
+	if to.Units == nil {
+		to.Units = map[uuid.UUID]models.AeonUnit{}
+	}
+	to.Units[unitID] = unit
+	delete(from.Units, unitID)
+	if running := c.a.CurrentRunningUnit; running != nil && running.DayKey == fromDayKey && running.UnitID == unitID {
+		running.DayKey = toDayKey
+	}
+}
+
+// sortedDayKeys returns the keys of all days in chronological order
+func sortedDayKeys(a *models.AeonVault) []string {
+	dayKeys := make([]string, 0, len(a.Days))
+	for dayKey := range a.Days {
+		dayKeys = append(dayKeys, dayKey)
+	}
+	sort.Strings(dayKeys)
+	return dayKeys
+}
+
+// sortedUnitIDs returns the IDs of the units of a day in a stable order
+func sortedUnitIDs(day *models.AeonDay) []uuid.UUID {
+	unitIDs := make([]uuid.UUID, 0, len(day.Units))
+	for unitID := range day.Units {
+		unitIDs = append(unitIDs, unitID)
+	}
+	sort.Slice(unitIDs, func(i, j int) bool {
+		return unitIDs[i].String() < unitIDs[j].String()
+	})
+	return unitIDs
+}
@@ -0,0 +1,230 @@
+package integrity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+var testUnitID = uuid.MustParse("6f1c2a4e-0d3b-4b8e-9a55-1f2e3d4c5b6a")
+
+// newConsistentVault returns a vault with a single completed unit of two hours on Tuesday, 2024-01-02
+func newConsistentVault() *models.AeonVault {
+	start := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	stop := start.Add(2 * time.Hour)
+	day := repositories.NewAoenDay(start)
+	day.Units[testUnitID] = models.AeonUnit{Start: &start, Stop: &stop, Duration: &models.AeonDuration{Duration: 2 * time.Hour}, Type: repositories.WorkType}
+	day.TotalHours = &models.AeonDuration{Duration: 2 * time.Hour}
+	day.OvertimeHours = &models.AeonDuration{Duration: -6 * time.Hour}
+	return &models.AeonVault{Days: map[string]*models.AeonDay{"2024-01-02": day}}
+}
+
+func TestCheckAndRepair(t *testing.T) {
+	workingHoursConfig := configuration.GetDefaultWorkingHoursConfig()
+	tests := []struct {
+		name               string
+		corrupt            func(a *models.AeonVault)
+		expectedChecks     []string
+		expectedUnrepaired int
+		verify             func(t *testing.T, a *models.AeonVault)
+	}{
+		{
+			name:           "ConsistentVault",
+			corrupt:        func(a *models.AeonVault) {},
+			expectedChecks: nil,
+		},
+		{
+			name: "RunningUnitMissing",
+			corrupt: func(a *models.AeonVault) {
+				a.CurrentRunningUnit = &models.AeonCurrentRunningUnit{DayKey: "2024-01-02", UnitID: uuid.New()}
+			},
+			expectedChecks: []string{CheckRunningUnit},
+			verify: func(t *testing.T, a *models.AeonVault) {
+				assert.Nil(t, a.CurrentRunningUnit)
+			},
+		},
+		{
+			name: "DurationMismatch",
+			corrupt: func(a *models.AeonVault) {
+				unit := a.Days["2024-01-02"].Units[testUnitID]
+				unit.Duration = &models.AeonDuration{Duration: time.Hour}
+				a.Days["2024-01-02"].Units[testUnitID] = unit
+			},
+			expectedChecks: []string{CheckDuration},
+			verify: func(t *testing.T, a *models.AeonVault) {
+				assert.Equal(t, 2*time.Hour, a.Days["2024-01-02"].Units[testUnitID].Duration.Duration)
+			},
+		},
+		{
+			name: "WrongCalendarFields",
+			corrupt: func(a *models.AeonVault) {
+				a.Days["2024-01-02"].IsoWeekNumber = 0
+			},
+			expectedChecks: []string{CheckCalendar},
+			verify: func(t *testing.T, a *models.AeonVault) {
+				assert.Equal(t, 1, a.Days["2024-01-02"].IsoWeekNumber)
+			},
+		},
+		{
+			name: "UnitOnWrongDay",
+			corrupt: func(a *models.AeonVault) {
+				a.Days["2024-01-03"] = a.Days["2024-01-02"]
+				delete(a.Days, "2024-01-02")
+				a.Days["2024-01-03"].IsoWeekDay = 3
+			},
+			expectedChecks: []string{CheckUnitDay},
+			verify: func(t *testing.T, a *models.AeonVault) {
+				assert.Contains(t, a.Days["2024-01-02"].Units, testUnitID)
+				assert.Empty(t, a.Days["2024-01-03"].Units)
+				assert.Equal(t, 2*time.Hour, a.Days["2024-01-02"].TotalHours.Duration)
+				assert.Equal(t, time.Duration(0), a.Days["2024-01-03"].TotalHours.Duration)
+			},
+		},
+		{
+			name: "NullDay",
+			corrupt: func(a *models.AeonVault) {
+				a.Days["2024-01-03"] = nil
+			},
+			expectedChecks: []string{CheckNullDay},
+			verify: func(t *testing.T, a *models.AeonVault) {
+				if assert.NotNil(t, a.Days["2024-01-03"]) {
+					assert.Equal(t, 3, a.Days["2024-01-03"].IsoWeekDay)
+					assert.Empty(t, a.Days["2024-01-03"].Units)
+				}
+			},
+		},
+		{
+			name: "NullDayWithoutDateIsRemoved",
+			corrupt: func(a *models.AeonVault) {
+				a.Days["not-a-date"] = nil
+			},
+			expectedChecks: []string{CheckNullDay},
+			verify: func(t *testing.T, a *models.AeonVault) {
+				assert.NotContains(t, a.Days, "not-a-date")
+			},
+		},
+		{
+			name: "RunningUnitOnNullDay",
+			corrupt: func(a *models.AeonVault) {
+				a.Days["2024-01-03"] = nil
+				a.CurrentRunningUnit = &models.AeonCurrentRunningUnit{DayKey: "2024-01-03", UnitID: uuid.New()}
+			},
+			expectedChecks: []string{CheckRunningUnit, CheckNullDay},
+			verify: func(t *testing.T, a *models.AeonVault) {
+				assert.Nil(t, a.CurrentRunningUnit)
+				assert.NotNil(t, a.Days["2024-01-03"])
+			},
+		},
+		{
+			name: "UnitMovedToNullDay",
+			corrupt: func(a *models.AeonVault) {
+				unit := a.Days["2024-01-02"].Units[testUnitID]
+				start, stop := unit.Start.AddDate(0, 0, 1), unit.Stop.AddDate(0, 0, 1)
+				unit.Start, unit.Stop = &start, &stop
+				a.Days["2024-01-02"].Units[testUnitID] = unit
+				a.Days["2024-01-03"] = nil
+			},
+			expectedChecks: []string{CheckUnitDay, CheckNullDay},
+			verify: func(t *testing.T, a *models.AeonVault) {
+				assert.Contains(t, a.Days["2024-01-03"].Units, testUnitID)
+				assert.Equal(t, 2*time.Hour, a.Days["2024-01-03"].TotalHours.Duration)
+			},
+		},
+		{
+			name: "TotalHoursMismatch",
+			corrupt: func(a *models.AeonVault) {
+				a.Days["2024-01-02"].TotalHours = &models.AeonDuration{Duration: 5 * time.Hour}
+			},
+			expectedChecks: []string{CheckTotalHours},
+			verify: func(t *testing.T, a *models.AeonVault) {
+				assert.Equal(t, 2*time.Hour, a.Days["2024-01-02"].TotalHours.Duration)
+				assert.Equal(t, -6*time.Hour, a.Days["2024-01-02"].OvertimeHours.Duration)
+			},
+		},
+		{
+			name: "OvertimeHoursMismatch",
+			corrupt: func(a *models.AeonVault) {
+				a.Days["2024-01-02"].OvertimeHours = &models.AeonDuration{Duration: 2 * time.Hour}
+			},
+			expectedChecks: []string{CheckOvertimeHours},
+			verify: func(t *testing.T, a *models.AeonVault) {
+				assert.Equal(t, 2*time.Hour, a.Days["2024-01-02"].TotalHours.Duration)
+				assert.Equal(t, -6*time.Hour, a.Days["2024-01-02"].OvertimeHours.Duration)
+			},
+		},
+		{
+			name: "DayWithoutUnitsHasNoOvertime",
+			corrupt: func(a *models.AeonVault) {
+				delete(a.Days["2024-01-02"].Units, testUnitID)
+				a.Days["2024-01-02"].TotalHours, a.Days["2024-01-02"].OvertimeHours = nil, nil
+			},
+			expectedChecks: nil,
+		},
+		{
+			name: "MissingHoursAreReportedBoth",
+			corrupt: func(a *models.AeonVault) {
+				a.Days["2024-01-02"].TotalHours, a.Days["2024-01-02"].OvertimeHours = nil, nil
+			},
+			expectedChecks: []string{CheckTotalHours, CheckOvertimeHours},
+			verify: func(t *testing.T, a *models.AeonVault) {
+				assert.Equal(t, 2*time.Hour, a.Days["2024-01-02"].TotalHours.Duration)
+				assert.Equal(t, -6*time.Hour, a.Days["2024-01-02"].OvertimeHours.Duration)
+			},
+		},
+		{
+			name: "OpenUnitIsNotRepaired",
+			corrupt: func(a *models.AeonVault) {
+				unit := a.Days["2024-01-02"].Units[testUnitID]
+				unit.Stop, unit.Duration = nil, nil
+				a.Days["2024-01-02"].Units[testUnitID] = unit
+			},
+			expectedChecks:     []string{CheckOpenUnit, CheckTotalHours, CheckOvertimeHours},
+			expectedUnrepaired: 1,
+		},
+		{
+			name: "OverlapIsNotRepaired",
+			corrupt: func(a *models.AeonVault) {
+				start := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+				stop := start.Add(time.Hour)
+				a.Days["2024-01-02"].Units[uuid.New()] = models.AeonUnit{Start: &start, Stop: &stop, Duration: &models.AeonDuration{Duration: time.Hour}, Type: repositories.WorkType}
+				a.Days["2024-01-02"].TotalHours.Duration = 3 * time.Hour
+				a.Days["2024-01-02"].OvertimeHours.Duration = -5 * time.Hour
+			},
+			expectedChecks:     []string{CheckOverlap},
+			expectedUnrepaired: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newConsistentVault()
+			tt.corrupt(a)
+
+			problems := Check(a, workingHoursConfig)
+			var checks []string
+			for _, problem := range problems {
+				checks = append(checks, problem.Check)
+				assert.False(t, problem.Repaired, "checking must not repair")
+			}
+			assert.ElementsMatch(t, tt.expectedChecks, checks)
+
+			repaired := Repair(a, workingHoursConfig)
+			assert.Len(t, repaired, len(problems))
+			assert.Len(t, Unrepaired(repaired), tt.expectedUnrepaired)
+			if tt.verify != nil {
+				tt.verify(t, a)
+			}
+			assert.Len(t, Check(a, workingHoursConfig), tt.expectedUnrepaired, "only unrepairable problems should remain")
+		})
+	}
+}
+
+func TestProblemString(t *testing.T) {
+	problem := Problem{Check: CheckDuration, DayKey: "2024-01-02", UnitID: testUnitID, Message: "duration 1h0m0s does not match stop - start of 2h0m0s", Repairable: true}
+	assert.Equal(t, "2024-01-02 unit 6f1c2a4e-0d3b-4b8e-9a55-1f2e3d4c5b6a: [duration] duration 1h0m0s does not match stop - start of 2h0m0s (repairable)", problem.String())
+}
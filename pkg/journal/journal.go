@@ -0,0 +1,265 @@
+// Package journal records vault mutations as reversible operations, so that they can be undone and redone.
+package journal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
+)
+
+const (
+	journalFileName = "aeon_journal.json"
+	// MaxEntries is the maximum number of operations kept in the journal, older operations are dropped.
+	MaxEntries = 500
+	SourceCLI  = "cli"
+	SourceAPI  = "api"
+	SourceTUI  = "tui"
+)
+
+type (
+	// State is a serialized snapshot of the vault, used to find the days changed by an operation.
+	State struct {
+		Days               map[string]json.RawMessage
+		CurrentRunningUnit *models.AeonCurrentRunningUnit
+	}
+	// Patch holds the serialized state of all days touched by an operation, a null day did not exist.
+	Patch struct {
+		Days               map[string]json.RawMessage     `json:"days"`
+		CurrentRunningUnit *models.AeonCurrentRunningUnit `json:"current_running_unit,omitempty"`
+	}
+	// Entry represents a single recorded operation together with its inverse.
+	Entry struct {
+		ID        int       `json:"id"`
+		Timestamp time.Time `json:"timestamp"`
+		Source    string    `json:"source"`
+		Operation string    `json:"operation"`
+		Arguments string    `json:"arguments,omitempty"`
+		Undo      Patch     `json:"undo"`
+		Redo      Patch     `json:"redo"`
+	}
+	// Journal holds the recorded operations, Cursor is the number of entries which are currently applied.
+	Journal struct {
+		Entries []Entry `json:"entries"`
+		Cursor  int     `json:"cursor"`
+	}
+)
+
+var nullDay = json.RawMessage("null")
+
+// Load loads the operation journal from the provided folder, if no journal exists an empty one is returned.
+// An encrypted journal is decrypted with the key.
+func Load(folder string, key *vaultcrypt.Key) (*Journal, error) {
+	fileContent, err := os.ReadFile(FilePath(folder))
+	if errors.Is(err, os.ErrNotExist) {
+		return &Journal{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if vaultcrypt.IsEncrypted(fileContent) {
+		if fileContent, err = vaultcrypt.Decrypt(key, fileContent); err != nil {
+			return nil, err
+		}
+	}
+	var j Journal
+	if err = json.Unmarshal(fileContent, &j); err != nil {
+		return nil, err
+	}
+	if j.Cursor < 0 || j.Cursor > len(j.Entries) {
+		j.Cursor = len(j.Entries)
+	}
+	return &j, nil
+}
+
+// Save saves the operation journal to the provided folder, encrypted with the key unless it is nil.
+func Save(folder string, j *Journal, key *vaultcrypt.Key) error {
+	jsonData, err := json.MarshalIndent(j, "", "    ")
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		if jsonData, err = vaultcrypt.Encrypt(key, jsonData); err != nil {
+			return err
+		}
+	}
+	return repositories.WriteFileAtomic(FilePath(folder), jsonData, 0644)
+}
+
+// FilePath returns the path of the operation journal in the provided folder.
+func FilePath(folder string) string {
+	return filepath.Join(folder, journalFileName)
+}
+
+// Capture serializes the current state of the vault.
+func Capture(a *models.AeonVault) (State, error) {
+	state := State{Days: make(map[string]json.RawMessage, len(a.Days))}
+	for dayKey, day := range a.Days {
+		dayJSON, err := json.Marshal(day)
+		if err != nil {
+			return State{}, err
+		}
+		state.Days[dayKey] = dayJSON
+	}
+	if a.CurrentRunningUnit != nil {
+		runningUnit := *a.CurrentRunningUnit
+		state.CurrentRunningUnit = &runningUnit
+	}
+	return state, nil
+}
+
+// Record adds an operation to the journal, if the operation changed the vault.
+// All operations which have been undone are discarded, so they can no longer be redone.
+func (j *Journal) Record(source, operation, arguments string, before State, a *models.AeonVault) error {
+	after, err := Capture(a)
+	if err != nil {
+		return err
+	}
+	undo := Patch{Days: map[string]json.RawMessage{}, CurrentRunningUnit: before.CurrentRunningUnit}
+	redo := Patch{Days: map[string]json.RawMessage{}, CurrentRunningUnit: after.CurrentRunningUnit}
+	for dayKey, beforeDay := range before.Days {
+		afterDay, ok := after.Days[dayKey]
+		if !ok {
+			afterDay = nullDay
+		}
+		if !equalJSON(beforeDay, afterDay) {
+			undo.Days[dayKey] = beforeDay
+			redo.Days[dayKey] = afterDay
+		}
+	}
+	for dayKey, afterDay := range after.Days {
+		if _, ok := before.Days[dayKey]; !ok {
+			undo.Days[dayKey] = nullDay
+			redo.Days[dayKey] = afterDay
+		}
+	}
+	if len(redo.Days) == 0 && sameRunningUnit(before.CurrentRunningUnit, after.CurrentRunningUnit) {
+		return nil
+	}
+
+	nextID := 1
+	if len(j.Entries) > 0 {
+		nextID = j.Entries[len(j.Entries)-1].ID + 1
+	}
+	j.Entries = append(j.Entries[:j.Cursor], Entry{
+		ID:        nextID,
+		Timestamp: time.Now(),
+		Source:    source,
+		Operation: operation,
+		Arguments: arguments,
+		Undo:      undo,
+		Redo:      redo,
+	})
+	if len(j.Entries) > MaxEntries {
+		j.Entries = j.Entries[len(j.Entries)-MaxEntries:]
+	}
+	j.Cursor = len(j.Entries)
+	return nil
+}
+
+// Undo reverts the last applied operation on the vault and returns it.
+// If the vault has been changed since the operation was recorded, an error is returned unless force is set.
+func (j *Journal) Undo(a *models.AeonVault, force bool) (Entry, error) {
+	if j.Cursor == 0 {
+		return Entry{}, aeonerrors.ErrNothingToUndo
+	}
+	entry := j.Entries[j.Cursor-1]
+	if err := apply(a, entry.Redo, entry.Undo, force); err != nil {
+		return Entry{}, err
+	}
+	j.Cursor--
+	return entry, nil
+}
+
+// Redo applies the last undone operation on the vault again and returns it.
+// If the vault has been changed since the operation was undone, an error is returned unless force is set.
+func (j *Journal) Redo(a *models.AeonVault, force bool) (Entry, error) {
+	if j.Cursor == len(j.Entries) {
+		return Entry{}, aeonerrors.ErrNothingToRedo
+	}
+	entry := j.Entries[j.Cursor]
+	if err := apply(a, entry.Undo, entry.Redo, force); err != nil {
+		return Entry{}, err
+	}
+	j.Cursor++
+	return entry, nil
+}
+
+// Recent returns up to limit entries, the most recent first, and whether each of them is currently applied.
+func (j *Journal) Recent(limit int) ([]Entry, []bool) {
+	var entries []Entry
+	var applied []bool
+	for i := len(j.Entries) - 1; i >= 0 && (limit <= 0 || len(entries) < limit); i-- {
+		entries = append(entries, j.Entries[i])
+		applied = append(applied, i < j.Cursor)
+	}
+	return entries, applied
+}
+
+// apply replaces the days contained in the target patch, after checking that the vault still matches the expected patch.
+func apply(a *models.AeonVault, expected, target Patch, force bool) error {
+	current, err := Capture(a)
+	if err != nil {
+		return err
+	}
+	if !force {
+		for dayKey, expectedDay := range expected.Days {
+			currentDay, ok := current.Days[dayKey]
+			if !ok {
+				currentDay = nullDay
+			}
+			if !equalJSON(currentDay, expectedDay) {
+				return aeonerrors.ErrJournalConflict
+			}
+		}
+		if !sameRunningUnit(current.CurrentRunningUnit, expected.CurrentRunningUnit) {
+			return aeonerrors.ErrJournalConflict
+		}
+	}
+
+	days := make(map[string]*models.AeonDay, len(target.Days))
+	for dayKey, dayJSON := range target.Days {
+		var day *models.AeonDay
+		if err = json.Unmarshal(dayJSON, &day); err != nil {
+			return err
+		}
+		days[dayKey] = day
+	}
+	for dayKey, day := range days {
+		if day == nil {
+			delete(a.Days, dayKey)
+			continue
+		}
+		a.Days[dayKey] = day
+	}
+	a.CurrentRunningUnit = nil
+	if target.CurrentRunningUnit != nil {
+		runningUnit := *target.CurrentRunningUnit
+		a.CurrentRunningUnit = &runningUnit
+	}
+	return nil
+}
+
+// equalJSON reports whether both JSON documents are equal, ignoring insignificant whitespace.
+func equalJSON(a, b json.RawMessage) bool {
+	var compactA, compactB bytes.Buffer
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
+}
+
+// sameRunningUnit reports whether both running units point to the same unit.
+func sameRunningUnit(a, b *models.AeonCurrentRunningUnit) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
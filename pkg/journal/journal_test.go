@@ -0,0 +1,155 @@
+package journal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestVault() *models.AeonVault {
+	start := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	return &models.AeonVault{
+		Days: map[string]*models.AeonDay{
+			"2024-01-02": {
+				IsoWeekNumber: 1,
+				IsoWeekDay:    2,
+				Units: map[uuid.UUID]models.AeonUnit{
+					uuid.New(): {Start: &start, Type: "WORK"},
+				},
+			},
+		},
+	}
+}
+
+func TestJournalUndoRedo(t *testing.T) {
+	a := newTestVault()
+	j := &Journal{}
+	before, err := Capture(a)
+	assert.NoError(t, err)
+
+	unitID := uuid.New()
+	start := time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC)
+	a.Days["2024-01-03"] = &models.AeonDay{IsoWeekNumber: 1, IsoWeekDay: 3, Units: map[uuid.UUID]models.AeonUnit{unitID: {Start: &start, Type: "WORK"}}}
+	a.CurrentRunningUnit = &models.AeonCurrentRunningUnit{DayKey: "2024-01-03", UnitID: unitID}
+	assert.NoError(t, j.Record(SourceCLI, "start", "", before, a))
+	assert.Len(t, j.Entries, 1)
+	assert.Len(t, j.Entries[0].Redo.Days, 1)
+
+	entry, err := j.Undo(a, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "start", entry.Operation)
+	assert.NotContains(t, a.Days, "2024-01-03")
+	assert.Nil(t, a.CurrentRunningUnit)
+	_, err = j.Undo(a, false)
+	assert.ErrorIs(t, err, aeonerrors.ErrNothingToUndo)
+
+	_, err = j.Redo(a, false)
+	assert.NoError(t, err)
+	assert.Contains(t, a.Days, "2024-01-03")
+	assert.Equal(t, unitID, a.CurrentRunningUnit.UnitID)
+	_, err = j.Redo(a, false)
+	assert.ErrorIs(t, err, aeonerrors.ErrNothingToRedo)
+}
+
+func TestJournalRecord(t *testing.T) {
+	tests := []struct {
+		name            string
+		change          func(a *models.AeonVault)
+		expectedEntries int
+	}{
+		{
+			name:            "NoChangeIsNotRecorded",
+			change:          func(a *models.AeonVault) {},
+			expectedEntries: 0,
+		},
+		{
+			name: "ChangedDayIsRecorded",
+			change: func(a *models.AeonVault) {
+				a.Days["2024-01-02"].VacationDay = true
+			},
+			expectedEntries: 1,
+		},
+		{
+			name: "RemovedDayIsRecorded",
+			change: func(a *models.AeonVault) {
+				delete(a.Days, "2024-01-02")
+			},
+			expectedEntries: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestVault()
+			j := &Journal{}
+			before, err := Capture(a)
+			assert.NoError(t, err)
+			tt.change(a)
+			assert.NoError(t, j.Record(SourceAPI, "test", "", before, a))
+			assert.Len(t, j.Entries, tt.expectedEntries)
+		})
+	}
+}
+
+func TestJournalUndoConflict(t *testing.T) {
+	a := newTestVault()
+	j := &Journal{}
+	before, err := Capture(a)
+	assert.NoError(t, err)
+	a.Days["2024-01-02"].VacationDay = true
+	assert.NoError(t, j.Record(SourceCLI, "vacation", "", before, a))
+
+	a.Days["2024-01-02"].PublicHoliday = true
+	_, err = j.Undo(a, false)
+	assert.ErrorIs(t, err, aeonerrors.ErrJournalConflict)
+
+	_, err = j.Undo(a, true)
+	assert.NoError(t, err)
+	assert.False(t, a.Days["2024-01-02"].VacationDay)
+	assert.False(t, a.Days["2024-01-02"].PublicHoliday)
+}
+
+func TestJournalRecordDiscardsUndoneEntries(t *testing.T) {
+	a := newTestVault()
+	j := &Journal{}
+	for _, operation := range []string{"first", "second"} {
+		before, err := Capture(a)
+		assert.NoError(t, err)
+		a.Days["2024-01-02"].IsoWeekDay++
+		assert.NoError(t, j.Record(SourceCLI, operation, "", before, a))
+	}
+	_, err := j.Undo(a, false)
+	assert.NoError(t, err)
+
+	before, err := Capture(a)
+	assert.NoError(t, err)
+	a.Days["2024-01-02"].VacationDay = true
+	assert.NoError(t, j.Record(SourceCLI, "third", "", before, a))
+
+	entries, applied := j.Recent(0)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "third", entries[0].Operation)
+	assert.Equal(t, 3, entries[0].ID)
+	assert.Equal(t, []bool{true, true}, applied)
+}
+
+func TestJournalSaveAndLoad(t *testing.T) {
+	folder := t.TempDir()
+	a := newTestVault()
+	j := &Journal{}
+	before, err := Capture(a)
+	assert.NoError(t, err)
+	a.Days["2024-01-02"].VacationDay = true
+	assert.NoError(t, j.Record(SourceCLI, "vacation", "", before, a))
+	assert.NoError(t, Save(folder, j, nil))
+
+	loaded, err := Load(folder, nil)
+	assert.NoError(t, err)
+	_, err = loaded.Undo(a, false)
+	assert.NoError(t, err, "a saved journal should still match the vault")
+	assert.False(t, a.Days["2024-01-02"].VacationDay)
+}
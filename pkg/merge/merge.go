@@ -0,0 +1,470 @@
+// Package merge combines diverging time tracking data, for example of two machines, unit by unit.
+package merge
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/jame-developer/aeontrac/pkg/tracking"
+)
+
+// Kinds of conflicts
+const (
+	// ConflictOverlap marks units of the local and the other data overlapping in time
+	ConflictOverlap = "overlap"
+	// ConflictModified marks a unit which has been changed differently in the local and the other data
+	ConflictModified = "modified"
+	// ConflictDeleted marks a unit which has been deleted on one side and changed on the other, only found with a base
+	ConflictDeleted = "deleted"
+)
+
+// Names of the strategies resolving all conflicts the same way
+const (
+	StrategyLocal = "local"
+	StrategyOther = "other"
+	StrategyBoth  = "both"
+)
+
+// Resolution decides which side of a conflict is kept.
+type Resolution int
+
+const (
+	// KeepLocal keeps the local unit and drops the other one
+	KeepLocal Resolution = iota
+	// KeepOther keeps the other unit and drops the local one
+	KeepOther
+	// KeepBoth keeps both units, a unit modified on both sides is kept twice with a new ID for the other version
+	KeepBoth
+)
+
+type (
+	// Conflict describes units of the local and the other data which cannot be merged automatically.
+	// Local or Other is nil if the unit has been deleted on that side.
+	Conflict struct {
+		Kind       string
+		DayKey     string
+		LocalID    uuid.UUID
+		Local      *models.AeonUnit
+		OtherID    uuid.UUID
+		Other      *models.AeonUnit
+		Resolution Resolution
+	}
+	// Resolver decides how a conflict is resolved, for example by asking the user.
+	Resolver func(conflict Conflict) (Resolution, error)
+	// Result summarizes the changes of a merge to the local data.
+	Result struct {
+		Added       int
+		Updated     int
+		Deleted     int
+		Conflicts   []Conflict
+		ChangedDays []string
+	}
+	// located is a unit together with the key of the day it is stored under
+	located struct {
+		dayKey string
+		unit   models.AeonUnit
+	}
+	// merger holds the state of a single merge
+	merger struct {
+		local, other, base map[uuid.UUID]located
+		// merged holds the units of the merge result
+		merged map[uuid.UUID]located
+		// fromOther holds the units of the merge result which have been taken from the other data
+		fromOther map[uuid.UUID]bool
+		// copies maps the new IDs of other versions kept besides the local version to the ID of the unit
+		copies    map[uuid.UUID]uuid.UUID
+		resolve   Resolver
+		conflicts []Conflict
+	}
+)
+
+// String describes the resolution.
+func (r Resolution) String() string {
+	switch r {
+	case KeepLocal:
+		return "kept local"
+	case KeepOther:
+		return "kept other"
+	case KeepBoth:
+		return "kept both"
+	default:
+		return fmt.Sprintf("resolution %d", int(r))
+	}
+}
+
+// String describes the conflict with both of its units.
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: [%s] local %s, other %s", c.DayKey, c.Kind, describeUnit(c.LocalID, c.Local), describeUnit(c.OtherID, c.Other))
+}
+
+// StrategyResolver returns a resolver which resolves every conflict with the named strategy.
+func StrategyResolver(strategy string) (Resolver, error) {
+	var resolution Resolution
+	switch strategy {
+	case StrategyLocal:
+		resolution = KeepLocal
+	case StrategyOther:
+		resolution = KeepOther
+	case StrategyBoth:
+		resolution = KeepBoth
+	default:
+		return nil, fmt.Errorf("unknown merge strategy %s, use %s, %s or %s", strategy, StrategyLocal, StrategyOther, StrategyBoth)
+	}
+	return func(Conflict) (Resolution, error) {
+		return resolution, nil
+	}, nil
+}
+
+// Merge merges the units of the other data into the local data, matching units by their ID.
+// Without base, units missing on one side are added from the other side and no unit is deleted.
+// With base, the common ancestor of both sides, a unit changed on only one side takes that change, including deletions.
+// Units changed on both sides and overlapping units of different sides are resolved by resolve.
+// The hours of every changed day are recalculated.
+func Merge(local, other, base *models.AeonVault, resolve Resolver, workingHoursConfig configuration.WorkingHoursConfig) (Result, error) {
+	m := &merger{
+		local:     indexUnits(local),
+		other:     indexUnits(other),
+		merged:    indexUnits(local),
+		fromOther: map[uuid.UUID]bool{},
+		copies:    map[uuid.UUID]uuid.UUID{},
+		resolve:   resolve,
+	}
+	if base != nil {
+		m.base = indexUnits(base)
+	}
+	for _, unitID := range m.unitIDs() {
+		if err := m.mergeUnit(unitID); err != nil {
+			return Result{}, err
+		}
+	}
+	if err := m.resolveOverlaps(); err != nil {
+		return Result{}, err
+	}
+	result := m.apply(local, other, base, workingHoursConfig)
+	result.Conflicts = m.conflicts
+	return result, nil
+}
+
+// unitIDs returns the IDs of all units of any side, sorted by day and ID
+func (m *merger) unitIDs() []uuid.UUID {
+	locations := map[uuid.UUID]string{}
+	for _, units := range []map[uuid.UUID]located{m.base, m.other, m.local} {
+		for unitID, unit := range units {
+			locations[unitID] = unit.dayKey
+		}
+	}
+	unitIDs := make([]uuid.UUID, 0, len(locations))
+	for unitID := range locations {
+		unitIDs = append(unitIDs, unitID)
+	}
+	sort.Slice(unitIDs, func(i, j int) bool {
+		if locations[unitIDs[i]] != locations[unitIDs[j]] {
+			return locations[unitIDs[i]] < locations[unitIDs[j]]
+		}
+		return unitIDs[i].String() < unitIDs[j].String()
+	})
+	return unitIDs
+}
+
+// mergeUnit decides which version of a unit is part of the merge result
+func (m *merger) mergeUnit(unitID uuid.UUID) error {
+	local, inLocal := m.local[unitID]
+	other, inOther := m.other[unitID]
+	base, inBase := m.base[unitID]
+	switch {
+	case inLocal && inOther:
+		if sameUnit(local, other) || (inBase && sameUnit(base, other)) {
+			return nil
+		}
+		if inBase && sameUnit(base, local) {
+			m.takeOther(unitID, other)
+			return nil
+		}
+		return m.conflict(Conflict{Kind: ConflictModified, DayKey: local.dayKey, LocalID: unitID, Local: &local.unit, OtherID: unitID, Other: &other.unit},
+			func(resolution Resolution) {
+				switch resolution {
+				case KeepOther:
+					m.takeOther(unitID, other)
+				case KeepBoth:
+					copyID := uuid.New()
+					m.takeOther(copyID, other)
+					m.copies[copyID] = unitID
+				}
+			})
+	case inLocal && inBase:
+		// Deleted on the other side
+		if sameUnit(base, local) {
+			delete(m.merged, unitID)
+			return nil
+		}
+		return m.conflict(Conflict{Kind: ConflictDeleted, DayKey: local.dayKey, LocalID: unitID, Local: &local.unit, OtherID: unitID},
+			func(resolution Resolution) {
+				if resolution == KeepOther {
+					delete(m.merged, unitID)
+				}
+			})
+	case inOther && inBase:
+		// Deleted on the local side
+		if sameUnit(base, other) {
+			return nil
+		}
+		return m.conflict(Conflict{Kind: ConflictDeleted, DayKey: other.dayKey, LocalID: unitID, OtherID: unitID, Other: &other.unit},
+			func(resolution Resolution) {
+				if resolution != KeepLocal {
+					m.takeOther(unitID, other)
+				}
+			})
+	case inOther:
+		m.takeOther(unitID, other)
+	}
+	// Units only in the local data, or deleted on both sides, stay as they are
+	return nil
+}
+
+// resolveOverlaps finds units taken from the other data which overlap units only found in the local data
+func (m *merger) resolveOverlaps() error {
+	unitIDs := m.sortedMergedIDs()
+	for _, otherID := range unitIDs {
+		for _, localID := range unitIDs {
+			other, otherKept := m.merged[otherID]
+			local, localKept := m.merged[localID]
+			if !otherKept || !localKept || !m.fromOther[otherID] || m.fromOther[localID] || !overlap(other.unit, local.unit) {
+				continue
+			}
+			if m.copies[otherID] == localID {
+				// Both versions of the unit have been kept on purpose
+				continue
+			}
+			if inOther, ok := m.other[localID]; ok && sameUnit(inOther, local) {
+				// The other data has the same unit, so the overlap is not caused by the merge
+				continue
+			}
+			err := m.conflict(Conflict{Kind: ConflictOverlap, DayKey: other.dayKey, LocalID: localID, Local: &local.unit, OtherID: otherID, Other: &other.unit},
+				func(resolution Resolution) {
+					switch resolution {
+					case KeepLocal:
+						m.dropOther(otherID)
+					case KeepOther:
+						delete(m.merged, localID)
+					}
+				})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// conflict asks the resolver how to resolve a conflict and applies the resolution
+func (m *merger) conflict(conflict Conflict, apply func(resolution Resolution)) error {
+	resolution, err := m.resolve(conflict)
+	if err != nil {
+		return fmt.Errorf("error resolving conflict %s: %w", conflict, err)
+	}
+	conflict.Resolution = resolution
+	apply(resolution)
+	m.conflicts = append(m.conflicts, conflict)
+	return nil
+}
+
+// takeOther puts the other version of a unit into the merge result
+func (m *merger) takeOther(unitID uuid.UUID, other located) {
+	m.merged[unitID] = other
+	m.fromOther[unitID] = true
+}
+
+// dropOther removes a unit taken from the other data from the merge result, restoring its local version if there is one
+func (m *merger) dropOther(unitID uuid.UUID) {
+	delete(m.fromOther, unitID)
+	if local, ok := m.local[unitID]; ok {
+		m.merged[unitID] = local
+		return
+	}
+	delete(m.merged, unitID)
+}
+
+// sortedMergedIDs returns the IDs of the units of the merge result, sorted by start time and ID
+func (m *merger) sortedMergedIDs() []uuid.UUID {
+	unitIDs := make([]uuid.UUID, 0, len(m.merged))
+	for unitID := range m.merged {
+		unitIDs = append(unitIDs, unitID)
+	}
+	sort.Slice(unitIDs, func(i, j int) bool {
+		a, b := m.merged[unitIDs[i]].unit.Start, m.merged[unitIDs[j]].unit.Start
+		if a != nil && b != nil && !a.Equal(*b) {
+			return a.Before(*b)
+		}
+		return unitIDs[i].String() < unitIDs[j].String()
+	})
+	return unitIDs
+}
+
+// apply replaces the units of the local data with the merge result and recalculates the changed days
+func (m *merger) apply(local, other, base *models.AeonVault, workingHoursConfig configuration.WorkingHoursConfig) Result {
+	var result Result
+	changedDays := map[string]bool{}
+	for unitID, before := range m.local {
+		after, ok := m.merged[unitID]
+		switch {
+		case !ok:
+			result.Deleted++
+			changedDays[before.dayKey] = true
+		case !sameUnit(before, after):
+			result.Updated++
+			changedDays[before.dayKey] = true
+			changedDays[after.dayKey] = true
+		}
+	}
+	for unitID, after := range m.merged {
+		if _, ok := m.local[unitID]; !ok {
+			result.Added++
+			changedDays[after.dayKey] = true
+		}
+	}
+
+	if local.Days == nil {
+		local.Days = map[string]*models.AeonDay{}
+	}
+	for dayKey, otherDay := range other.Days {
+		day, ok := local.Days[dayKey]
+		if !ok {
+			copied := *otherDay
+			copied.Units = map[uuid.UUID]models.AeonUnit{}
+			local.Days[dayKey] = &copied
+			changedDays[dayKey] = true
+			continue
+		}
+		if vacationDay := mergeVacationDay(day, otherDay, baseDay(base, dayKey)); vacationDay != day.VacationDay {
+			day.VacationDay = vacationDay
+			changedDays[dayKey] = true
+		}
+	}
+	for dayKey := range changedDays {
+		if _, ok := local.Days[dayKey]; !ok {
+			date, _ := time.Parse(time.DateOnly, dayKey)
+			local.Days[dayKey] = repositories.NewAoenDay(date)
+		}
+		local.Days[dayKey].Units = map[uuid.UUID]models.AeonUnit{}
+	}
+	for unitID, unit := range m.merged {
+		if changedDays[unit.dayKey] {
+			local.Days[unit.dayKey].Units[unitID] = unit.unit
+		}
+	}
+	for dayKey := range changedDays {
+		tracking.RecalculateDay(local.Days[dayKey], workingHoursConfig)
+		result.ChangedDays = append(result.ChangedDays, dayKey)
+	}
+	slices.Sort(result.ChangedDays)
+
+	local.CurrentRunningUnit = m.runningUnit(local.CurrentRunningUnit, other.CurrentRunningUnit)
+	for _, year := range other.InitializedYears {
+		if !slices.Contains(local.InitializedYears, year) {
+			local.InitializedYears = append(local.InitializedYears, year)
+		}
+	}
+	slices.Sort(local.InitializedYears)
+	return result
+}
+
+// runningUnit returns the running unit of the merge result, the local one takes precedence.
+// A running unit which has been stopped or removed by the merge is no longer running.
+func (m *merger) runningUnit(candidates ...*models.AeonCurrentRunningUnit) *models.AeonCurrentRunningUnit {
+	for _, candidate := range candidates {
+		if candidate == nil {
+			continue
+		}
+		if unit, ok := m.merged[candidate.UnitID]; ok && unit.unit.Stop == nil {
+			return &models.AeonCurrentRunningUnit{DayKey: unit.dayKey, UnitID: candidate.UnitID}
+		}
+	}
+	return nil
+}
+
+// mergeVacationDay returns whether the merged day is a vacation day.
+// With base, the side which changed the flag wins, without base a vacation day on either side is kept.
+func mergeVacationDay(local, other, base *models.AeonDay) bool {
+	if base == nil {
+		return local.VacationDay || other.VacationDay
+	}
+	if local.VacationDay == base.VacationDay {
+		return other.VacationDay
+	}
+	return local.VacationDay
+}
+
+// baseDay returns the day of the base data, nil without base or if the base does not have the day
+func baseDay(base *models.AeonVault, dayKey string) *models.AeonDay {
+	if base == nil {
+		return nil
+	}
+	return base.Days[dayKey]
+}
+
+// indexUnits returns all units of the data by their ID
+func indexUnits(a *models.AeonVault) map[uuid.UUID]located {
+	units := map[uuid.UUID]located{}
+	for dayKey, day := range a.Days {
+		for unitID, unit := range day.Units {
+			units[unitID] = located{dayKey: dayKey, unit: unit}
+		}
+	}
+	return units
+}
+
+// sameUnit reports whether both versions of a unit are equal
+func sameUnit(a, b located) bool {
+	return a.dayKey == b.dayKey && sameTime(a.unit.Start, b.unit.Start) && sameTime(a.unit.Stop, b.unit.Stop) &&
+		sameDuration(a.unit.Duration, b.unit.Duration) && a.unit.Type == b.unit.Type && a.unit.Comment == b.unit.Comment
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func sameDuration(a, b *models.AeonDuration) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Duration == b.Duration
+}
+
+// overlap reports whether two completed units overlap in time
+func overlap(a, b models.AeonUnit) bool {
+	if a.Start == nil || a.Stop == nil || b.Start == nil || b.Stop == nil {
+		return false
+	}
+	return a.Start.Before(*b.Stop) && b.Start.Before(*a.Stop)
+}
+
+// describeUnit returns a short description of a unit with its times, type and comment
+func describeUnit(unitID uuid.UUID, unit *models.AeonUnit) string {
+	if unit == nil {
+		return "deleted"
+	}
+	var description strings.Builder
+	description.WriteString(unitID.String()[:8])
+	if unit.Start != nil {
+		description.WriteString(" " + unit.Start.Format("15:04"))
+	}
+	description.WriteString("-")
+	if unit.Stop != nil {
+		description.WriteString(unit.Stop.Format("15:04"))
+	}
+	description.WriteString(" " + unit.Type)
+	if unit.Comment != "" {
+		fmt.Fprintf(&description, " %q", unit.Comment)
+	}
+	return description.String()
+}
@@ -0,0 +1,232 @@
+package merge
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDayKey = "2024-01-02"
+
+var (
+	sharedID = uuid.MustParse("11111111-1111-4111-8111-111111111111")
+	localID  = uuid.MustParse("22222222-2222-4222-8222-222222222222")
+	otherID  = uuid.MustParse("33333333-3333-4333-8333-333333333333")
+)
+
+// newUnit returns a work unit on Tuesday, 2024-01-02 between the provided hours
+func newUnit(fromHour, toHour int, comment string) models.AeonUnit {
+	start := time.Date(2024, 1, 2, fromHour, 0, 0, 0, time.UTC)
+	stop := time.Date(2024, 1, 2, toHour, 0, 0, 0, time.UTC)
+	return repositories.NewAeonUnit(&start, &stop, comment, &models.AeonDuration{Duration: stop.Sub(start)}, repositories.WorkType)
+}
+
+// newVault returns a vault with the provided units on Tuesday, 2024-01-02
+func newVault(units map[uuid.UUID]models.AeonUnit) *models.AeonVault {
+	day := repositories.NewAoenDay(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	for unitID, unit := range units {
+		day.Units[unitID] = unit
+	}
+	return &models.AeonVault{Days: map[string]*models.AeonDay{testDayKey: day}}
+}
+
+func TestMerge(t *testing.T) {
+	workingHoursConfig := configuration.GetDefaultWorkingHoursConfig()
+	shared := newUnit(8, 9, "shared")
+	tests := []struct {
+		name              string
+		local             map[uuid.UUID]models.AeonUnit
+		other             map[uuid.UUID]models.AeonUnit
+		base              map[uuid.UUID]models.AeonUnit
+		strategy          string
+		expectedUnits     map[uuid.UUID]string
+		expectedConflicts []string
+		expectedResult    Result
+		expectedTotal     time.Duration
+		// expectedCopies is the number of other versions kept with a new ID
+		expectedCopies int
+	}{
+		{
+			name:           "TwoWayAddsUnitsOfOtherSide",
+			local:          map[uuid.UUID]models.AeonUnit{sharedID: shared, localID: newUnit(9, 10, "local")},
+			other:          map[uuid.UUID]models.AeonUnit{sharedID: shared, otherID: newUnit(10, 12, "other")},
+			strategy:       StrategyLocal,
+			expectedUnits:  map[uuid.UUID]string{sharedID: "shared", localID: "local", otherID: "other"},
+			expectedResult: Result{Added: 1, ChangedDays: []string{testDayKey}},
+			expectedTotal:  4 * time.Hour,
+		},
+		{
+			name:              "TwoWayOverlapKeepsLocal",
+			local:             map[uuid.UUID]models.AeonUnit{localID: newUnit(9, 11, "local")},
+			other:             map[uuid.UUID]models.AeonUnit{otherID: newUnit(10, 12, "other")},
+			strategy:          StrategyLocal,
+			expectedUnits:     map[uuid.UUID]string{localID: "local"},
+			expectedConflicts: []string{ConflictOverlap},
+			expectedResult:    Result{},
+			expectedTotal:     2 * time.Hour,
+		},
+		{
+			name:              "TwoWayOverlapKeepsOther",
+			local:             map[uuid.UUID]models.AeonUnit{localID: newUnit(9, 11, "local")},
+			other:             map[uuid.UUID]models.AeonUnit{otherID: newUnit(10, 13, "other")},
+			strategy:          StrategyOther,
+			expectedUnits:     map[uuid.UUID]string{otherID: "other"},
+			expectedConflicts: []string{ConflictOverlap},
+			expectedResult:    Result{Added: 1, Deleted: 1, ChangedDays: []string{testDayKey}},
+			expectedTotal:     3 * time.Hour,
+		},
+		{
+			name:              "TwoWayModifiedKeepsBoth",
+			local:             map[uuid.UUID]models.AeonUnit{sharedID: newUnit(9, 10, "local")},
+			other:             map[uuid.UUID]models.AeonUnit{sharedID: newUnit(9, 10, "other")},
+			strategy:          StrategyBoth,
+			expectedUnits:     map[uuid.UUID]string{sharedID: "local"},
+			expectedConflicts: []string{ConflictModified},
+			expectedResult:    Result{Added: 1, ChangedDays: []string{testDayKey}},
+			expectedTotal:     2 * time.Hour,
+			expectedCopies:    1,
+		},
+		{
+			name:           "TwoWayKeepsUnitsMissingOnOtherSide",
+			local:          map[uuid.UUID]models.AeonUnit{sharedID: shared, localID: newUnit(9, 10, "local")},
+			other:          map[uuid.UUID]models.AeonUnit{},
+			strategy:       StrategyOther,
+			expectedUnits:  map[uuid.UUID]string{sharedID: "shared", localID: "local"},
+			expectedResult: Result{},
+			expectedTotal:  2 * time.Hour,
+		},
+		{
+			name:           "ThreeWayTakesChangeOfOtherSide",
+			local:          map[uuid.UUID]models.AeonUnit{sharedID: shared},
+			other:          map[uuid.UUID]models.AeonUnit{sharedID: newUnit(8, 11, "extended")},
+			base:           map[uuid.UUID]models.AeonUnit{sharedID: shared},
+			strategy:       StrategyLocal,
+			expectedUnits:  map[uuid.UUID]string{sharedID: "extended"},
+			expectedResult: Result{Updated: 1, ChangedDays: []string{testDayKey}},
+			expectedTotal:  3 * time.Hour,
+		},
+		{
+			name:           "ThreeWayKeepsChangeOfLocalSide",
+			local:          map[uuid.UUID]models.AeonUnit{sharedID: newUnit(8, 11, "extended")},
+			other:          map[uuid.UUID]models.AeonUnit{sharedID: shared},
+			base:           map[uuid.UUID]models.AeonUnit{sharedID: shared},
+			strategy:       StrategyOther,
+			expectedUnits:  map[uuid.UUID]string{sharedID: "extended"},
+			expectedResult: Result{},
+			expectedTotal:  3 * time.Hour,
+		},
+		{
+			name:           "ThreeWayTakesDeletionOfOtherSide",
+			local:          map[uuid.UUID]models.AeonUnit{sharedID: shared, localID: newUnit(9, 10, "local")},
+			other:          map[uuid.UUID]models.AeonUnit{},
+			base:           map[uuid.UUID]models.AeonUnit{sharedID: shared},
+			strategy:       StrategyLocal,
+			expectedUnits:  map[uuid.UUID]string{localID: "local"},
+			expectedResult: Result{Deleted: 1, ChangedDays: []string{testDayKey}},
+			expectedTotal:  time.Hour,
+		},
+		{
+			name:              "ThreeWayDeletedAndModified",
+			local:             map[uuid.UUID]models.AeonUnit{},
+			other:             map[uuid.UUID]models.AeonUnit{sharedID: newUnit(8, 10, "changed")},
+			base:              map[uuid.UUID]models.AeonUnit{sharedID: shared},
+			strategy:          StrategyOther,
+			expectedUnits:     map[uuid.UUID]string{sharedID: "changed"},
+			expectedConflicts: []string{ConflictDeleted},
+			expectedResult:    Result{Added: 1, ChangedDays: []string{testDayKey}},
+			expectedTotal:     2 * time.Hour,
+		},
+		{
+			name:              "ThreeWayModifiedOnBothSides",
+			local:             map[uuid.UUID]models.AeonUnit{sharedID: newUnit(8, 10, "local")},
+			other:             map[uuid.UUID]models.AeonUnit{sharedID: newUnit(8, 11, "other")},
+			base:              map[uuid.UUID]models.AeonUnit{sharedID: shared},
+			strategy:          StrategyOther,
+			expectedUnits:     map[uuid.UUID]string{sharedID: "other"},
+			expectedConflicts: []string{ConflictModified},
+			expectedResult:    Result{Updated: 1, ChangedDays: []string{testDayKey}},
+			expectedTotal:     3 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local, other := newVault(tt.local), newVault(tt.other)
+			var base *models.AeonVault
+			if tt.base != nil {
+				base = newVault(tt.base)
+			}
+			resolve, err := StrategyResolver(tt.strategy)
+			require.NoError(t, err)
+
+			result, err := Merge(local, other, base, resolve, workingHoursConfig)
+			require.NoError(t, err)
+
+			var conflicts []string
+			for _, conflict := range result.Conflicts {
+				conflicts = append(conflicts, conflict.Kind)
+			}
+			assert.Equal(t, tt.expectedConflicts, conflicts)
+			result.Conflicts = nil
+			assert.Equal(t, tt.expectedResult, result)
+			day := local.Days[testDayKey]
+			for unitID, comment := range tt.expectedUnits {
+				assert.Equal(t, comment, day.Units[unitID].Comment, unitID.String())
+			}
+			assert.Len(t, day.Units, len(tt.expectedUnits)+tt.expectedCopies)
+			if len(result.ChangedDays) > 0 {
+				assert.Equal(t, tt.expectedTotal, day.TotalHours.Duration)
+			}
+		})
+	}
+}
+
+func TestMergeDays(t *testing.T) {
+	workingHoursConfig := configuration.GetDefaultWorkingHoursConfig()
+	running := newUnit(9, 10, "running")
+	running.Stop, running.Duration = nil, nil
+	local := newVault(map[uuid.UUID]models.AeonUnit{sharedID: running})
+	local.CurrentRunningUnit = &models.AeonCurrentRunningUnit{DayKey: testDayKey, UnitID: sharedID}
+	base := newVault(map[uuid.UUID]models.AeonUnit{sharedID: running})
+	other := newVault(map[uuid.UUID]models.AeonUnit{sharedID: newUnit(9, 12, "running")})
+	other.Days[testDayKey].VacationDay = true
+	otherDay := repositories.NewAoenDay(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC))
+	start, stop := time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC), time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC)
+	otherDay.Units[otherID] = models.AeonUnit{Start: &start, Stop: &stop, Duration: &models.AeonDuration{Duration: time.Hour}, Type: repositories.WorkType}
+	other.Days["2024-01-03"] = otherDay
+	other.InitializedYears = []int{2024}
+
+	resolve, err := StrategyResolver(StrategyLocal)
+	require.NoError(t, err)
+	result, err := Merge(local, other, base, resolve, workingHoursConfig)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{testDayKey, "2024-01-03"}, result.ChangedDays)
+	assert.Nil(t, local.CurrentRunningUnit, "the unit has been stopped on the other side")
+	assert.True(t, local.Days[testDayKey].VacationDay)
+	assert.Equal(t, 3*time.Hour, local.Days[testDayKey].OvertimeHours.Duration)
+	assert.Contains(t, local.Days["2024-01-03"].Units, otherID)
+	assert.Equal(t, []int{2024}, local.InitializedYears)
+}
+
+func TestMergeResolverError(t *testing.T) {
+	local := newVault(map[uuid.UUID]models.AeonUnit{localID: newUnit(9, 11, "local")})
+	other := newVault(map[uuid.UUID]models.AeonUnit{otherID: newUnit(10, 12, "other")})
+	abort := errors.New("aborted")
+
+	_, err := Merge(local, other, nil, func(Conflict) (Resolution, error) { return KeepLocal, abort }, configuration.GetDefaultWorkingHoursConfig())
+
+	assert.ErrorIs(t, err, abort)
+	assert.NotContains(t, local.Days[testDayKey].Units, otherID, "the local data is unchanged")
+}
+
+func TestStrategyResolver(t *testing.T) {
+	_, err := StrategyResolver("newest")
+	assert.Error(t, err)
+}
@@ -1,8 +1,8 @@
 package models
 
 type WorkTimeRequest struct {
-    Date    string `json:"date"`
-    Start   string `json:"start"`
-    Stop    string `json:"stop"`
-    Comment string `json:"comment"`
-}
\ No newline at end of file
+	Date    string `json:"date" validate:"required,datetime=2006-01-02"`
+	Start   string `json:"start" validate:"required,datetime=2006-01-02T15:04:05Z07:00"`
+	Stop    string `json:"stop" validate:"required,datetime=2006-01-02T15:04:05Z07:00"`
+	Comment string `json:"comment"`
+}
@@ -31,13 +31,18 @@ type (
 	}
 	// AeonVault represents all tracking data
 	AeonVault struct {
+		SchemaVersion      int                     `json:"schema_version"` // SchemaVersion is the version of the data format, older data is migrated on load
 		Days               map[string]*AeonDay     `json:"aeon_days" validate:"required"`
 		CurrentRunningUnit *AeonCurrentRunningUnit `json:"current_running_unit,omitempty"`
-		CommandComment     string                  `json:"-"` // CommandComment is used to store the comment for the current command
+		InitializedYears   []int                   `json:"initialized_years,omitempty"` // InitializedYears lists the years whose days and public holidays have been created
+		Settings           map[string]string       `json:"settings,omitempty"`          // Settings stores key-value settings kept together with the tracking data
+		CommandComment     string                  `json:"-"`                           // CommandComment is used to store the comment for the current command
+		CommandOperation   string                  `json:"-"`                           // CommandOperation is the operation changing the data, recorded with the events of the event log
 	}
+	// AeonCurrentRunningUnit references the unit of work which is currently running
 	AeonCurrentRunningUnit struct {
-		DayKey string
-		UnitID uuid.UUID
+		DayKey string    `json:"day_key"`
+		UnitID uuid.UUID `json:"unit_id"`
 	}
 	ReportItem struct {
 		TotalHours time.Duration
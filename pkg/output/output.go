@@ -0,0 +1,241 @@
+// Package output writes the results of commands as text for people, or as JSON, YAML or CSV for scripts.
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a format of the output of commands.
+type Format string
+
+// Formats of the output, Text is the default
+const (
+	Text Format = "text"
+	JSON Format = "json"
+	YAML Format = "yaml"
+	CSV  Format = "csv"
+)
+
+// Formats are all formats of the output
+var Formats = []Format{Text, JSON, YAML, CSV}
+
+type (
+	// TextRenderer is implemented by results which render themselves as text for people.
+	TextRenderer interface {
+		RenderText(w io.Writer) error
+	}
+	// TableRenderer is implemented by results which are a table, they are written as CSV with the header and rows of the table.
+	// Other results are written as a table of their JSON fields.
+	TableRenderer interface {
+		RenderTable() (header []string, rows [][]string)
+	}
+	// Message is the result of a command which only reports what it did.
+	Message struct {
+		Message string `json:"message"`
+	}
+)
+
+// Messagef returns a message formatted like fmt.Sprintf.
+func Messagef(format string, a ...any) Message {
+	return Message{Message: fmt.Sprintf(format, a...)}
+}
+
+// RenderText writes the message as a line.
+func (m Message) RenderText(w io.Writer) error {
+	_, err := fmt.Fprintln(w, m.Message)
+	return err
+}
+
+// String returns the name of the format.
+func (f Format) String() string {
+	return string(f)
+}
+
+// Set sets the format by its name, so that a Format is a command line flag.
+func (f *Format) Set(value string) error {
+	for _, format := range Formats {
+		if strings.EqualFold(value, string(format)) {
+			*f = format
+			return nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = string(format)
+	}
+	return fmt.Errorf("unknown output format %s, use %s", value, strings.Join(names, ", "))
+}
+
+// Type returns the type name of the flag in the usage.
+func (f Format) Type() string {
+	return "format"
+}
+
+// Write writes a result in the format. JSON, YAML and CSV are derived from the JSON encoding of the result,
+// text requires a TextRenderer or a fmt.Stringer.
+func Write(w io.Writer, format Format, result any) error {
+	switch format {
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(result)
+	case YAML:
+		node, err := jsonNode(result)
+		if err != nil {
+			return err
+		}
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err = encoder.Encode(node); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case CSV:
+		return writeCSV(w, result)
+	case Text, "":
+		switch r := result.(type) {
+		case TextRenderer:
+			return r.RenderText(w)
+		case fmt.Stringer:
+			_, err := fmt.Fprintln(w, r)
+			return err
+		}
+		return fmt.Errorf("%T cannot be written as text", result)
+	}
+	return fmt.Errorf("unknown output format %s", format)
+}
+
+// jsonNode returns the YAML node of the JSON encoding of a result, so that YAML and CSV use the names and
+// order of the JSON fields. The styles of JSON are removed, so that the node is written in block style.
+func jsonNode(result any) (*yaml.Node, error) {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var document yaml.Node
+	if err = yaml.Unmarshal(encoded, &document); err != nil {
+		return nil, err
+	}
+	node := document.Content[0]
+	resetStyle(node)
+	return node, nil
+}
+
+// resetStyle removes the flow and quoting styles of a node and its content. Strings are quoted like yaml.v3 quotes
+// Go strings, which includes strings like 08:30 that YAML 1.1 would read as numbers.
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	var str yaml.Node
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" && str.Encode(node.Value) == nil {
+		node.Style = str.Style
+	}
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// writeCSV writes a result as CSV. A list is a row per element, any other result a single row,
+// nested values are written as JSON.
+func writeCSV(w io.Writer, result any) error {
+	var header []string
+	var rows [][]string
+	if table, ok := result.(TableRenderer); ok {
+		header, rows = table.RenderTable()
+	} else {
+		node, err := jsonNode(result)
+		if err != nil {
+			return err
+		}
+		items := []*yaml.Node{node}
+		if node.Kind == yaml.SequenceNode {
+			items = node.Content
+		}
+		if header, rows, err = nodeTable(items); err != nil {
+			return err
+		}
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// nodeTable returns the table of the items, the columns are the keys of the mappings in the order they appear
+func nodeTable(items []*yaml.Node) ([]string, [][]string, error) {
+	var header []string
+	columns := map[string]int{}
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		if item.Kind != yaml.MappingNode {
+			cell, err := nodeCell(item)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, ok := columns["value"]; !ok {
+				columns["value"] = len(header)
+				header = append(header, "value")
+			}
+			row := make([]string, len(header))
+			row[columns["value"]] = cell
+			rows = append(rows, row)
+			continue
+		}
+		row := make([]string, len(header))
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			key := item.Content[i].Value
+			column, ok := columns[key]
+			if !ok {
+				column = len(header)
+				columns[key] = column
+				header = append(header, key)
+				row = append(row, "")
+			}
+			cell, err := nodeCell(item.Content[i+1])
+			if err != nil {
+				return nil, nil, err
+			}
+			row[column] = cell
+		}
+		rows = append(rows, row)
+	}
+	// Rows written before a column appeared are shorter than the header
+	for i := range rows {
+		for len(rows[i]) < len(header) {
+			rows[i] = append(rows[i], "")
+		}
+	}
+	return header, rows, nil
+}
+
+// nodeCell returns the value of a scalar node, or the JSON of a nested value
+func nodeCell(node *yaml.Node) (string, error) {
+	switch {
+	case node.Kind == yaml.ScalarNode && node.Tag == "!!null":
+		return "", nil
+	case node.Kind == yaml.ScalarNode:
+		return node.Value, nil
+	}
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return "", err
+	}
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(encoded.String(), "\n"), nil
+}
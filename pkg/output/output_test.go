@@ -0,0 +1,121 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUnit struct {
+	Start    string   `json:"start"`
+	Comment  string   `json:"comment,omitempty"`
+	Projects []string `json:"projects,omitempty"`
+	Running  bool     `json:"running"`
+}
+
+type testReport struct {
+	Units []testUnit `json:"units"`
+	Total string     `json:"total"`
+}
+
+func (r testReport) RenderText(w io.Writer) error {
+	_, err := io.WriteString(w, "Total: "+r.Total+"\n")
+	return err
+}
+
+type testTable struct{}
+
+func (testTable) RenderTable() ([]string, [][]string) {
+	return []string{"a", "b"}, [][]string{{"1", "2"}}
+}
+
+func TestWrite(t *testing.T) {
+	report := testReport{Units: []testUnit{{Start: "08:30", Comment: "+aeontrac review", Projects: []string{"aeontrac"}}}, Total: "1h"}
+	tests := []struct {
+		name          string
+		format        Format
+		result        any
+		expected      string
+		expectedError bool
+	}{
+		{name: "Text", format: Text, result: report, expected: "Total: 1h\n"},
+		{name: "TextMessage", format: Text, result: Messagef("Lock %s.", "removed"), expected: "Lock removed.\n"},
+		{name: "TextWithoutRenderer", format: Text, result: testUnit{}, expectedError: true},
+		{
+			name:     "JSON",
+			format:   JSON,
+			result:   Messagef("a <b> & c"),
+			expected: "{\n  \"message\": \"a <b> & c\"\n}\n",
+		},
+		{
+			name:   "YAMLInOrderOfJSONFields",
+			format: YAML,
+			result: report,
+			expected: "units:\n" +
+				"  - start: \"08:30\"\n" +
+				"    comment: +aeontrac review\n" +
+				"    projects:\n" +
+				"      - aeontrac\n" +
+				"    running: false\n" +
+				"total: 1h\n",
+		},
+		{
+			name:     "CSVOfList",
+			format:   CSV,
+			result:   []testUnit{{Start: "08:30", Running: true}, {Start: "13:00", Comment: "a, b", Projects: []string{"x", "y"}}},
+			expected: "start,running,comment,projects\n08:30,true,,\n13:00,false,\"a, b\",\"[\"\"x\"\",\"\"y\"\"]\"\n",
+		},
+		{
+			name:     "CSVOfSingleResult",
+			format:   CSV,
+			result:   Messagef("Lock removed."),
+			expected: "message\nLock removed.\n",
+		},
+		{
+			name:     "CSVOfTable",
+			format:   CSV,
+			result:   testTable{},
+			expected: "a,b\n1,2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var written bytes.Buffer
+
+			err := Write(&written, tt.format, tt.result)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, written.String())
+		})
+	}
+}
+
+func TestFormatSet(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		expected      Format
+		expectedError bool
+	}{
+		{name: "JSON", value: "json", expected: JSON},
+		{name: "IgnoresCase", value: "YAML", expected: YAML},
+		{name: "Unknown", value: "xml", expected: Text, expectedError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format := Text
+
+			err := format.Set(tt.value)
+
+			assert.Equal(t, tt.expectedError, err != nil)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// Package profile bundles the configuration and all time tracking data into a portable archive, to move them to another machine.
+package profile
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatVersion is the version of the archive format written by Export
+	FormatVersion = 1
+	// ManifestName is the name of the manifest in the archive
+	ManifestName = "manifest.json"
+	// ConfigFolder is the folder of the archive holding the files of the configuration folder
+	ConfigFolder = "config"
+	// DataFolder is the folder of the archive holding the files of the data folder
+	DataFolder = "data"
+)
+
+type (
+	// Manifest describes the files of a profile archive, so they can be verified before they are imported
+	Manifest struct {
+		FormatVersion int            `json:"format_version"`
+		Created       time.Time      `json:"created"`
+		Files         []ManifestFile `json:"files"`
+	}
+	// ManifestFile describes a single file of a profile archive, its path uses slashes and starts with the folder of the archive
+	ManifestFile struct {
+		Path   string `json:"path"`
+		Size   int64  `json:"size"`
+		SHA256 string `json:"sha256"`
+	}
+	// archiveFile is a file read from one of the folders, to be written to the archive
+	archiveFile struct {
+		path string
+		mode fs.FileMode
+		data []byte
+	}
+)
+
+// Export writes all files of the configuration and data folders to w as gzip compressed tar archive,
+// preceded by a manifest with their checksums. Files for which skip returns true are left out,
+// skip gets their path relative to the configuration or data folder.
+func Export(w io.Writer, configFolder, dataFolder string, skip func(relativePath string) bool) (Manifest, error) {
+	manifest := Manifest{FormatVersion: FormatVersion, Created: time.Now()}
+	configFiles, err := readFolder(configFolder, ConfigFolder, skip)
+	if err != nil {
+		return manifest, fmt.Errorf("error reading configuration folder: %w", err)
+	}
+	dataFiles, err := readFolder(dataFolder, DataFolder, skip)
+	if err != nil {
+		return manifest, fmt.Errorf("error reading data folder: %w", err)
+	}
+	files := append(configFiles, dataFiles...)
+	for _, file := range files {
+		checksum := sha256.Sum256(file.data)
+		manifest.Files = append(manifest.Files, ManifestFile{Path: file.path, Size: int64(len(file.data)), SHA256: hex.EncodeToString(checksum[:])})
+	}
+	encodedManifest, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return manifest, err
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, file := range append([]archiveFile{{path: ManifestName, mode: 0644, data: encodedManifest}}, files...) {
+		header := &tar.Header{Name: file.path, Mode: int64(file.mode.Perm()), Size: int64(len(file.data)), ModTime: manifest.Created, Typeflag: tar.TypeReg}
+		if err = tarWriter.WriteHeader(header); err != nil {
+			return manifest, err
+		}
+		if _, err = tarWriter.Write(file.data); err != nil {
+			return manifest, err
+		}
+	}
+	if err = tarWriter.Close(); err != nil {
+		return manifest, err
+	}
+	return manifest, gzipWriter.Close()
+}
+
+// Extract extracts a profile archive into the provided folder, which then holds the config and data folders of the archive.
+// Every file is verified against the manifest, an archive with missing, additional or modified files is rejected.
+func Extract(r io.Reader, folder string) (Manifest, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("the archive is not gzip compressed: %w", err)
+	}
+	defer func(gzipReader *gzip.Reader) {
+		_ = gzipReader.Close()
+	}(gzipReader)
+
+	var manifest *Manifest
+	checksums := map[string]ManifestFile{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Manifest{}, fmt.Errorf("error reading archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			return Manifest{}, fmt.Errorf("the archive entry %s is not a regular file", header.Name)
+		}
+		name, err := cleanEntryName(header.Name)
+		if err != nil {
+			return Manifest{}, err
+		}
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("error reading %s from archive: %w", name, err)
+		}
+		if name == ManifestName {
+			manifest = &Manifest{}
+			if err = json.Unmarshal(data, manifest); err != nil {
+				return Manifest{}, fmt.Errorf("error decoding manifest: %w", err)
+			}
+			continue
+		}
+		checksum := sha256.Sum256(data)
+		checksums[name] = ManifestFile{Path: name, Size: int64(len(data)), SHA256: hex.EncodeToString(checksum[:])}
+		target := filepath.Join(folder, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return Manifest{}, err
+		}
+		if err = os.WriteFile(target, data, fs.FileMode(header.Mode).Perm()); err != nil {
+			return Manifest{}, err
+		}
+	}
+
+	if manifest == nil {
+		return Manifest{}, errors.New("the archive has no manifest")
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return Manifest{}, fmt.Errorf("unsupported archive format version %d, supported is %d", manifest.FormatVersion, FormatVersion)
+	}
+	for _, expected := range manifest.Files {
+		actual, ok := checksums[expected.Path]
+		if !ok {
+			return Manifest{}, fmt.Errorf("the file %s of the manifest is missing in the archive", expected.Path)
+		}
+		if actual != expected {
+			return Manifest{}, fmt.Errorf("the checksum of %s does not match the manifest", expected.Path)
+		}
+		delete(checksums, expected.Path)
+	}
+	if len(checksums) > 0 {
+		return Manifest{}, fmt.Errorf("the file %s of the archive is not listed in the manifest", slices.Sorted(maps.Keys(checksums))[0])
+	}
+	return *manifest, nil
+}
+
+// readFolder reads all regular files of the folder, their paths are prefixed with the provided archive folder
+func readFolder(folder, archiveFolder string, skip func(relativePath string) bool) ([]archiveFile, error) {
+	var files []archiveFile
+	err := filepath.WalkDir(folder, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(folder, filePath)
+		if err != nil {
+			return err
+		}
+		if skip != nil && skip(relativePath) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		files = append(files, archiveFile{path: path.Join(archiveFolder, filepath.ToSlash(relativePath)), mode: info.Mode(), data: data})
+		return nil
+	})
+	slices.SortFunc(files, func(a, b archiveFile) int {
+		return strings.Compare(a.path, b.path)
+	})
+	return files, err
+}
+
+// cleanEntryName returns the cleaned name of an archive entry, rejecting names outside of the config and data folders
+func cleanEntryName(name string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(name, "./"))
+	if cleaned == ManifestName {
+		return cleaned, nil
+	}
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") ||
+		!(strings.HasPrefix(cleaned, ConfigFolder+"/") || strings.HasPrefix(cleaned, DataFolder+"/")) {
+		return "", fmt.Errorf("the archive entry %s is outside of the %s and %s folders", name, ConfigFolder, DataFolder)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,153 @@
+package profile
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFolders returns configuration and data folders with a few files
+func newFolders(t *testing.T) (string, string) {
+	configFolder, dataFolder := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(configFolder, "config.json"), []byte(`{"storage":{}}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dataFolder, "aeon_vault.json"), []byte(`{"days":{}}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dataFolder, "aeon_vault.lock"), []byte(`{}`), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dataFolder, "backups"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dataFolder, "backups", "1.json"), []byte(`{}`), 0600))
+	return configFolder, dataFolder
+}
+
+// writeArchive writes the entries in order as gzip compressed tar archive
+func writeArchive(t *testing.T, entries map[string]string, order []string) []byte {
+	var archive bytes.Buffer
+	gzipWriter := gzip.NewWriter(&archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, name := range order {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(entries[name])), Typeflag: tar.TypeReg}))
+		_, err := tarWriter.Write([]byte(entries[name]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+	return archive.Bytes()
+}
+
+// readArchive returns the entries of an archive and their order
+func readArchive(t *testing.T, archive []byte) (map[string]string, []string) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	entries := map[string]string{}
+	var order []string
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			break
+		}
+		var content bytes.Buffer
+		_, err = content.ReadFrom(tarReader)
+		require.NoError(t, err)
+		entries[header.Name] = content.String()
+		order = append(order, header.Name)
+	}
+	return entries, order
+}
+
+func TestExportAndExtract(t *testing.T) {
+	configFolder, dataFolder := newFolders(t)
+	var archive bytes.Buffer
+	manifest, err := Export(&archive, configFolder, dataFolder, func(relativePath string) bool {
+		return relativePath == "aeon_vault.lock"
+	})
+	require.NoError(t, err)
+
+	var paths []string
+	for _, file := range manifest.Files {
+		paths = append(paths, file.Path)
+	}
+	assert.Equal(t, []string{"config/config.json", "data/aeon_vault.json", "data/backups/1.json"}, paths)
+
+	folder := t.TempDir()
+	extracted, err := Extract(&archive, folder)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Files, extracted.Files)
+	content, err := os.ReadFile(filepath.Join(folder, "data", "backups", "1.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{}`, string(content))
+	info, err := os.Stat(filepath.Join(folder, "data", "backups", "1.json"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	assert.NoFileExists(t, filepath.Join(folder, "data", "aeon_vault.lock"))
+}
+
+func TestExtractInvalidArchive(t *testing.T) {
+	configFolder, dataFolder := newFolders(t)
+	var exported bytes.Buffer
+	_, err := Export(&exported, configFolder, dataFolder, nil)
+	require.NoError(t, err)
+	valid, order := readArchive(t, exported.Bytes())
+
+	tests := []struct {
+		name          string
+		modify        func(entries map[string]string, order []string) []string
+		expectedError string
+	}{
+		{
+			name: "ModifiedFile",
+			modify: func(entries map[string]string, order []string) []string {
+				entries["data/aeon_vault.json"] = `{"days":{"2024-01-02":{}}}`
+				return order
+			},
+			expectedError: "checksum of data/aeon_vault.json",
+		},
+		{
+			name: "MissingFile",
+			modify: func(entries map[string]string, order []string) []string {
+				return order[:len(order)-1]
+			},
+			expectedError: "missing in the archive",
+		},
+		{
+			name: "AdditionalFile",
+			modify: func(entries map[string]string, order []string) []string {
+				entries["data/extra.json"] = `{}`
+				return append(order, "data/extra.json")
+			},
+			expectedError: "data/extra.json of the archive is not listed",
+		},
+		{
+			name: "PathOutsideOfFolders",
+			modify: func(entries map[string]string, order []string) []string {
+				entries["data/../../evil"] = `{}`
+				return append(order, "data/../../evil")
+			},
+			expectedError: "outside of the config and data folders",
+		},
+		{
+			name: "MissingManifest",
+			modify: func(entries map[string]string, order []string) []string {
+				return order[1:]
+			},
+			expectedError: "no manifest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := map[string]string{}
+			for name, content := range valid {
+				entries[name] = content
+			}
+			archive := writeArchive(t, entries, tt.modify(entries, append([]string(nil), order...)))
+
+			_, err := Extract(bytes.NewReader(archive), t.TempDir())
+
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package reporting
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+)
+
+// CombinedTotal is the name of the line with the total hours of all profiles in the combined report
+const CombinedTotal = "Total"
+
+// ProfileHours are the hours worked in a profile today, in the current ISO week and in the current month
+type ProfileHours struct {
+	Profile string
+	Day     time.Duration
+	Week    time.Duration
+	Month   time.Duration
+}
+
+// WorkedHours returns the hours of the work units of today, the current ISO week and the current month,
+// including the running unit until now. Compensatory units are time off and not counted.
+func WorkedHours(profile string, a *models.AeonVault, now time.Time) ProfileHours {
+	hours := ProfileHours{Profile: profile}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	weekStart := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	from := weekStart
+	if monthStart.Before(from) {
+		from = monthStart
+	}
+	for date := from; !date.After(today); date = date.AddDate(0, 0, 1) {
+		day, ok := a.Days[date.Format(time.DateOnly)]
+		if !ok {
+			continue
+		}
+		worked := workedDuration(day, now)
+		if date.Equal(today) {
+			hours.Day += worked
+		}
+		if !date.Before(weekStart) {
+			hours.Week += worked
+		}
+		if !date.Before(monthStart) {
+			hours.Month += worked
+		}
+	}
+	return hours
+}
+
+// CombineHours returns the total hours of all profiles
+func CombineHours(profiles []ProfileHours) ProfileHours {
+	total := ProfileHours{Profile: CombinedTotal}
+	for _, hours := range profiles {
+		total.Day += hours.Day
+		total.Week += hours.Week
+		total.Month += hours.Month
+	}
+	return total
+}
+
+// CombinedReport lists the worked hours of every profile and their total, the total is the last line.
+type CombinedReport struct {
+	Profiles []CombinedReportLine `json:"profiles"`
+	// Warnings report that the total exceeds the maximum working time per day or week
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// CombinedReportLine are the hours worked in a profile today, in the current ISO week and in the current month.
+type CombinedReportLine struct {
+	Profile string `json:"profile"`
+	Today   string `json:"today"`
+	Week    string `json:"week"`
+	Month   string `json:"month"`
+}
+
+// NewCombinedReport returns the report of the worked hours of every profile and their total,
+// with warnings if the total exceeds the maximum working time per day or week. A maximum of 0 is not checked.
+func NewCombinedReport(profiles []ProfileHours, maxDay, maxWeek time.Duration) CombinedReport {
+	var report CombinedReport
+	total := CombineHours(profiles)
+	for _, hours := range append(profiles, total) {
+		report.Profiles = append(report.Profiles, CombinedReportLine{
+			Profile: hours.Profile,
+			Today:   formatDuration(hours.Day),
+			Week:    formatDuration(hours.Week),
+			Month:   formatDuration(hours.Month),
+		})
+	}
+	if maxDay > 0 && total.Day > maxDay {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s worked today exceed the maximum of %s per day.", formatDuration(total.Day), formatDuration(maxDay)))
+	}
+	if maxWeek > 0 && total.Week > maxWeek {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s worked this week exceed the maximum of %s per week.", formatDuration(total.Week), formatDuration(maxWeek)))
+	}
+	return report
+}
+
+// RenderText writes the hours of the profiles as table, followed by the warnings.
+func (r CombinedReport) RenderText(w io.Writer) error {
+	var text strings.Builder
+	text.WriteString("Profile\t\tToday\t\tWeek\t\tMonth\n")
+	for _, line := range r.Profiles {
+		fmt.Fprintf(&text, "%-15s\t%s\t%s\t%s\n", line.Profile, line.Today, line.Week, line.Month)
+	}
+	for _, warning := range r.Warnings {
+		fmt.Fprintf(&text, "\nWarning: %s\n", warning)
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// RenderTable returns the hours of the profiles as table.
+func (r CombinedReport) RenderTable() ([]string, [][]string) {
+	rows := make([][]string, len(r.Profiles))
+	for i, line := range r.Profiles {
+		rows[i] = []string{line.Profile, line.Today, line.Week, line.Month}
+	}
+	return []string{"profile", "today", "week", "month"}, rows
+}
+
+// workedDuration returns the duration of the work units of a day, running units count until now
+func workedDuration(day *models.AeonDay, now time.Time) time.Duration {
+	var worked time.Duration
+	for _, unit := range day.Units {
+		if unit.Type != repositories.WorkType {
+			continue
+		}
+		switch {
+		case unit.Duration != nil:
+			worked += unit.Duration.Duration
+		case unit.Start != nil && unit.Stop == nil && unit.Start.Before(now):
+			worked += now.Sub(*unit.Start)
+		}
+	}
+	return worked
+}
@@ -0,0 +1,106 @@
+package reporting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+// newVault returns a vault with a unit per day key between the provided hours, a stop hour of 0 is a running unit
+func newVault(units map[string][3]int, unitType string) *models.AeonVault {
+	a := &models.AeonVault{Days: map[string]*models.AeonDay{}}
+	for dayKey, hours := range units {
+		date, _ := time.Parse(time.DateOnly, dayKey)
+		day := repositories.NewAoenDay(date)
+		for i := 0; i < hours[2]; i++ {
+			start := date.Add(time.Duration(hours[0]+i*(hours[1]-hours[0])) * time.Hour)
+			unit := models.AeonUnit{Start: &start, Type: unitType}
+			if hours[1] > 0 {
+				stop := start.Add(time.Duration(hours[1]-hours[0]) * time.Hour)
+				unit.Stop, unit.Duration = &stop, &models.AeonDuration{Duration: stop.Sub(start)}
+			}
+			day.Units[uuid.New()] = unit
+		}
+		a.Days[dayKey] = day
+	}
+	return a
+}
+
+func TestWorkedHours(t *testing.T) {
+	// Wednesday, the week started in the previous month
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		vault    *models.AeonVault
+		expected ProfileHours
+	}{
+		{
+			name:     "DayWeekAndMonth",
+			vault:    newVault(map[string][3]int{"2024-05-01": {8, 10, 1}, "2024-04-29": {8, 11, 1}, "2024-04-28": {8, 12, 1}}, repositories.WorkType),
+			expected: ProfileHours{Profile: "work", Day: 2 * time.Hour, Week: 5 * time.Hour, Month: 2 * time.Hour},
+		},
+		{
+			name:     "RunningUnitCountsUntilNow",
+			vault:    newVault(map[string][3]int{"2024-05-01": {9, 0, 1}}, repositories.WorkType),
+			expected: ProfileHours{Profile: "work", Day: 3 * time.Hour, Week: 3 * time.Hour, Month: 3 * time.Hour},
+		},
+		{
+			name:     "CompensatoryUnitsAreNotCounted",
+			vault:    newVault(map[string][3]int{"2024-05-01": {8, 10, 1}}, repositories.CompensatoryType),
+			expected: ProfileHours{Profile: "work"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, WorkedHours("work", tt.vault, now))
+		})
+	}
+}
+
+func TestCombineHours(t *testing.T) {
+	total := CombineHours([]ProfileHours{
+		{Profile: "work", Day: 6 * time.Hour, Week: 30 * time.Hour, Month: 100 * time.Hour},
+		{Profile: "side", Day: 5 * time.Hour, Week: 20 * time.Hour, Month: 40 * time.Hour},
+	})
+
+	assert.Equal(t, ProfileHours{Profile: CombinedTotal, Day: 11 * time.Hour, Week: 50 * time.Hour, Month: 140 * time.Hour}, total)
+}
+
+func TestNewCombinedReport(t *testing.T) {
+	profiles := []ProfileHours{
+		{Profile: "work", Day: 6 * time.Hour, Week: 30 * time.Hour, Month: 100 * time.Hour},
+		{Profile: "side", Day: 5 * time.Hour, Week: 20 * time.Hour, Month: 40 * time.Hour},
+	}
+	tests := []struct {
+		name             string
+		maxDay, maxWeek  time.Duration
+		expectedWarnings []string
+	}{
+		{name: "WithinMaximum", maxDay: 12 * time.Hour, maxWeek: 60 * time.Hour},
+		{name: "MaximumNotChecked"},
+		{
+			name:   "DayAndWeekExceeded",
+			maxDay: 10 * time.Hour, maxWeek: 48 * time.Hour,
+			expectedWarnings: []string{
+				"11:00:00 worked today exceed the maximum of 10:00:00 per day.",
+				"50:00:00 worked this week exceed the maximum of 48:00:00 per week.",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewCombinedReport(profiles, tt.maxDay, tt.maxWeek)
+
+			assert.Equal(t, []CombinedReportLine{
+				{Profile: "work", Today: "06:00:00", Week: "30:00:00", Month: "100:00:00"},
+				{Profile: "side", Today: "05:00:00", Week: "20:00:00", Month: "40:00:00"},
+				{Profile: CombinedTotal, Today: "11:00:00", Week: "50:00:00", Month: "140:00:00"},
+			}, report.Profiles)
+			assert.Equal(t, tt.expectedWarnings, report.Warnings)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package reporting
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jame-developer/aeontrac/pkg/commands"
+)
+
+// UnitConfirmation is the result of start, stop and add: what the command did, the unit of work and,
+// after start and stop, the report of today.
+type UnitConfirmation struct {
+	Message string `json:"message"`
+	commands.UnitResult
+	Today *TodayReport `json:"today,omitempty"`
+}
+
+// RenderText writes the message, followed by the report of today.
+func (c UnitConfirmation) RenderText(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, c.Message); err != nil {
+		return err
+	}
+	if c.Today == nil {
+		return nil
+	}
+	return c.Today.RenderText(w)
+}
@@ -0,0 +1,93 @@
+package reporting
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jame-developer/aeontrac/pkg/commands"
+)
+
+// UnitLog is a list of units of work, as listed by log.
+type UnitLog []commands.UnitEntry
+
+// RenderText writes the units of work with their short IDs, times, durations and comments, one line per unit.
+func (l UnitLog) RenderText(w io.Writer) error {
+	if len(l) == 0 {
+		_, err := fmt.Fprintln(w, "No units of work.")
+		return err
+	}
+	var text strings.Builder
+	for _, entry := range l {
+		fmt.Fprintf(&text, "%s  %s  %s\t%s\t%s\t%s\n", entry.ShortID, entry.DayKey, unitTimes(entry), unitDuration(entry), entry.Type, entry.Comment)
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// RenderTable returns the units of work as table, the projects are separated by spaces.
+func (l UnitLog) RenderTable() ([]string, [][]string) {
+	rows := make([][]string, len(l))
+	for i, entry := range l {
+		stop, duration := "", ""
+		if entry.Stop != nil {
+			stop = entry.Stop.Format(time.RFC3339)
+		}
+		if entry.Duration != nil {
+			duration = entry.Duration.String()
+		}
+		rows[i] = []string{entry.ID.String(), entry.ShortID, entry.DayKey, entry.Start.Format(time.RFC3339), stop, duration,
+			entry.Type, entry.Comment, strings.Join(entry.Projects, " "), strconv.FormatBool(entry.Running)}
+	}
+	return []string{"id", "short_id", "day", "start", "stop", "duration", "type", "comment", "projects", "running"}, rows
+}
+
+// UnitDetail is a single unit of work, as shown by show.
+type UnitDetail struct {
+	commands.UnitEntry
+	// AsOf is the time the data is shown as of, if it was rebuilt from the event log
+	AsOf *time.Time `json:"as_of,omitempty"`
+}
+
+// RenderText writes the unit of work with its full ID.
+func (d UnitDetail) RenderText(w io.Writer) error {
+	entry := d.UnitEntry
+	var text strings.Builder
+	writeAsOf(&text, d.AsOf)
+	fmt.Fprintf(&text, "ID:\t\t%s\n", entry.ID)
+	fmt.Fprintf(&text, "Day:\t\t%s\n", entry.DayKey)
+	fmt.Fprintf(&text, "Time:\t\t%s\n", unitTimes(entry))
+	fmt.Fprintf(&text, "Duration:\t%s\n", unitDuration(entry))
+	fmt.Fprintf(&text, "Type:\t\t%s\n", entry.Type)
+	if entry.Comment != "" {
+		fmt.Fprintf(&text, "Comment:\t%s\n", entry.Comment)
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// RenderTable returns the unit of work as table with a single row.
+func (d UnitDetail) RenderTable() ([]string, [][]string) {
+	return UnitLog{d.UnitEntry}.RenderTable()
+}
+
+// unitTimes formats the start and stop time of a unit, a running unit has no stop time
+func unitTimes(entry commands.UnitEntry) string {
+	if entry.Stop == nil {
+		return entry.Start.Format(time.TimeOnly) + " - ⏱      "
+	}
+	return entry.Start.Format(time.TimeOnly) + " - " + entry.Stop.Format(time.TimeOnly)
+}
+
+// unitDuration formats the duration of a unit, the duration of a running unit is the time since its start
+func unitDuration(entry commands.UnitEntry) string {
+	switch {
+	case entry.Duration != nil:
+		return formatDuration(entry.Duration.Duration)
+	case entry.Running:
+		return formatDuration(time.Since(entry.Start))
+	}
+	return ""
+}
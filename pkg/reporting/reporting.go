@@ -3,70 +3,74 @@ package reporting
 import (
 	"fmt"
 	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/commands"
 	"github.com/jame-developer/aeontrac/pkg/models"
+	"io"
 	"log"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
 const unitLineTmpl = "%s %s\t%s\t%s"
 
-func PrintTodayReport(workingHoursConfig configuration.WorkingHoursConfig, a *models.AeonVault) {
-	today := a.Days[time.Now().Format(time.DateOnly)]
-	var reportLines []string
-	unitLines := map[int]string{}
-	runningDuration := time.Second * 0
-	if len(today.Units) > 0 {
-		for _, unit := range today.Units {
-			if unit.Duration != nil {
-				unitLines[int(unit.Start.UnixMicro())] = fmt.Sprintf(unitLineTmpl, " ", unit.Start.Format(time.TimeOnly), unit.Stop.Format(time.TimeOnly), formatDuration(unit.Duration.Duration))
-			} else {
-				now := time.Now()
-				runningDuration = now.Sub(*unit.Start)
-				unitLines[int(unit.Start.UnixMicro())] = fmt.Sprintf(unitLineTmpl, "⏱", unit.Start.Format(time.TimeOnly), now.Format(time.TimeOnly), formatDuration(runningDuration))
-			}
-		}
-		keys := make([]int, 0, len(unitLines))
-		for k := range unitLines {
-			keys = append(keys, k)
-		}
-		sort.Ints(keys)
-		reportLines = append(reportLines, fmt.Sprintf(unitLineTmpl, " ", "Start\t", "End\t", "Duration"))
-		for _, key := range keys {
-			reportLines = append(reportLines, unitLines[key])
-		}
-		reportLines = append(reportLines, strings.Repeat("", 32))
+// RenderText writes the units of today, the total and overtime hours and the public holidays of the next days.
+func (r TodayReport) RenderText(w io.Writer) error {
+	if len(r.Units) == 0 {
+		_, err := fmt.Fprintln(w, "No time tracked today.")
+		return err
 	}
-	if today.TotalHours == nil {
-		fmt.Println("No time tracked today.")
-		return
-	}
-	total := today.TotalHours.Duration + runningDuration
-	reportLines = append(reportLines, fmt.Sprintf("TotalHours:\t%s", formatDuration(total)))
-	if today.OvertimeHours == nil {
-		return
+	reportLines := []string{fmt.Sprintf(unitLineTmpl, " ", "Start\t", "End\t", "Duration")}
+	for _, unit := range r.Units {
+		marker := " "
+		if unit.Running {
+			marker = "⏱"
+		}
+		reportLines = append(reportLines, fmt.Sprintf(unitLineTmpl, marker, unit.Start, unit.Stop, unit.Duration))
 	}
-	overtime := total - workingHoursConfig.WorkDay.Duration
-	reportLines = append(reportLines, fmt.Sprintf("Overtime:\t%s", formatDuration(overtime)))
-	holidayLinesForNextDays := getHolidayLinesForNextDays(7, a)
-	if len(holidayLinesForNextDays) > 0 {
+	reportLines = append(reportLines, "")
+	reportLines = append(reportLines, fmt.Sprintf("TotalHours:\t%s", r.TotalHours))
+	reportLines = append(reportLines, fmt.Sprintf("Overtime:\t%s", r.Overtime))
+	if len(r.Holidays) > 0 {
 		reportLines = append(reportLines, "")
-		reportLines = append(reportLines, holidayLinesForNextDays...)
+		reportLines = append(reportLines, r.Holidays...)
 	}
-	for _, line := range reportLines {
-		println(line)
+	_, err := fmt.Fprintln(w, strings.Join(reportLines, "\n"))
+	return err
+}
+
+// RenderTable returns the units of today as table.
+func (r TodayReport) RenderTable() ([]string, [][]string) {
+	rows := make([][]string, len(r.Units))
+	for i, unit := range r.Units {
+		rows[i] = []string{unit.Start, unit.Stop, unit.Duration, strconv.FormatBool(unit.Running)}
 	}
+	return []string{"start", "stop", "duration", "running"}, rows
+}
+
+// WeekHours are the total and overtime hours of an ISO week.
+type WeekHours struct {
+	Week          string `json:"week"`
+	TotalHours    string `json:"total_hours"`
+	OvertimeHours string `json:"overtime_hours"`
 }
 
-func PrintQuarterlyReport(workingHoursConfig configuration.WorkingHoursConfig, a *models.AeonVault) {
+// QuarterlyReport lists the hours of the ISO weeks of the current and the two previous months.
+type QuarterlyReport struct {
+	Weeks []WeekHours `json:"weeks"`
+}
+
+// GetQuarterlyReport returns the total and overtime hours per ISO week of the current and the two previous months.
+func GetQuarterlyReport(a *models.AeonVault) QuarterlyReport {
 	now := time.Now()
 	startOfCurrentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
 	startOfTwoMonthsBefore := startOfCurrentMonth.AddDate(0, -2, 0)
 
-	// Initialize maps to store total and overtime hours per week
-	weekHours := make(map[int]time.Duration)
-	weekOvertime := make(map[int]time.Duration)
+	// Initialize maps to store total and overtime hours per ISO week, weeks are keyed by their ISO year as well,
+	// so that a report spanning the turn of the year does not mix weeks of different years
+	weekHours := make(map[isoWeek]time.Duration)
+	weekOvertime := make(map[isoWeek]time.Duration)
 
 	// Parse the dates and aggregate hours per week
 	for dateStr, day := range a.Days {
@@ -82,25 +86,77 @@ func PrintQuarterlyReport(workingHoursConfig configuration.WorkingHoursConfig, a
 		if day.TotalHours == nil {
 			continue
 		}
-		// Aggregate hours by week number
-		weekHours[day.IsoWeekNumber] += day.TotalHours.Duration
-		weekOvertime[day.IsoWeekNumber] += day.OvertimeHours.Duration
+		// Aggregate hours by week
+		week := newIsoWeek(date)
+		weekHours[week] += day.TotalHours.Duration
+		if day.OvertimeHours != nil {
+			weekOvertime[week] += day.OvertimeHours.Duration
+		}
 	}
 
-	// Sort week numbers
-	weekNumbers := make([]int, 0, len(weekHours))
+	// Sort weeks
+	weeks := make([]isoWeek, 0, len(weekHours))
 	for week := range weekHours {
-		weekNumbers = append(weekNumbers, week)
+		weeks = append(weeks, week)
+	}
+	sort.Slice(weeks, func(i, j int) bool {
+		return weeks[i].before(weeks[j])
+	})
+	report := QuarterlyReport{Weeks: make([]WeekHours, 0, len(weeks))}
+	for _, week := range weeks {
+		report.Weeks = append(report.Weeks, WeekHours{
+			Week:          week.String(),
+			TotalHours:    formatDuration(weekHours[week]),
+			OvertimeHours: formatDuration(weekOvertime[week]),
+		})
 	}
-	sort.Ints(weekNumbers)
-	// Print total and overtime hours per week
-	fmt.Println("Week Number | Total Hours  | Overtime Hours")
-	fmt.Println("------------------------------------------")
-	for _, week := range weekNumbers {
-		total := weekHours[week]
-		overtime := weekOvertime[week]
-		fmt.Printf("Week %-6d | %12s | %12s\n", week, formatDuration(total), formatDuration(overtime))
+	return report
+}
+
+// RenderText writes the total and overtime hours per week as table.
+func (r QuarterlyReport) RenderText(w io.Writer) error {
+	var text strings.Builder
+	text.WriteString("Week Number | Total Hours  | Overtime Hours\n")
+	text.WriteString("------------------------------------------\n")
+	for _, week := range r.Weeks {
+		fmt.Fprintf(&text, "%-11s | %12s | %12s\n", week.Week, week.TotalHours, week.OvertimeHours)
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// RenderTable returns the weeks as table.
+func (r QuarterlyReport) RenderTable() ([]string, [][]string) {
+	rows := make([][]string, len(r.Weeks))
+	for i, week := range r.Weeks {
+		rows[i] = []string{week.Week, week.TotalHours, week.OvertimeHours}
+	}
+	return []string{"week", "total_hours", "overtime_hours"}, rows
+}
+
+// isoWeek identifies a week according to the ISO 8601 standard
+type isoWeek struct {
+	Year int
+	Week int
+}
+
+// newIsoWeek returns the ISO week of the provided date
+func newIsoWeek(date time.Time) isoWeek {
+	year, week := date.ISOWeek()
+	return isoWeek{Year: year, Week: week}
+}
+
+// before reports whether the week is before the other week
+func (w isoWeek) before(other isoWeek) bool {
+	if w.Year != other.Year {
+		return w.Year < other.Year
 	}
+	return w.Week < other.Week
+}
+
+// String returns the week in the format YYYY-Www
+func (w isoWeek) String() string {
+	return fmt.Sprintf("%d-W%02d", w.Year, w.Week)
 }
 
 type TodayReportUnit struct {
@@ -108,27 +164,43 @@ type TodayReportUnit struct {
 	Stop     string `json:"stop"`
 	Duration string `json:"duration"`
 	Running  bool   `json:"running"`
+	Comment  string `json:"comment,omitempty"`
 }
 
 type TodayReport struct {
-	Units       []TodayReportUnit `json:"units"`
-	TotalHours  string            `json:"total_hours"`
-	Overtime    string            `json:"overtime"`
-	Holidays    []string          `json:"holidays,omitempty"`
+	Units      []TodayReportUnit `json:"units"`
+	TotalHours string            `json:"total_hours"`
+	Overtime   string            `json:"overtime"`
+	Holidays   []string          `json:"holidays,omitempty"`
 }
 
+// GetTodayReport returns the units of today ordered by their start, the running unit until now, the total and
+// overtime hours including the running unit and the public holidays of the next seven days.
 func GetTodayReport(workingHoursConfig configuration.WorkingHoursConfig, a *models.AeonVault) TodayReport {
-	today := a.Days[time.Now().Format(time.DateOnly)]
-	var units []TodayReportUnit
+	today, ok := a.Days[time.Now().Format(time.DateOnly)]
+	if !ok {
+		today = &models.AeonDay{}
+	}
+	todayUnits := make([]models.AeonUnit, 0, len(today.Units))
+	for _, unit := range today.Units {
+		if unit.Start != nil {
+			todayUnits = append(todayUnits, unit)
+		}
+	}
+	sort.Slice(todayUnits, func(i, j int) bool {
+		return todayUnits[i].Start.Before(*todayUnits[j].Start)
+	})
+	units := make([]TodayReportUnit, 0, len(todayUnits))
 	var runningDuration time.Duration
 
-	for _, unit := range today.Units {
+	for _, unit := range todayUnits {
 		if unit.Duration != nil {
 			units = append(units, TodayReportUnit{
 				Start:    unit.Start.Format(time.TimeOnly),
 				Stop:     unit.Stop.Format(time.TimeOnly),
 				Duration: formatDuration(unit.Duration.Duration),
 				Running:  false,
+				Comment:  unit.Comment,
 			})
 		} else {
 			now := time.Now()
@@ -138,6 +210,7 @@ func GetTodayReport(workingHoursConfig configuration.WorkingHoursConfig, a *mode
 				Stop:     now.Format(time.TimeOnly),
 				Duration: formatDuration(runningDuration),
 				Running:  true,
+				Comment:  unit.Comment,
 			})
 		}
 	}
@@ -194,3 +267,91 @@ func formatDuration(d time.Duration) string {
 	seconds := int(d.Seconds()*correctionMultiplier) % 60
 	return fmt.Sprintf("%s%02d:%02d:%02d", sign, hours, minutes, seconds)
 }
+
+// DayReport lists the units of a day with their short IDs and the hours of the day.
+type DayReport struct {
+	Day               string               `json:"day"`
+	WeekEnd           bool                 `json:"weekend"`
+	VacationDay       bool                 `json:"vacation_day"`
+	PublicHoliday     bool                 `json:"public_holiday"`
+	PublicHolidayName string               `json:"public_holiday_name,omitempty"`
+	Units             []commands.UnitEntry `json:"units"`
+	TotalHours        *models.AeonDuration `json:"total_hours,omitempty"`
+	OvertimeHours     *models.AeonDuration `json:"overtime_hours,omitempty"`
+	// AsOf is the time the data is shown as of, if it was rebuilt from the event log
+	AsOf *time.Time `json:"as_of,omitempty"`
+	// found reports whether the time tracking data has the day
+	found bool
+}
+
+// GetDayReport returns the units of a day ordered by their start and the hours of the day.
+func GetDayReport(dayKey string, a *models.AeonVault) DayReport {
+	report := DayReport{Day: dayKey, Units: []commands.UnitEntry{}}
+	day, ok := a.Days[dayKey]
+	if !ok {
+		return report
+	}
+	report.found = true
+	report.WeekEnd, report.VacationDay = day.WeekEnd, day.VacationDay
+	report.PublicHoliday, report.PublicHolidayName = day.PublicHoliday, day.PublicHolidayName
+	report.TotalHours, report.OvertimeHours = day.TotalHours, day.OvertimeHours
+	if date, err := time.Parse(time.DateOnly, dayKey); err == nil {
+		if entries := commands.LogCommand(commands.LogFilter{From: date, To: date}, a); entries != nil {
+			report.Units = entries
+		}
+	}
+	return report
+}
+
+// RenderText writes the units of the day with their times and comments, followed by the total and overtime hours of the day.
+func (r DayReport) RenderText(w io.Writer) error {
+	var text strings.Builder
+	writeAsOf(&text, r.AsOf)
+	if !r.found {
+		fmt.Fprintf(&text, "No data for %s.\n", r.Day)
+		_, err := io.WriteString(w, text.String())
+		return err
+	}
+	title := r.Day
+	switch {
+	case r.PublicHoliday:
+		title += " (" + r.PublicHolidayName + ")"
+	case r.VacationDay:
+		title += " (vacation)"
+	case r.WeekEnd:
+		title += " (weekend)"
+	}
+	text.WriteString(title + "\n")
+	for _, unit := range r.Units {
+		marker, stop, duration := " ", "\t", ""
+		if unit.Stop == nil {
+			marker = "⏱"
+		} else {
+			stop = unit.Stop.Format(time.TimeOnly)
+		}
+		if unit.Duration != nil {
+			duration = formatDuration(unit.Duration.Duration)
+		}
+		fmt.Fprintf(&text, unitLineTmpl+"\t%s\t%s\n", marker, unit.Start.Format(time.TimeOnly), stop, duration, unit.Type, unit.Comment)
+	}
+	if r.TotalHours != nil {
+		fmt.Fprintf(&text, "TotalHours:\t%s\n", formatDuration(r.TotalHours.Duration))
+	}
+	if r.OvertimeHours != nil {
+		fmt.Fprintf(&text, "Overtime:\t%s\n", formatDuration(r.OvertimeHours.Duration))
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// writeAsOf writes the time the data is shown as of, if it is set
+func writeAsOf(text *strings.Builder, asOf *time.Time) {
+	if asOf != nil {
+		fmt.Fprintf(text, "As of %s\n", asOf.Format(time.DateTime))
+	}
+}
+
+// RenderTable returns the units of the day as table.
+func (r DayReport) RenderTable() ([]string, [][]string) {
+	return UnitLog(r.Units).RenderTable()
+}
@@ -0,0 +1,53 @@
+package reporting
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jame-developer/aeontrac/pkg/commands"
+	"github.com/jame-developer/aeontrac/pkg/output"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowAsOf(t *testing.T) {
+	asOf := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	a := newVault(map[string][3]int{"2024-05-01": {8, 10, 1}}, repositories.WorkType)
+	day := GetDayReport("2024-05-01", a)
+	day.AsOf = &asOf
+	missingDay := GetDayReport("2024-05-02", a)
+	missingDay.AsOf = &asOf
+	entries := commands.LogCommand(commands.LogFilter{}, a)
+	require.Len(t, entries, 1)
+	tests := []struct {
+		name     string
+		format   output.Format
+		result   any
+		expected []string
+		absent   []string
+	}{
+		{name: "DayAsText", format: output.Text, result: day, expected: []string{"As of 2024-05-01 12:00:00\n2024-05-01\n"}},
+		{name: "MissingDayAsText", format: output.Text, result: missingDay, expected: []string{"As of 2024-05-01 12:00:00\nNo data for 2024-05-02.\n"}},
+		{name: "DayAsJSON", format: output.JSON, result: day, expected: []string{`"as_of": "2024-05-01T12:00:00Z"`}, absent: []string{"As of"}},
+		{name: "DayAsYAML", format: output.YAML, result: day, expected: []string{`as_of: "2024-05-01T12:00:00Z"`}, absent: []string{"As of"}},
+		{name: "DayAsCSV", format: output.CSV, result: day, expected: []string{"08:00:00"}, absent: []string{"As of"}},
+		{name: "DayNowAsJSON", format: output.JSON, result: GetDayReport("2024-05-01", a), absent: []string{"as_of"}},
+		{name: "UnitAsText", format: output.Text, result: UnitDetail{UnitEntry: entries[0], AsOf: &asOf}, expected: []string{"As of 2024-05-01 12:00:00\nID:"}},
+		{name: "UnitAsJSON", format: output.JSON, result: UnitDetail{UnitEntry: entries[0], AsOf: &asOf}, expected: []string{`"as_of": "2024-05-01T12:00:00Z"`, `"short_id"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var written bytes.Buffer
+			require.NoError(t, output.Write(&written, tt.format, tt.result))
+
+			for _, expected := range tt.expected {
+				assert.Contains(t, written.String(), expected)
+			}
+			for _, absent := range tt.absent {
+				assert.NotContains(t, written.String(), absent)
+			}
+		})
+	}
+}
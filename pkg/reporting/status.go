@@ -0,0 +1,172 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/commands"
+	"github.com/jame-developer/aeontrac/pkg/models"
+)
+
+// DefaultStatusFormat is the name of the status format used without --format
+const DefaultStatusFormat = "default"
+
+// StatusFormats are the built-in templates of status lines by their name
+var StatusFormats = map[string]string{
+	DefaultStatusFormat: `{{if .Running}}⏱ {{.Elapsed.Clock}}{{with .Comment}} {{.}}{{end}}{{else}}⏸{{end}}` +
+		` | today {{.Today.Clock}}{{if .Target}}, {{.Remaining.Clock}} left{{end}}`,
+	// waybar reads a JSON object per line from custom modules with "return-type": "json"
+	"waybar": `{{$icon := "⏸"}}{{if .Running}}{{$icon = "⏱"}}{{end}}` +
+		`{{$tooltip := printf "Today %s" .Today.HMS}}{{if .Target}}{{$tooltip = printf "%s, %s left" $tooltip .Remaining.HMS}}{{end}}` +
+		`{{if .Running}}{{$tooltip = printf "Running %s since %s %s\n%s" .Elapsed.HMS (.Start.Format "15:04") .Comment $tooltip}}{{end}}` +
+		`{"text":{{json (printf "%s %s" $icon .Today.Clock)}},"tooltip":{{json $tooltip}},` +
+		`"alt":{{json .State}},"class":{{json .State}},"percentage":{{.Percentage}}}`,
+	// i3blocks reads the full text, the short text and the color from the lines of the output
+	"i3blocks": "{{if .Running}}⏱ {{.Elapsed.Clock}} | {{end}}{{.Today.Clock}}\n" +
+		"{{.Today.Clock}}\n" +
+		"{{if .Running}}#A3BE8C{{else if and .Target (not .Remaining)}}#88C0D0{{else}}#EBCB8B{{end}}",
+}
+
+// StatusDuration is a duration of the status, written like 1h2m3s.
+type StatusDuration time.Duration
+
+// String returns the duration rounded to seconds, like 1h2m3s.
+func (d StatusDuration) String() string {
+	return time.Duration(d).Round(time.Second).String()
+}
+
+// Clock returns the duration in hours and minutes, like 1:02.
+func (d StatusDuration) Clock() string {
+	minutes := int64(time.Duration(d).Round(time.Minute) / time.Minute)
+	sign := ""
+	if minutes < 0 {
+		sign, minutes = "-", -minutes
+	}
+	return fmt.Sprintf("%s%d:%02d", sign, minutes/60, minutes%60)
+}
+
+// HMS returns the duration in hours, minutes and seconds, like 01:02:03.
+func (d StatusDuration) HMS() string {
+	return formatDuration(time.Duration(d))
+}
+
+// Minutes returns the duration in whole minutes.
+func (d StatusDuration) Minutes() int64 {
+	return int64(time.Duration(d) / time.Minute)
+}
+
+// MarshalText writes the duration like String, so that it is a string in JSON.
+func (d StatusDuration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Status is the state of time tracking for the status lines of shell prompts and bars. Today counts the units of work
+// of today and the running unit until now, Target is the working time of today, which is zero on days off.
+type Status struct {
+	Running   bool           `json:"running"`
+	Day       string         `json:"day"`
+	Start     *time.Time     `json:"start,omitempty"`
+	Elapsed   StatusDuration `json:"elapsed"`
+	Comment   string         `json:"comment,omitempty"`
+	Projects  []string       `json:"projects,omitempty"`
+	Today     StatusDuration `json:"today"`
+	Target    StatusDuration `json:"target"`
+	Remaining StatusDuration `json:"remaining"`
+	Overtime  StatusDuration `json:"overtime"`
+}
+
+// GetStatus returns the status of time tracking at now.
+func GetStatus(workingHoursConfig configuration.WorkingHoursConfig, a *models.AeonVault, now time.Time) Status {
+	dayKey := now.Format(time.DateOnly)
+	status := Status{Day: dayKey}
+	if running := a.CurrentRunningUnit; running != nil {
+		if day, ok := a.Days[running.DayKey]; ok {
+			if unit, ok := day.Units[running.UnitID]; ok && unit.Start != nil {
+				status.Running, status.Start = true, unit.Start
+				status.Elapsed = StatusDuration(now.Sub(*unit.Start))
+				status.Comment, status.Projects = unit.Comment, commands.Projects(unit.Comment)
+			}
+		}
+	}
+	day, ok := a.Days[dayKey]
+	if ok {
+		status.Today = StatusDuration(workedDuration(day, now))
+	}
+	if workingHoursConfig.Enabled && workingHoursConfig.WorkDay != nil && (!ok || !(day.VacationDay || day.PublicHoliday || day.WeekEnd)) {
+		status.Target = StatusDuration(workingHoursConfig.WorkDay.Duration)
+	}
+	status.Remaining = max(status.Target-status.Today, 0)
+	status.Overtime = max(status.Today-status.Target, 0)
+	return status
+}
+
+// State returns running or stopped, for the classes of bars.
+func (s Status) State() string {
+	if s.Running {
+		return "running"
+	}
+	return "stopped"
+}
+
+// Percentage returns the share of the target worked today, 100 once the target is reached or if there is none.
+func (s Status) Percentage() int {
+	if s.Target <= 0 {
+		return 100
+	}
+	return int(min(100*s.Today/s.Target, 100))
+}
+
+// Format writes the status with a built-in format by its name, or with a Go template like "{{.Today.Clock}}".
+func (s Status) Format(format string) (string, error) {
+	text, ok := StatusFormats[format]
+	if !ok {
+		text = format
+	}
+	tmpl, err := template.New("status").Funcs(template.FuncMap{"json": statusJSON}).Parse(text)
+	if err != nil {
+		return "", &commands.Error{Kind: commands.KindInvalidInput, Err: fmt.Errorf("invalid status format: %w", err)}
+	}
+	var formatted bytes.Buffer
+	if err = tmpl.Execute(&formatted, s); err != nil {
+		return "", &commands.Error{Kind: commands.KindInvalidInput, Err: fmt.Errorf("invalid status format: %w", err)}
+	}
+	return formatted.String(), nil
+}
+
+// RenderText writes the status with the default format.
+func (s Status) RenderText(w io.Writer) error {
+	formatted, err := s.Format(DefaultStatusFormat)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, formatted)
+	return err
+}
+
+// StatusFormatNames returns the names of the built-in formats in alphabetical order.
+func StatusFormatNames() []string {
+	names := make([]string, 0, len(StatusFormats))
+	for name := range StatusFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// statusJSON returns a value as JSON, to write strings of templates into JSON
+func statusJSON(value any) (string, error) {
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(encoded.String(), "\n"), nil
+}
@@ -0,0 +1,125 @@
+package reporting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withRunningUnit marks the only unit of the day as the running unit with a comment
+func withRunningUnit(a *models.AeonVault, dayKey, comment string) *models.AeonVault {
+	for id, unit := range a.Days[dayKey].Units {
+		unit.Comment = comment
+		a.Days[dayKey].Units[id] = unit
+		a.CurrentRunningUnit = &models.AeonCurrentRunningUnit{DayKey: dayKey, UnitID: id}
+	}
+	return a
+}
+
+func TestGetStatus(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	workingHours := configuration.WorkingHoursConfig{Enabled: true, WorkDay: &models.AeonDuration{Duration: 8 * time.Hour}}
+	tests := []struct {
+		name         string
+		workingHours configuration.WorkingHoursConfig
+		vault        *models.AeonVault
+		expected     Status
+	}{
+		{
+			name:         "Stopped",
+			workingHours: workingHours,
+			vault:        newVault(map[string][3]int{"2024-05-01": {8, 11, 1}}, repositories.WorkType),
+			expected:     Status{Day: "2024-05-01", Today: StatusDuration(3 * time.Hour), Target: StatusDuration(8 * time.Hour), Remaining: StatusDuration(5 * time.Hour)},
+		},
+		{
+			name:         "RunningUnitCountsUntilNow",
+			workingHours: workingHours,
+			vault:        withRunningUnit(newVault(map[string][3]int{"2024-05-01": {9, 0, 1}}, repositories.WorkType), "2024-05-01", "+aeontrac review"),
+			expected: Status{Running: true, Day: "2024-05-01", Elapsed: StatusDuration(3*time.Hour + 30*time.Minute),
+				Comment: "+aeontrac review", Projects: []string{"aeontrac"}, Today: StatusDuration(3*time.Hour + 30*time.Minute),
+				Target: StatusDuration(8 * time.Hour), Remaining: StatusDuration(4*time.Hour + 30*time.Minute)},
+		},
+		{
+			name:         "OvertimeBeyondTarget",
+			workingHours: workingHours,
+			vault:        newVault(map[string][3]int{"2024-05-01": {0, 9, 1}}, repositories.WorkType),
+			expected:     Status{Day: "2024-05-01", Today: StatusDuration(9 * time.Hour), Target: StatusDuration(8 * time.Hour), Overtime: StatusDuration(time.Hour)},
+		},
+		{
+			name:         "NoTargetWithoutWorkingHours",
+			workingHours: configuration.WorkingHoursConfig{},
+			vault:        newVault(map[string][3]int{"2024-05-01": {8, 10, 1}}, repositories.WorkType),
+			expected:     Status{Day: "2024-05-01", Today: StatusDuration(2 * time.Hour), Overtime: StatusDuration(2 * time.Hour)},
+		},
+		{
+			name:         "NoTargetOnVacation",
+			workingHours: workingHours,
+			vault: func() *models.AeonVault {
+				a := newVault(map[string][3]int{"2024-05-01": {8, 10, 1}}, repositories.WorkType)
+				a.Days["2024-05-01"].VacationDay = true
+				return a
+			}(),
+			expected: Status{Day: "2024-05-01", Today: StatusDuration(2 * time.Hour), Overtime: StatusDuration(2 * time.Hour)},
+		},
+		{
+			name:         "EmptyVault",
+			workingHours: workingHours,
+			vault:        &models.AeonVault{Days: map[string]*models.AeonDay{}},
+			expected:     Status{Day: "2024-05-01", Target: StatusDuration(8 * time.Hour), Remaining: StatusDuration(8 * time.Hour)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := GetStatus(tt.workingHours, tt.vault, now)
+
+			status.Start = nil
+			assert.Equal(t, tt.expected, status)
+		})
+	}
+}
+
+func TestStatusFormat(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	running := Status{Running: true, Day: "2024-05-01", Start: &start, Elapsed: StatusDuration(90 * time.Minute), Comment: `"quoted" +aeontrac`,
+		Today: StatusDuration(6 * time.Hour), Target: StatusDuration(8 * time.Hour), Remaining: StatusDuration(2 * time.Hour)}
+	done := Status{Day: "2024-05-01", Today: StatusDuration(8*time.Hour + 20*time.Minute), Target: StatusDuration(8 * time.Hour), Overtime: StatusDuration(20 * time.Minute)}
+	tests := []struct {
+		name          string
+		status        Status
+		format        string
+		expected      string
+		expectedError bool
+	}{
+		{name: "Default", status: running, format: DefaultStatusFormat, expected: `⏱ 1:30 "quoted" +aeontrac | today 6:00, 2:00 left`},
+		{name: "DefaultStopped", status: done, format: DefaultStatusFormat, expected: "⏸ | today 8:20, 0:00 left"},
+		{
+			name:   "Waybar",
+			status: running,
+			format: "waybar",
+			expected: `{"text":"⏱ 6:00","tooltip":"Running 01:30:00 since 09:00 \"quoted\" +aeontrac\nToday 06:00:00, 02:00:00 left",` +
+				`"alt":"running","class":"running","percentage":75}`,
+		},
+		{name: "I3blocks", status: done, format: "i3blocks", expected: "8:20\n8:20\n#88C0D0"},
+		{name: "Template", status: done, format: "{{.State}} {{.Overtime}} {{.Overtime.Minutes}}", expected: "stopped 20m0s 20"},
+		{name: "InvalidTemplate", status: done, format: "{{.Today", expectedError: true},
+		{name: "UnknownField", status: done, format: "{{.Unknown}}", expectedError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted, err := tt.status.Format(tt.format)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, formatted)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package reporting
+
+import (
+	"time"
+
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/tracking"
+)
+
+// WeekDayHours are the hours of a day of a week, the running unit is counted until now.
+type WeekDayHours struct {
+	Day               string `json:"day"`
+	Weekday           string `json:"weekday"`
+	TotalHours        string `json:"total_hours"`
+	OvertimeHours     string `json:"overtime_hours"`
+	Today             bool   `json:"today"`
+	WeekEnd           bool   `json:"weekend"`
+	VacationDay       bool   `json:"vacation_day"`
+	PublicHoliday     bool   `json:"public_holiday"`
+	PublicHolidayName string `json:"public_holiday_name,omitempty"`
+}
+
+// WeekReport lists the hours of the days of an ISO week from Monday to Sunday.
+type WeekReport struct {
+	Week          string         `json:"week"`
+	Days          []WeekDayHours `json:"days"`
+	TotalHours    string         `json:"total_hours"`
+	OvertimeHours string         `json:"overtime_hours"`
+}
+
+// GetWeekReport returns the hours of the days of the ISO week of now, including the running unit until now.
+func GetWeekReport(workingHoursConfig configuration.WorkingHoursConfig, a *models.AeonVault, now time.Time) WeekReport {
+	todayKey := now.Format(time.DateOnly)
+	monday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday = monday.AddDate(0, 0, -(int(monday.Weekday())+6)%7)
+	report := WeekReport{Week: newIsoWeek(monday).String(), Days: make([]WeekDayHours, 0, 7)}
+	var weekTotal, weekOvertime time.Duration
+	for i := 0; i < 7; i++ {
+		date := monday.AddDate(0, 0, i)
+		dayKey := date.Format(time.DateOnly)
+		hours := WeekDayHours{Day: dayKey, Weekday: date.Weekday().String(), Today: dayKey == todayKey, WeekEnd: isWeekEnd(date)}
+		var total, overtime time.Duration
+		if day, ok := a.Days[dayKey]; ok {
+			hours.WeekEnd, hours.VacationDay = day.WeekEnd, day.VacationDay
+			hours.PublicHoliday, hours.PublicHolidayName = day.PublicHoliday, day.PublicHolidayName
+			if day.TotalHours != nil {
+				total = day.TotalHours.Duration
+			}
+			if day.OvertimeHours != nil {
+				overtime = day.OvertimeHours.Duration
+			}
+			// The running unit adds to the total, the overtime is calculated from it like once the unit is stopped
+			if running := runningDuration(a, dayKey, now); running > 0 {
+				total += running
+				overtime = tracking.OvertimeHours(day, total, workingHoursConfig)
+			}
+		}
+		hours.TotalHours, hours.OvertimeHours = formatDuration(total), formatDuration(overtime)
+		weekTotal, weekOvertime = weekTotal+total, weekOvertime+overtime
+		report.Days = append(report.Days, hours)
+	}
+	report.TotalHours, report.OvertimeHours = formatDuration(weekTotal), formatDuration(weekOvertime)
+	return report
+}
+
+// UpcomingHolidays returns the public holidays of the next days starting today, like "2024-12-25: Christmas Day".
+func UpcomingHolidays(nextNumberOfDays int, a *models.AeonVault) []string {
+	return getHolidayLinesForNextDays(nextNumberOfDays, a)
+}
+
+// runningDuration returns how long the running unit has been running until now, if it runs on the day
+func runningDuration(a *models.AeonVault, dayKey string, now time.Time) time.Duration {
+	running := a.CurrentRunningUnit
+	if running == nil || running.DayKey != dayKey {
+		return 0
+	}
+	unit, ok := a.Days[dayKey].Units[running.UnitID]
+	if !ok || unit.Start == nil || unit.Start.After(now) {
+		return 0
+	}
+	return now.Sub(*unit.Start)
+}
+
+// isWeekEnd reports whether the date is a Saturday or Sunday
+func isWeekEnd(date time.Time) bool {
+	return date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+}
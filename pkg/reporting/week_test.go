@@ -0,0 +1,113 @@
+package reporting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/repositories"
+	"github.com/jame-developer/aeontrac/pkg/tracking"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetWeekReport(t *testing.T) {
+	// Wednesday of the week 2024-W18, which started in the previous month
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	workingHours := configuration.WorkingHoursConfig{Enabled: true, WorkDay: &models.AeonDuration{Duration: 8 * time.Hour}}
+	tests := []struct {
+		name             string
+		workingHours     configuration.WorkingHoursConfig
+		vault            *models.AeonVault
+		expectedTotals   []string
+		expectedTotal    string
+		expectedOvertime string
+	}{
+		{
+			name:         "DaysOfTheWeekOnly",
+			workingHours: workingHours,
+			vault: func() *models.AeonVault {
+				a := newVault(map[string][3]int{"2024-04-29": {8, 10, 1}, "2024-04-28": {8, 12, 1}, "2024-05-06": {8, 12, 1}}, repositories.WorkType)
+				a.Days["2024-04-29"].TotalHours = &models.AeonDuration{Duration: 2 * time.Hour}
+				a.Days["2024-04-29"].OvertimeHours = &models.AeonDuration{Duration: -6 * time.Hour}
+				return a
+			}(),
+			expectedTotals:   []string{"02:00:00", "00:00:00", "00:00:00", "00:00:00", "00:00:00", "00:00:00", "00:00:00"},
+			expectedTotal:    "02:00:00",
+			expectedOvertime: "-06:00:00",
+		},
+		{
+			name:         "RunningUnitCountsUntilNow",
+			workingHours: workingHours,
+			vault: func() *models.AeonVault {
+				a := &models.AeonVault{Days: map[string]*models.AeonDay{}}
+				start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+				require.NoError(t, tracking.StartTracking(&start, "", a))
+				return a
+			}(),
+			expectedTotals:   []string{"00:00:00", "00:00:00", "03:00:00", "00:00:00", "00:00:00", "00:00:00", "00:00:00"},
+			expectedTotal:    "03:00:00",
+			expectedOvertime: "-05:00:00",
+		},
+		{
+			name:         "RunningUnitAfterCompletedUnit",
+			workingHours: workingHours,
+			vault: func() *models.AeonVault {
+				a := &models.AeonVault{Days: map[string]*models.AeonDay{}}
+				start, stop := time.Date(2024, 5, 1, 7, 0, 0, 0, time.UTC), time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+				require.NoError(t, tracking.AddTimeWorkUnit(&start, &stop, "", workingHours, a))
+				start = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+				require.NoError(t, tracking.StartTracking(&start, "", a))
+				return a
+			}(),
+			expectedTotals:   []string{"00:00:00", "00:00:00", "03:00:00", "00:00:00", "00:00:00", "00:00:00", "00:00:00"},
+			expectedTotal:    "03:00:00",
+			expectedOvertime: "-05:00:00",
+		},
+		{
+			name:         "RunningUnitOnHolidayIsOvertime",
+			workingHours: workingHours,
+			vault: func() *models.AeonVault {
+				a := withRunningUnit(newVault(map[string][3]int{"2024-05-01": {9, 0, 1}}, repositories.WorkType), "2024-05-01", "")
+				a.Days["2024-05-01"].PublicHoliday = true
+				return a
+			}(),
+			expectedTotals:   []string{"00:00:00", "00:00:00", "03:00:00", "00:00:00", "00:00:00", "00:00:00", "00:00:00"},
+			expectedTotal:    "03:00:00",
+			expectedOvertime: "03:00:00",
+		},
+		{
+			name:         "RunningUnitIsNoOvertimeWithoutWorkingHours",
+			workingHours: configuration.WorkingHoursConfig{},
+			vault: func() *models.AeonVault {
+				a := withRunningUnit(newVault(map[string][3]int{"2024-05-01": {9, 0, 1}}, repositories.WorkType), "2024-05-01", "")
+				a.Days["2024-05-01"].TotalHours = &models.AeonDuration{}
+				a.Days["2024-05-01"].OvertimeHours = &models.AeonDuration{}
+				return a
+			}(),
+			expectedTotals:   []string{"00:00:00", "00:00:00", "03:00:00", "00:00:00", "00:00:00", "00:00:00", "00:00:00"},
+			expectedTotal:    "03:00:00",
+			expectedOvertime: "00:00:00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := GetWeekReport(tt.workingHours, tt.vault, now)
+
+			assert.Equal(t, "2024-W18", report.Week)
+			require.Len(t, report.Days, 7)
+			assert.Equal(t, "2024-04-29", report.Days[0].Day)
+			assert.Equal(t, "Monday", report.Days[0].Weekday)
+			assert.True(t, report.Days[2].Today)
+			assert.True(t, report.Days[6].WeekEnd)
+			totals := make([]string, len(report.Days))
+			for i, day := range report.Days {
+				totals[i] = day.TotalHours
+			}
+			assert.Equal(t, tt.expectedTotals, totals)
+			assert.Equal(t, tt.expectedTotal, report.TotalHours)
+			assert.Equal(t, tt.expectedOvertime, report.OvertimeHours)
+		})
+	}
+}
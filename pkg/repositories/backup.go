@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
+)
+
+const backupFolderName = "backups"
+
+// ErrBackupNotFound is returned if no backup with the requested ID exists.
+var ErrBackupNotFound = errors.New("backup not found")
+
+// Backup describes a single backup of the time tracking data.
+type Backup struct {
+	ID      string
+	Created time.Time
+	Size    int64
+	Path    string
+}
+
+// ListBackups returns all backups of the time tracking data in the provided folder, the most recent first.
+func ListBackups(folder string) ([]Backup, error) {
+	entries, err := os.ReadDir(filepath.Join(folder, backupFolderName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		var timestamp int64
+		if entry.IsDir() {
+			continue
+		}
+		if _, scanErr := fmt.Sscanf(entry.Name(), BackUpFileNameTmpl, &timestamp); scanErr != nil || fmt.Sprintf(BackUpFileNameTmpl, timestamp) != entry.Name() {
+			continue
+		}
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			return nil, infoErr
+		}
+		backups = append(backups, Backup{
+			ID:      strconv.FormatInt(timestamp, 10),
+			Created: time.UnixMilli(timestamp),
+			Size:    info.Size(),
+			Path:    filepath.Join(folder, backupFolderName, entry.Name()),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Created.After(backups[j].Created)
+	})
+
+	return backups, nil
+}
+
+// FindBackup returns the backup with the provided ID or unique ID prefix, "latest" selects the most recent backup.
+func FindBackup(folder, id string) (Backup, error) {
+	backups, err := ListBackups(folder)
+	if err != nil {
+		return Backup{}, err
+	}
+	if id == "latest" && len(backups) > 0 {
+		return backups[0], nil
+	}
+	var found []Backup
+	for _, backup := range backups {
+		if strings.HasPrefix(backup.ID, id) {
+			found = append(found, backup)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Backup{}, fmt.Errorf("%w: %s", ErrBackupNotFound, id)
+	case 1:
+		return found[0], nil
+	default:
+		return Backup{}, fmt.Errorf("backup ID %s is ambiguous, it matches %d backups", id, len(found))
+	}
+}
+
+// LoadBackup loads and validates the time tracking data stored in the backup with the provided ID, encrypted backups are decrypted with the key.
+func LoadBackup(folder, id string, validator *validator.Validate, key *vaultcrypt.Key) (models.AeonVault, error) {
+	backup, err := FindBackup(folder, id)
+	if err != nil {
+		return models.AeonVault{}, err
+	}
+
+	return LoadAeonVaultFile(backup.Path, validator, key)
+}
+
+// PruneBackups removes all backups exceeding the maximum count or age of the provided backup configuration.
+func PruneBackups(folder string, backupConfig configuration.BackupConfig) error {
+	backups, err := ListBackups(folder)
+	if err != nil {
+		return err
+	}
+	for i, backup := range backups {
+		tooMany := backupConfig.MaxCount > 0 && i >= backupConfig.MaxCount
+		tooOld := backupConfig.MaxAge != nil && backupConfig.MaxAge.Duration > 0 && time.Since(backup.Created) > backupConfig.MaxAge.Duration
+		if tooMany || tooOld {
+			if err = os.Remove(backup.Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// createBackup stores the provided data as a new timestamped backup in the backup folder.
+func createBackup(folder string, data []byte) (Backup, error) {
+	backupFolder := filepath.Join(folder, backupFolderName)
+	if err := os.MkdirAll(backupFolder, 0755); err != nil {
+		return Backup{}, err
+	}
+	created := time.Now()
+	// Two saves within the same millisecond must not overwrite each other's backup
+	for {
+		if _, err := os.Stat(filepath.Join(backupFolder, fmt.Sprintf(BackUpFileNameTmpl, created.UnixMilli()))); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		created = created.Add(time.Millisecond)
+	}
+	fileName := filepath.Join(backupFolder, fmt.Sprintf(BackUpFileNameTmpl, created.UnixMilli()))
+	if err := WriteFileAtomic(fileName, data, 0600); err != nil {
+		return Backup{}, err
+	}
+
+	return Backup{
+		ID:      strconv.FormatInt(created.UnixMilli(), 10),
+		Created: time.UnixMilli(created.UnixMilli()),
+		Size:    int64(len(data)),
+		Path:    fileName,
+	}, nil
+}
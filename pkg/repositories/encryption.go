@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
+)
+
+// IsAeonVaultEncrypted reports whether the time tracking data file of the provided folder is encrypted, a missing file is not encrypted.
+func IsAeonVaultEncrypted(folder string) (bool, error) {
+	return IsAeonVaultFileEncrypted(filepath.Join(folder, dataFileName))
+}
+
+// IsAeonVaultFileEncrypted reports whether the provided time tracking data file is encrypted, a missing file is not encrypted.
+func IsAeonVaultFileEncrypted(fileName string) (bool, error) {
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return vaultcrypt.IsEncrypted(data), nil
+}
+
+// ReencryptAeonVault re-encrypts the time tracking data file, all backups of the provided folder and the other files,
+// like the operation journal, from the old to the new key, keeping their permissions. Missing other files are skipped.
+// A nil old key reads plaintext files, a nil new key writes plaintext files.
+// All files are re-encrypted in memory before the first one is written, so a wrong key or a damaged file changes nothing.
+// If writing a file fails, the error lists the files which still use the old key.
+func ReencryptAeonVault(folder string, oldKey, newKey *vaultcrypt.Key, otherFileNames ...string) error {
+	fileNames := []string{filepath.Join(folder, dataFileName)}
+	backups, err := ListBackups(folder)
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups {
+		fileNames = append(fileNames, backup.Path)
+	}
+	fileNames = append(fileNames, otherFileNames...)
+
+	type sealedFile struct {
+		name string
+		data []byte
+		perm os.FileMode
+	}
+	sealed := make([]sealedFile, 0, len(fileNames))
+	for _, fileName := range fileNames {
+		info, err := os.Stat(fileName)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		data, err := readVaultFile(fileName, oldKey)
+		if err != nil {
+			return err
+		}
+		if data, err = sealVaultData(data, newKey); err != nil {
+			return err
+		}
+		sealed = append(sealed, sealedFile{name: fileName, data: data, perm: info.Mode().Perm()})
+	}
+	for i, file := range sealed {
+		if err = WriteFileAtomic(file.name, file.data, file.perm); err != nil {
+			remaining := make([]string, 0, len(sealed)-i)
+			for _, file := range sealed[i:] {
+				remaining = append(remaining, file.name)
+			}
+			return fmt.Errorf("error writing %s, these files still use the old key: %s: %w", file.name, strings.Join(remaining, ", "), err)
+		}
+	}
+	return nil
+}
+
+// readVaultFile reads a file of time tracking data, decrypting it with the key if it is encrypted
+func readVaultFile(fileName string, key *vaultcrypt.Key) ([]byte, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil || !vaultcrypt.IsEncrypted(data) {
+		return data, err
+	}
+	if data, err = vaultcrypt.Decrypt(key, data); err != nil {
+		return nil, fmt.Errorf("error decrypting %s: %w", filepath.Base(fileName), err)
+	}
+	return data, nil
+}
+
+// sealVaultData encrypts time tracking data with the key, without key the data is kept in plaintext
+func sealVaultData(data []byte, key *vaultcrypt.Key) ([]byte, error) {
+	if key == nil {
+		return data, nil
+	}
+	return vaultcrypt.Encrypt(key, data)
+}
@@ -0,0 +1,495 @@
+package repositories
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/pkg/eventlog"
+	"github.com/jame-developer/aeontrac/pkg/models"
+)
+
+const (
+	eventLogFileName     = "aeon_events.jsonl"
+	snapshotFolderName   = "snapshots"
+	snapshotFileNameTmpl = "%d.json"
+	// DefaultSnapshotInterval is the number of events after which a snapshot is taken, if the configuration does not set one
+	DefaultSnapshotInterval = 500
+)
+
+type (
+	// EventLogVaultRepository stores the time tracking data as an append-only log of events in JSON Lines format.
+	// The data is rebuilt by replaying the events on top of the latest snapshot, which allows rebuilding it for any point in time.
+	EventLogVaultRepository struct {
+		folder           string
+		validator        *validator.Validate
+		snapshotInterval int
+		loaded           *models.AeonVault
+	}
+	// snapshot is the time tracking data after the event with its sequence number
+	snapshot struct {
+		Sequence int64           `json:"seq"`
+		Time     time.Time       `json:"time"`
+		Vault    json.RawMessage `json:"vault"`
+	}
+	// snapshotInfo describes a snapshot file without reading it
+	snapshotInfo struct {
+		sequence int64
+		path     string
+	}
+	// projection is the time tracking data rebuilt from the event log
+	projection struct {
+		data             models.AeonVault
+		lastSequence     int64
+		snapshotSequence int64
+	}
+)
+
+// NewEventLogVaultRepository creates a repository storing the time tracking data in the event log of the provided folder.
+// A snapshot is taken after every snapshotInterval events, DefaultSnapshotInterval is used if it is not positive.
+func NewEventLogVaultRepository(folder string, validator *validator.Validate, snapshotInterval int) *EventLogVaultRepository {
+	if snapshotInterval <= 0 {
+		snapshotInterval = DefaultSnapshotInterval
+	}
+	return &EventLogVaultRepository{folder: folder, validator: validator, snapshotInterval: snapshotInterval}
+}
+
+func (r *EventLogVaultRepository) Load() (models.AeonVault, error) {
+	p, err := r.project(time.Time{})
+	if err != nil {
+		return models.AeonVault{}, err
+	}
+	return p.data, nil
+}
+
+// LoadAsOf rebuilds the time tracking data as it was at the provided time.
+func (r *EventLogVaultRepository) LoadAsOf(asOf time.Time) (models.AeonVault, error) {
+	p, err := r.project(asOf)
+	if err != nil {
+		return models.AeonVault{}, err
+	}
+	if p.lastSequence == 0 {
+		return models.AeonVault{}, fmt.Errorf("no time tracking data has been recorded before %s", asOf.Format(time.DateTime))
+	}
+	return p.data, nil
+}
+
+// Events returns all events of the log in their order.
+func (r *EventLogVaultRepository) Events() ([]eventlog.Event, error) {
+	var events []eventlog.Event
+	err := r.readEvents(0, time.Time{}, func(event eventlog.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	return events, err
+}
+
+// Save appends the events changing the stored data into the provided data to the log, taking a snapshot if it is due.
+// The events are recorded with the operation of the data.
+func (r *EventLogVaultRepository) Save(data models.AeonVault) error {
+	return r.saveChanges(data, nil)
+}
+
+// saveChanges appends the events changing the stored data into the provided data like Save.
+// If data was stored before and changes, backup is called with the stored data before the events are appended.
+func (r *EventLogVaultRepository) saveChanges(data models.AeonVault, backup func(previous models.AeonVault) error) error {
+	r.loaded = nil
+	data.SchemaVersion = CurrentSchemaVersion
+	var before *models.AeonVault
+	p, err := r.project(time.Time{})
+	if err == nil {
+		before = &p.data
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	events := eventlog.Diff(before, &data)
+	if len(events) == 0 {
+		return nil
+	}
+	if before != nil && backup != nil {
+		if err = backup(*before); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	var encoded bytes.Buffer
+	for i := range events {
+		p.lastSequence++
+		events[i].Sequence, events[i].Time, events[i].Operation = p.lastSequence, now, data.CommandOperation
+		line, err := json.Marshal(events[i])
+		if err != nil {
+			return err
+		}
+		encoded.Write(line)
+		encoded.WriteByte('\n')
+	}
+	fileName := filepath.Join(r.folder, eventLogFileName)
+	if err = repairFinalLine(fileName); err != nil {
+		return fmt.Errorf("error repairing the end of the event log: %w", err)
+	}
+	if err = appendFile(fileName, encoded.Bytes()); err != nil {
+		return fmt.Errorf("error appending to event log: %w", err)
+	}
+	if p.lastSequence-p.snapshotSequence >= int64(r.snapshotInterval) {
+		return r.saveSnapshot(snapshot{Sequence: p.lastSequence, Time: now}, data)
+	}
+	return nil
+}
+
+func (r *EventLogVaultRepository) Exists() (bool, error) {
+	_, err := os.Stat(filepath.Join(r.folder, eventLogFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *EventLogVaultRepository) Day(dayKey string) (*models.AeonDay, error) {
+	data, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	day, ok := data.Days[dayKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: day %s", os.ErrNotExist, dayKey)
+	}
+	return day, nil
+}
+
+func (r *EventLogVaultRepository) DaysInRange(fromDayKey, toDayKey string) (map[string]*models.AeonDay, error) {
+	data, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	days := map[string]*models.AeonDay{}
+	for dayKey, day := range data.Days {
+		if dayKey >= fromDayKey && dayKey <= toDayKey {
+			days[dayKey] = day
+		}
+	}
+	return days, nil
+}
+
+func (r *EventLogVaultRepository) SaveDay(dayKey string, day *models.AeonDay) error {
+	return r.update(func(data *models.AeonVault) error {
+		data.Days[dayKey] = day
+		return nil
+	})
+}
+
+func (r *EventLogVaultRepository) DeleteDay(dayKey string) error {
+	return r.update(func(data *models.AeonVault) error {
+		delete(data.Days, dayKey)
+		return nil
+	})
+}
+
+func (r *EventLogVaultRepository) SaveUnit(dayKey string, unitID uuid.UUID, unit models.AeonUnit) error {
+	return r.update(func(data *models.AeonVault) error {
+		day, ok := data.Days[dayKey]
+		if !ok {
+			return fmt.Errorf("%w: day %s", os.ErrNotExist, dayKey)
+		}
+		if day.Units == nil {
+			day.Units = map[uuid.UUID]models.AeonUnit{}
+		}
+		day.Units[unitID] = unit
+		return nil
+	})
+}
+
+func (r *EventLogVaultRepository) DeleteUnit(dayKey string, unitID uuid.UUID) error {
+	return r.update(func(data *models.AeonVault) error {
+		if day, ok := data.Days[dayKey]; ok {
+			delete(day.Units, unitID)
+		}
+		return nil
+	})
+}
+
+func (r *EventLogVaultRepository) RunningUnit() (*models.AeonCurrentRunningUnit, error) {
+	data, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	return data.CurrentRunningUnit, nil
+}
+
+func (r *EventLogVaultRepository) SetRunningUnit(runningUnit *models.AeonCurrentRunningUnit) error {
+	return r.update(func(data *models.AeonVault) error {
+		data.CurrentRunningUnit = runningUnit
+		return nil
+	})
+}
+
+func (r *EventLogVaultRepository) Setting(key string) (string, bool, error) {
+	data, err := r.read()
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := data.Settings[key]
+	return value, ok, nil
+}
+
+func (r *EventLogVaultRepository) SetSetting(key, value string) error {
+	return r.update(func(data *models.AeonVault) error {
+		if data.Settings == nil {
+			data.Settings = map[string]string{}
+		}
+		data.Settings[key] = value
+		return nil
+	})
+}
+
+func (r *EventLogVaultRepository) Close() error {
+	return nil
+}
+
+// read rebuilds the data once for the reading methods, saving discards it
+func (r *EventLogVaultRepository) read() (models.AeonVault, error) {
+	if r.loaded == nil {
+		data, err := r.Load()
+		if err != nil {
+			return models.AeonVault{}, err
+		}
+		r.loaded = &data
+	}
+	return *r.loaded, nil
+}
+
+// update rebuilds the data, applies the change and appends the resulting events
+func (r *EventLogVaultRepository) update(change func(data *models.AeonVault) error) error {
+	data, err := r.Load()
+	if err != nil {
+		return err
+	}
+	if err = change(&data); err != nil {
+		return err
+	}
+	return r.Save(data)
+}
+
+// project rebuilds the data from the latest snapshot and the events after it, up to the provided time if it is not zero
+func (r *EventLogVaultRepository) project(asOf time.Time) (projection, error) {
+	exists, err := r.Exists()
+	if err != nil {
+		return projection{}, err
+	}
+	if !exists {
+		return projection{}, fmt.Errorf("%w: %s", os.ErrNotExist, eventLogFileName)
+	}
+	p := projection{data: models.AeonVault{Days: map[string]*models.AeonDay{}}}
+	if s, data, ok, err := r.latestSnapshot(asOf); err != nil {
+		return projection{}, err
+	} else if ok {
+		p.data, p.lastSequence, p.snapshotSequence = data, s.Sequence, s.Sequence
+	}
+	err = r.readEvents(p.snapshotSequence, asOf, func(event eventlog.Event) error {
+		if event.Sequence != p.lastSequence+1 {
+			return fmt.Errorf("event %d follows event %d, the event log is incomplete", event.Sequence, p.lastSequence)
+		}
+		p.lastSequence = event.Sequence
+		return eventlog.Apply(&p.data, event)
+	})
+	if err != nil {
+		return projection{}, err
+	}
+	p.data.SchemaVersion = CurrentSchemaVersion
+	if err = ValidateAeonVault(&p.data, r.validator); err != nil {
+		return projection{}, err
+	}
+	return p, nil
+}
+
+// readEvents calls handle for every event after the provided sequence number, up to the provided time if it is not zero.
+// A final line without line break which cannot be decoded is ignored, it is left by a crash while appending events.
+func (r *EventLogVaultRepository) readEvents(afterSequence int64, asOf time.Time, handle func(event eventlog.Event) error) error {
+	file, err := os.Open(filepath.Join(r.folder, eventLogFileName))
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		content, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		final := err != nil
+		if len(bytes.TrimSpace(content)) > 0 {
+			var event eventlog.Event
+			if err = json.Unmarshal(content, &event); err != nil {
+				if final {
+					return nil
+				}
+				return fmt.Errorf("error decoding line %d of the event log: %w", line, err)
+			}
+			if !asOf.IsZero() && event.Time.After(asOf) {
+				return nil
+			}
+			if event.Sequence > afterSequence {
+				if err = handle(event); err != nil {
+					return err
+				}
+			}
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// latestSnapshot returns the latest snapshot taken up to the provided time if it is not zero, ok is false if there is none
+func (r *EventLogVaultRepository) latestSnapshot(asOf time.Time) (snapshot, models.AeonVault, bool, error) {
+	snapshots, err := r.listSnapshots()
+	if err != nil {
+		return snapshot{}, models.AeonVault{}, false, err
+	}
+	for _, info := range snapshots {
+		encoded, err := os.ReadFile(info.path)
+		if err != nil {
+			return snapshot{}, models.AeonVault{}, false, err
+		}
+		var s snapshot
+		if err = json.Unmarshal(encoded, &s); err != nil {
+			return snapshot{}, models.AeonVault{}, false, fmt.Errorf("error decoding snapshot %d: %w", info.sequence, err)
+		}
+		if !asOf.IsZero() && s.Time.After(asOf) {
+			continue
+		}
+		data, _, err := decodeAeonVault(s.Vault, r.validator)
+		if err != nil {
+			return snapshot{}, models.AeonVault{}, false, fmt.Errorf("error decoding snapshot %d: %w", info.sequence, err)
+		}
+		return s, data, true, nil
+	}
+	return snapshot{}, models.AeonVault{}, false, nil
+}
+
+// listSnapshots returns all snapshots, the latest first
+func (r *EventLogVaultRepository) listSnapshots() ([]snapshotInfo, error) {
+	entries, err := os.ReadDir(filepath.Join(r.folder, snapshotFolderName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []snapshotInfo
+	for _, entry := range entries {
+		sequence, err := strconv.ParseInt(strings.TrimSuffix(entry.Name(), ".json"), 10, 64)
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") || err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshotInfo{sequence: sequence, path: filepath.Join(r.folder, snapshotFolderName, entry.Name())})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].sequence > snapshots[j].sequence
+	})
+	return snapshots, nil
+}
+
+// saveSnapshot stores the data as snapshot
+func (r *EventLogVaultRepository) saveSnapshot(s snapshot, data models.AeonVault) error {
+	var err error
+	if s.Vault, err = json.Marshal(data); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	snapshotFolder := filepath.Join(r.folder, snapshotFolderName)
+	if err = os.MkdirAll(snapshotFolder, 0755); err != nil {
+		return err
+	}
+	if err = WriteFileAtomic(filepath.Join(snapshotFolder, fmt.Sprintf(snapshotFileNameTmpl, s.Sequence)), encoded, 0644); err != nil {
+		return fmt.Errorf("error saving snapshot: %w", err)
+	}
+	return nil
+}
+
+// repairFinalLine ends the final line of the named file with a line break, so that events are appended on their own line.
+// A final line which cannot be decoded is left by a crash while appending events, it is removed instead.
+func repairFinalLine(fileName string) error {
+	file, err := os.OpenFile(fileName, os.O_RDWR, 0644)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	// Find the start of the final line by reading the file backwards
+	end, start := info.Size(), info.Size()
+	buffer := make([]byte, 4096)
+	for start > 0 {
+		size := min(start, int64(len(buffer)))
+		if _, err = file.ReadAt(buffer[:size], start-size); err != nil {
+			return err
+		}
+		if start == end && buffer[size-1] == '\n' {
+			return nil
+		}
+		if i := bytes.LastIndexByte(buffer[:size], '\n'); i >= 0 {
+			start = start - size + int64(i) + 1
+			break
+		}
+		start -= size
+	}
+	if start == end {
+		return nil
+	}
+	finalLine := make([]byte, end-start)
+	if _, err = file.ReadAt(finalLine, start); err != nil {
+		return err
+	}
+	var event eventlog.Event
+	if len(bytes.TrimSpace(finalLine)) > 0 && json.Unmarshal(finalLine, &event) == nil {
+		_, err = file.WriteAt([]byte{'\n'}, end)
+	} else {
+		err = file.Truncate(start)
+	}
+	if err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// appendFile appends the data to the named file with a single write and flushes it to disk, the file is created if it does not exist
+func appendFile(fileName string, data []byte) error {
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
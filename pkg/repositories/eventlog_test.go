@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/pkg/eventlog"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLogVaultRepository(t *testing.T) {
+	tests := []struct {
+		name              string
+		snapshotInterval  int
+		expectedSnapshots int
+	}{
+		{name: "WithoutSnapshots", snapshotInterval: 1000, expectedSnapshots: 0},
+		{name: "WithSnapshots", snapshotInterval: 2, expectedSnapshots: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folder := t.TempDir()
+			repository := NewEventLogVaultRepository(folder, validator.New(), tt.snapshotInterval)
+			unitID := uuid.New()
+			data := newRepositoryTestVault(unitID)
+			running := data.Days["2024-01-02"].Units[unitID]
+			stop := *running.Stop
+			running.Stop, running.Duration = nil, nil
+			data.Days["2024-01-02"].Units[unitID] = running
+			firstVersion, err := json.Marshal(data)
+			require.NoError(t, err)
+			data.CommandOperation = "start"
+			require.NoError(t, repository.Save(data))
+			afterFirstSave := time.Now()
+			time.Sleep(10 * time.Millisecond)
+
+			stopped := running
+			stopped.Stop, stopped.Duration = &stop, &models.AeonDuration{Duration: 8 * time.Hour}
+			data.Days["2024-01-02"].Units[unitID] = stopped
+			data.CurrentRunningUnit = nil
+			data.Days["2024-01-01"].VacationDay = true
+			data.CommandOperation = "stop"
+			require.NoError(t, repository.Save(data))
+			// Saving unchanged data appends nothing
+			require.NoError(t, repository.Save(data))
+
+			events, err := repository.Events()
+			require.NoError(t, err)
+			var types []string
+			for i, event := range events {
+				assert.Equal(t, int64(i+1), event.Sequence)
+				types = append(types, event.Type)
+			}
+			assert.Equal(t, "start", events[0].Operation)
+			assert.Equal(t, "stop", events[len(events)-1].Operation)
+			assert.Equal(t, []string{
+				eventlog.DayCreated, eventlog.DayCreated, eventlog.UnitStarted, eventlog.YearsInitialized, eventlog.SettingSet,
+				eventlog.VacationSet, eventlog.UnitStopped,
+			}, types)
+			snapshots, err := repository.listSnapshots()
+			require.NoError(t, err)
+			assert.Len(t, snapshots, tt.expectedSnapshots)
+
+			loaded, err := repository.Load()
+			require.NoError(t, err)
+			assertSameVault(t, data, loaded)
+
+			asOf, err := repository.LoadAsOf(afterFirstSave)
+			require.NoError(t, err)
+			asOfJSON, err := json.Marshal(asOf)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(firstVersion), string(asOfJSON))
+
+			_, err = repository.LoadAsOf(afterFirstSave.Add(-time.Hour))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEventLogVaultRepositoryIncompleteLog(t *testing.T) {
+	folder := t.TempDir()
+	line := `{"seq":2,"time":"2024-01-02T09:00:00Z","type":"SettingSet","key":"theme","value":"dark"}` + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(folder, eventLogFileName), []byte(line), 0644))
+
+	_, err := NewEventLogVaultRepository(folder, validator.New(), 0).Load()
+
+	assert.ErrorContains(t, err, "incomplete")
+}
+
+func TestEventLogVaultRepositoryDamagedLog(t *testing.T) {
+	tests := []struct {
+		name          string
+		damage        func(log []byte) []byte
+		expectedError string
+	}{
+		{
+			name: "IncompleteFinalLine",
+			damage: func(log []byte) []byte {
+				return append(log, `{"seq":6,"time":"2024-01-02T09:00:00Z","type":"Sett`...)
+			},
+		},
+		{
+			name: "FinalLineWithoutLineBreak",
+			damage: func(log []byte) []byte {
+				return bytes.TrimSuffix(log, []byte("\n"))
+			},
+		},
+		{
+			name: "CorruptLineInTheMiddle",
+			damage: func(log []byte) []byte {
+				return append([]byte(`{"seq":0,"time":`+"\n"), log...)
+			},
+			expectedError: "error decoding line 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folder := t.TempDir()
+			repository := NewEventLogVaultRepository(folder, validator.New(), 0)
+			data := newRepositoryTestVault(uuid.New())
+			data.CurrentRunningUnit = nil
+			require.NoError(t, repository.Save(data))
+			saved, err := repository.Events()
+			require.NoError(t, err)
+			fileName := filepath.Join(folder, eventLogFileName)
+			log, err := os.ReadFile(fileName)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(fileName, tt.damage(log), 0644))
+
+			loaded, err := repository.Load()
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assertSameVault(t, data, loaded)
+
+			// The next save continues the log on its own line
+			data.Settings["theme"] = "light"
+			data.CommandOperation = "config set"
+			require.NoError(t, repository.Save(data))
+			events, err := repository.Events()
+			require.NoError(t, err)
+			require.Len(t, events, len(saved)+1)
+			for i, event := range events {
+				assert.Equal(t, int64(i+1), event.Sequence)
+			}
+			assert.Equal(t, "config set", events[len(saved)].Operation)
+			loaded, err = repository.Load()
+			require.NoError(t, err)
+			assertSameVault(t, data, loaded)
+		})
+	}
+}
+
+// assertSameVault asserts that both vaults have the same JSON encoding
+func assertSameVault(t *testing.T, expected, actual models.AeonVault) {
+	t.Helper()
+	expectedJSON, err := json.Marshal(expected)
+	require.NoError(t, err)
+	actualJSON, err := json.Marshal(actual)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expectedJSON), string(actualJSON))
+}
@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-playground/validator/v10"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
+)
+
+// CurrentSchemaVersion is the schema version of the time tracking data written by this version.
+const CurrentSchemaVersion = 1
+
+// schemaVersionKey is the field holding the schema version of the time tracking data
+const schemaVersionKey = "schema_version"
+
+type (
+	// migration upgrades the decoded time tracking data from one schema version to the next
+	migration struct {
+		description string
+		// migrate changes the document in place and describes every change it made
+		migrate func(document map[string]any) ([]string, error)
+	}
+	// SchemaUpgrade describes the upgrade of time tracking data to the current schema version
+	SchemaUpgrade struct {
+		FromVersion int
+		ToVersion   int
+		Changes     []string
+		// BackupID is the ID of the backup of the original data, empty if nothing has been written
+		BackupID string
+	}
+)
+
+// migrations holds the migration from schema version i to version i+1 at index i.
+// Migrations work on the decoded JSON document, so they do not depend on the current models.
+var migrations = []migration{
+	{description: "add JSON names to the running unit", migrate: migrateV0ToV1},
+}
+
+// Required reports whether the data has to be upgraded.
+func (u SchemaUpgrade) Required() bool {
+	return u.FromVersion < u.ToVersion
+}
+
+// UpgradeAeonVault upgrades the time tracking data of the provided folder to the current schema version.
+// The original data is kept as backup before it is replaced, if dryRun is set, the upgrade is only described.
+// Encrypted data is decrypted with the key and stays encrypted.
+func UpgradeAeonVault(folder string, validator *validator.Validate, key *vaultcrypt.Key, dryRun bool) (SchemaUpgrade, error) {
+	fileName := filepath.Join(folder, dataFileName)
+	original, err := os.ReadFile(fileName)
+	if err != nil {
+		return SchemaUpgrade{}, err
+	}
+	decrypted, err := readVaultFile(fileName, key)
+	if err != nil {
+		return SchemaUpgrade{}, err
+	}
+	data, upgrade, err := decodeAeonVault(decrypted, validator)
+	if err != nil || !upgrade.Required() || dryRun {
+		return upgrade, err
+	}
+	backup, err := createBackup(folder, original)
+	if err != nil {
+		return upgrade, fmt.Errorf("error creating backup: %w", err)
+	}
+	upgrade.BackupID = backup.ID
+	return upgrade, SaveAeonVaultWithKey(folder, data, key)
+}
+
+// decodeAeonVault upgrades the encoded time tracking data to the current schema version, decodes and validates it
+func decodeAeonVault(encoded []byte, validator *validator.Validate) (models.AeonVault, SchemaUpgrade, error) {
+	document, err := decodeDocument(encoded)
+	if err != nil {
+		return models.AeonVault{}, SchemaUpgrade{}, err
+	}
+	upgrade, err := migrateDocument(document)
+	if err != nil {
+		return models.AeonVault{}, upgrade, err
+	}
+	if upgrade.Required() {
+		if encoded, err = json.Marshal(document); err != nil {
+			return models.AeonVault{}, upgrade, err
+		}
+	}
+	var data models.AeonVault
+	if err = json.Unmarshal(encoded, &data); err != nil {
+		return models.AeonVault{}, upgrade, err
+	}
+	if err = ValidateAeonVault(&data, validator); err != nil {
+		return models.AeonVault{}, upgrade, err
+	}
+	return data, upgrade, nil
+}
+
+// migrateDocument upgrades the decoded time tracking data in place, applying all migrations from its schema version on
+func migrateDocument(document map[string]any) (SchemaUpgrade, error) {
+	version, err := schemaVersion(document)
+	if err != nil {
+		return SchemaUpgrade{}, err
+	}
+	upgrade := SchemaUpgrade{FromVersion: version, ToVersion: CurrentSchemaVersion}
+	if version > CurrentSchemaVersion {
+		return upgrade, fmt.Errorf("%w: schema version %d, supported up to %d", aeonerrors.ErrUnsupportedSchemaVersion, version, CurrentSchemaVersion)
+	}
+	for ; version < CurrentSchemaVersion; version++ {
+		changes, err := migrations[version].migrate(document)
+		if err != nil {
+			return upgrade, fmt.Errorf("error migrating from schema version %d to %d (%s): %w", version, version+1, migrations[version].description, err)
+		}
+		document[schemaVersionKey] = version + 1
+		changes = append(changes, fmt.Sprintf("set %s to %d", schemaVersionKey, version+1))
+		for _, change := range changes {
+			upgrade.Changes = append(upgrade.Changes, fmt.Sprintf("v%d -> v%d: %s", version, version+1, change))
+		}
+	}
+	return upgrade, nil
+}
+
+// decodeDocument decodes time tracking data without a schema, keeping numbers as they are
+func decodeDocument(encoded []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var document map[string]any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	if document == nil {
+		return nil, fmt.Errorf("time tracking data is empty")
+	}
+	return document, nil
+}
+
+// schemaVersion returns the schema version of the decoded time tracking data, data without version has version 0
+func schemaVersion(document map[string]any) (int, error) {
+	var version float64
+	switch value := document[schemaVersionKey].(type) {
+	case nil:
+		return 0, nil
+	case json.Number:
+		parsed, err := value.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %s: %w", schemaVersionKey, value, err)
+		}
+		version = parsed
+	case float64:
+		version = value
+	case int:
+		version = float64(value)
+	default:
+		return 0, fmt.Errorf("invalid %s %v", schemaVersionKey, value)
+	}
+	if version < 0 || version != float64(int(version)) {
+		return 0, fmt.Errorf("invalid %s %v", schemaVersionKey, version)
+	}
+	return int(version), nil
+}
+
+// migrateV0ToV1 renames the fields of the running unit, which were written with their Go names
+func migrateV0ToV1(document map[string]any) ([]string, error) {
+	runningUnit, ok := document["current_running_unit"].(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+	var changes []string
+	for _, field := range [][2]string{{"DayKey", "day_key"}, {"UnitID", "unit_id"}} {
+		if value, ok := runningUnit[field[0]]; ok {
+			delete(runningUnit, field[0])
+			runningUnit[field[1]] = value
+			changes = append(changes, fmt.Sprintf("renamed current_running_unit.%s to current_running_unit.%s", field[0], field[1]))
+		}
+	}
+	return changes, nil
+}
+
+// backupOutdatedAeonVault keeps a backup of the data file of the provided folder if it has an older schema version, so the original survives the upgrade
+func backupOutdatedAeonVault(folder string, key *vaultcrypt.Key) error {
+	fileName := filepath.Join(folder, dataFileName)
+	original, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	decrypted, err := readVaultFile(fileName, key)
+	if err != nil {
+		return err
+	}
+	document, err := decodeDocument(decrypted)
+	if err != nil {
+		return err
+	}
+	version, err := schemaVersion(document)
+	if err != nil || version >= CurrentSchemaVersion {
+		return err
+	}
+	if _, err = createBackup(folder, original); err != nil {
+		return fmt.Errorf("error creating backup of schema version %d: %w", version, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUnitID   = "6f1c2a4e-0d3b-4b8e-9a55-1f2e3d4c5b6a"
+	v0VaultFile  = "aeon_vault_v0.json"
+	v1VaultJSON  = `{"schema_version":1,"aeon_days":{"2024-01-02":{"iso_week_number":1,"iso_week_day":2,"public_holiday":false,"vacation_day":false,"week_end":false}},"current_running_unit":{"day_key":"2024-01-02","unit_id":"` + testUnitID + `"}}`
+	futureVault  = `{"schema_version":99,"aeon_days":{}}`
+	invalidVault = `{"schema_version":"one","aeon_days":{}}`
+)
+
+func TestDecodeAeonVault(t *testing.T) {
+	v0Vault, err := os.ReadFile(filepath.Join("test_data", v0VaultFile))
+	require.NoError(t, err)
+	tests := []struct {
+		name            string
+		encoded         string
+		expectedError   error
+		expectedFrom    int
+		expectedChanges int
+	}{
+		{
+			name:            "VersionZeroIsUpgraded",
+			encoded:         string(v0Vault),
+			expectedFrom:    0,
+			expectedChanges: 3,
+		},
+		{
+			name:            "CurrentVersionIsUnchanged",
+			encoded:         v1VaultJSON,
+			expectedFrom:    1,
+			expectedChanges: 0,
+		},
+		{
+			name:          "NewerVersionIsRejected",
+			encoded:       futureVault,
+			expectedError: aeonerrors.ErrUnsupportedSchemaVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, upgrade, err := decodeAeonVault([]byte(tt.encoded), validator.New())
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedFrom, upgrade.FromVersion)
+			assert.Equal(t, CurrentSchemaVersion, upgrade.ToVersion)
+			assert.Len(t, upgrade.Changes, tt.expectedChanges)
+			assert.Equal(t, CurrentSchemaVersion, data.SchemaVersion)
+			assert.Equal(t, "2024-01-02", data.CurrentRunningUnit.DayKey)
+			assert.Equal(t, testUnitID, data.CurrentRunningUnit.UnitID.String())
+		})
+	}
+
+	_, _, err = decodeAeonVault([]byte(invalidVault), validator.New())
+	assert.Error(t, err)
+}
+
+func TestUpgradeAeonVault(t *testing.T) {
+	v0Vault, err := os.ReadFile(filepath.Join("test_data", v0VaultFile))
+	require.NoError(t, err)
+	folder := copyTestData(t, v0VaultFile)
+	fileName := filepath.Join(folder, dataFileName)
+
+	upgrade, err := UpgradeAeonVault(folder, validator.New(), nil, true)
+	assert.NoError(t, err)
+	assert.True(t, upgrade.Required())
+	assert.Empty(t, upgrade.BackupID)
+	unchanged, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, string(v0Vault), string(unchanged), "a dry run must not change the data")
+
+	upgrade, err = UpgradeAeonVault(folder, validator.New(), nil, false)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, upgrade.BackupID)
+	backup, err := FindBackup(folder, upgrade.BackupID)
+	assert.NoError(t, err)
+	original, err := os.ReadFile(backup.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, string(v0Vault), string(original), "the backup should keep the original data")
+
+	upgrade, err = UpgradeAeonVault(folder, validator.New(), nil, false)
+	assert.NoError(t, err)
+	assert.False(t, upgrade.Required())
+}
+
+func TestJSONVaultRepositorySaveBacksUpOutdatedData(t *testing.T) {
+	folder := copyTestData(t, v0VaultFile)
+	repository := NewJSONVaultRepository(folder, validator.New())
+
+	data, err := repository.Load()
+	assert.NoError(t, err)
+	data.Days["2024-01-02"].VacationDay = true
+	assert.NoError(t, repository.Save(data))
+	assert.NoError(t, repository.Save(data))
+
+	backups, err := ListBackups(folder)
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1, "only the original of the older schema version should be backed up")
+	_, err = LoadBackup(folder, "latest", validator.New(), nil)
+	assert.NoError(t, err, "backups of older schema versions should still load")
+}
@@ -2,19 +2,22 @@ package repositories
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/jame-developer/aeontrac/configuration"
 	holidays2 "github.com/jame-developer/aeontrac/pkg/holidays"
 	"github.com/jame-developer/aeontrac/pkg/models"
-	"io"
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 )
 
 const (
 	dataFileName       = "aeon_vault.json"
+	sqliteFileName     = "aeon_vault.db"
 	BackUpFileNameTmpl = "%d.bak"
 	WorkType           = "WORK"
 	CompensatoryType   = "COMPENSATORY"
@@ -22,93 +25,134 @@ const (
 
 // LoadAeonVault loads the time tracking data from the provided folder, using the provided public holdidays configuration, if the data does not exist, it creates a new one.
 func LoadAeonVault(folder string, validator *validator.Validate) (models.AeonVault, error) {
-	fileName := filepath.Join(folder, dataFileName)
-	// Check if the file exists
-	if _, err := os.Stat(fileName); err != nil {
-		return models.AeonVault{}, err
-	}
+	return LoadAeonVaultWithKey(folder, validator, nil)
+}
 
-	file, err := os.Open(fileName)
+// LoadAeonVaultWithKey loads the time tracking data from the provided folder, decrypting it with the key if it is encrypted.
+func LoadAeonVaultWithKey(folder string, validator *validator.Validate, key *vaultcrypt.Key) (models.AeonVault, error) {
+	return LoadAeonVaultFile(filepath.Join(folder, dataFileName), validator, key)
+}
+
+// LoadAeonVaultFile loads and validates the time tracking data from the provided JSON file, upgrading it to the current schema version in memory.
+// Encrypted data is decrypted with the key.
+func LoadAeonVaultFile(fileName string, validator *validator.Validate, key *vaultcrypt.Key) (models.AeonVault, error) {
+	encoded, err := readVaultFile(fileName, key)
 	if err != nil {
 		return models.AeonVault{}, err
 	}
-	defer func(file *os.File) {
-		_ = file.Close()
-	}(file)
-
-	var currentYear models.AeonVault
-	err = json.NewDecoder(file).Decode(&currentYear)
+	data, _, err := decodeAeonVault(encoded, validator)
 	if err != nil {
 		return models.AeonVault{}, err
 	}
-	validErr := validator.Struct(&currentYear)
-	if validErr != nil {
-		return models.AeonVault{}, validErr
-	}
-	for _, aeonDay := range currentYear.Days {
-		dayValidErr := validator.Struct(aeonDay)
-		if dayValidErr != nil {
-			return models.AeonVault{}, dayValidErr
-		}
-		for _, aeonUnit := range aeonDay.Units {
-			unitValidErr := validator.Struct(aeonUnit)
-			if unitValidErr != nil {
-				return models.AeonVault{}, unitValidErr
-			}
-		}
-
-	}
-	return currentYear, nil
-
+	return data, nil
 }
 
-// SaveAeonVault saves the time tracking data to the provided folder
+// SaveAeonVault saves the time tracking data to the provided folder, always in the current schema version.
+// The data is written to a temporary file first, which then replaces the data file atomically.
 func SaveAeonVault(folder string, data models.AeonVault) error {
+	return SaveAeonVaultWithKey(folder, data, nil)
+}
 
+// SaveAeonVaultWithKey saves the time tracking data to the provided folder, encrypted with the key unless it is nil.
+func SaveAeonVaultWithKey(folder string, data models.AeonVault, key *vaultcrypt.Key) error {
+	data.SchemaVersion = CurrentSchemaVersion
 	jsonData, err := json.MarshalIndent(data, "", "    ")
 	if err != nil {
 		return err
 	}
-	fileName := filepath.Join(folder, dataFileName)
-	file, err := os.Create(fileName)
-	if err != nil {
+	if jsonData, err = sealVaultData(jsonData, key); err != nil {
 		return err
 	}
-	defer func(file *os.File) {
-		_ = file.Close()
-	}(file)
 
-	_, err = io.Writer.Write(file, jsonData)
+	return WriteFileAtomic(filepath.Join(folder, dataFileName), jsonData, 0644)
+}
+
+// WriteFileAtomic writes the data to a temporary file in the folder of the named file, flushes it to disk and renames it to the named file.
+// A crash during the write leaves the named file untouched.
+func WriteFileAtomic(fileName string, data []byte, perm os.FileMode) error {
+	folder := filepath.Dir(fileName)
+	file, err := os.CreateTemp(folder, "."+filepath.Base(fileName)+".*.tmp")
 	if err != nil {
 		return err
 	}
+	tmpFileName := file.Name()
+	defer func() {
+		_ = os.Remove(tmpFileName)
+	}()
 
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpFileName, perm); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFileName, fileName); err != nil {
+		return err
+	}
+
+	// Flush the rename to disk, not every platform supports syncing a directory
+	if dir, dirErr := os.Open(folder); dirErr == nil {
+		_ = dir.Sync()
+		_ = dir.Close()
+	}
 	return nil
 }
 
 // NewAeonVault creates a new AeonVault instance with the provided year and public holidays configuration
 func NewAeonVault(year int, publicHolidaysConfig configuration.PublicHolidaysConfig) (models.AeonVault, error) {
-	holidays, err := holidays2.LoadHolidays(publicHolidaysConfig, year)
-	if err != nil {
+	vault := models.AeonVault{
+		Days: map[string]*models.AeonDay{},
+	}
+	if err := EnsureYear(&vault, year, publicHolidaysConfig); err != nil {
 		return models.AeonVault{}, err
 	}
 
-	days := map[string]*models.AeonDay{}
+	return vault, nil
+}
+
+// EnsureYear adds all days of the provided year to the vault and marks its public holidays if they are enabled, unless the year has been initialized before.
+// Days which already exist keep their units. If the public holidays cannot be loaded, the days are added anyway,
+// but the year is not marked as initialized, so that the holidays are loaded again the next time.
+func EnsureYear(a *models.AeonVault, year int, publicHolidaysConfig configuration.PublicHolidaysConfig) error {
+	if slices.Contains(a.InitializedYears, year) {
+		return nil
+	}
+	if a.Days == nil {
+		a.Days = map[string]*models.AeonDay{}
+	}
+
 	firstDayOfYear := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
 	for d := firstDayOfYear; d.Year() == year; d = d.AddDate(0, 0, 1) {
 		dayKey := d.Format(time.DateOnly)
-		newDay := NewAoenDay(d)
-		if holiday, ok := holidays[dayKey]; ok {
-			newDay.PublicHoliday = true
-			newDay.PublicHolidayName = holiday.Name
+		if _, ok := a.Days[dayKey]; !ok {
+			a.Days[dayKey] = NewAoenDay(d)
 		}
+	}
 
-		days[dayKey] = newDay
+	if publicHolidaysConfig.Enabled {
+		holidays, err := holidays2.LoadHolidays(publicHolidaysConfig, year)
+		if err != nil {
+			return fmt.Errorf("error loading public holidays of %d: %w", year, err)
+		}
+		for dayKey, holiday := range holidays {
+			if day, ok := a.Days[dayKey]; ok {
+				day.PublicHoliday = true
+				day.PublicHolidayName = holiday.Name
+			}
+		}
 	}
+	a.InitializedYears = append(a.InitializedYears, year)
+	slices.Sort(a.InitializedYears)
 
-	return models.AeonVault{
-		Days: days,
-	}, nil
+	return nil
 }
 
 // getIsoDayOfWeek returns the day of the week according to the ISO 8601 standard and the name of the day
@@ -6,13 +6,27 @@ import (
 	"github.com/jame-developer/aeontrac/configuration"
 	"github.com/jame-developer/aeontrac/pkg/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 )
 
+// copyTestData copies a file of the test_data folder into a temporary folder as its AeonVault data file and returns
+// the folder, so that tests can upgrade and save the data without changing the shared file
+func copyTestData(t *testing.T, fileName string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("test_data", fileName))
+	require.NoError(t, err)
+	folder := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(folder, dataFileName), data, 0644))
+	return folder
+}
+
 func TestNewAeonVault(t *testing.T) {
 	// Define your test holidays
 	holidaysJSON := `[{"id":"1","startDate":"2024-01-01","endDate":"2024-01-01","type":"Public","name":[{"language":"en","text":"New Year's Day"}],"nationwide":true,"subdivisions":[{"code":"DE","shortName":"Germany"}]},{"id":"2","startDate":"2024-12-25","endDate":"2024-12-25","type":"Public","name":[{"language":"en","text":"Christmas Day"}],"nationwide":true,"subdivisions":[{"code":"DE","shortName":"Germany"}]},{"id":"3","startDate":"2024-12-26","endDate":"2024-12-26","type":"Public","name":[{"language":"en","text":"Second Day of Christmas"}],"nationwide":true,"subdivisions":[{"code":"DE","shortName":"Germany"}]}]`
@@ -87,13 +101,7 @@ func TestLoadAeonVault(t *testing.T) {
 	testValidator := validator.New()
 	// Define your test holidays
 	holidaysJSON := `[{"id":"1","startDate":"2024-01-01","endDate":"2024-01-01","type":"Public","name":[{"language":"en","text":"New Year's Day"}],"nationwide":true,"subdivisions":[{"code":"DE","shortName":"Germany"}]},{"id":"2","startDate":"2024-12-25","endDate":"2024-12-25","type":"Public","name":[{"language":"en","text":"Christmas Day"}],"nationwide":true,"subdivisions":[{"code":"DE","shortName":"Germany"}]},{"id":"3","startDate":"2024-12-26","endDate":"2024-12-26","type":"Public","name":[{"language":"en","text":"Second Day of Christmas"}],"nationwide":true,"subdivisions":[{"code":"DE","shortName":"Germany"}]}]`
-	testFolder := "./test_data"
-	if _, err := os.Stat(testFolder); err != nil {
-		if mkDirErr := os.Mkdir(testFolder, 0755); mkDirErr != nil {
-			assert.NoError(t, mkDirErr)
-			return
-		}
-	}
+	testFolder := copyTestData(t, dataFileName)
 	// Create a mock server
 	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		_, err := rw.Write([]byte(holidaysJSON))
@@ -192,7 +200,6 @@ func TestLoadAeonVault(t *testing.T) {
 			}
 		})
 	}
-	_ = os.Remove(testFolder + "/aeon_vault.json")
 }
 
 func TestSaveAeonVault(t *testing.T) {
@@ -220,7 +227,7 @@ func TestSaveAeonVault(t *testing.T) {
 		},
 		{
 			name:   "SuccessfullySaved",
-			folder: "./test_data",
+			folder: copyTestData(t, dataFileName),
 			data: models.AeonVault{
 				Days: map[string]*models.AeonDay{
 					"2024-01-01": {
@@ -247,3 +254,155 @@ func TestSaveAeonVault(t *testing.T) {
 		})
 	}
 }
+
+func TestSaveVaultWithBackup(t *testing.T) {
+	tests := []struct {
+		name          string
+		newRepository func(folder string) VaultRepository
+	}{
+		{
+			name: "JSON",
+			newRepository: func(folder string) VaultRepository {
+				return NewJSONVaultRepository(folder, validator.New())
+			},
+		},
+		{
+			name: "SQLite",
+			newRepository: func(folder string) VaultRepository {
+				return NewSQLiteVaultRepository(folder, validator.New())
+			},
+		},
+		{
+			name: "EventLog",
+			newRepository: func(folder string) VaultRepository {
+				return NewEventLogVaultRepository(folder, validator.New(), 0)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folder := t.TempDir()
+			repository := tt.newRepository(folder)
+			defer func(repository VaultRepository) {
+				assert.NoError(t, repository.Close())
+			}(repository)
+			backupConfig := configuration.BackupConfig{Enabled: true, MaxCount: 2}
+			newVault := func(totalHours int) models.AeonVault {
+				return models.AeonVault{
+					Days: map[string]*models.AeonDay{
+						"2024-01-01": {IsoWeekNumber: 1, IsoWeekDay: 1, TotalHours: &models.AeonDuration{Duration: time.Duration(totalHours) * time.Hour}, Units: map[uuid.UUID]models.AeonUnit{}},
+					},
+				}
+			}
+
+			// The first save has no previous data to back up
+			assert.NoError(t, SaveVaultWithBackup(repository, folder, newVault(1), backupConfig))
+			backups, err := ListBackups(folder)
+			assert.NoError(t, err)
+			assert.Len(t, backups, 0)
+
+			// Unchanged data is neither written nor backed up
+			assert.NoError(t, SaveVaultWithBackup(repository, folder, newVault(1), backupConfig))
+			backups, err = ListBackups(folder)
+			assert.NoError(t, err)
+			assert.Len(t, backups, 0)
+
+			for totalHours := 2; totalHours <= 4; totalHours++ {
+				assert.NoError(t, SaveVaultWithBackup(repository, folder, newVault(totalHours), backupConfig))
+			}
+			backups, err = ListBackups(folder)
+			assert.NoError(t, err)
+			assert.Len(t, backups, 2, "backups exceeding the maximum count should be removed")
+
+			latest, err := LoadBackup(folder, "latest", validator.New(), nil)
+			assert.NoError(t, err)
+			assert.Equal(t, 3*time.Hour, latest.Days["2024-01-01"].TotalHours.Duration)
+			_, err = LoadBackup(folder, "0", validator.New(), nil)
+			assert.ErrorIs(t, err, ErrBackupNotFound)
+			loaded, err := repository.Load()
+			assert.NoError(t, err)
+			assert.Equal(t, 4*time.Hour, loaded.Days["2024-01-01"].TotalHours.Duration)
+
+			entries, err := os.ReadDir(folder)
+			assert.NoError(t, err)
+			for _, entry := range entries {
+				assert.NotContains(t, entry.Name(), ".tmp", "no temporary files should be left behind")
+			}
+		})
+	}
+}
+
+func TestEnsureYear(t *testing.T) {
+	holidaysJSON := `[{"id":"1","startDate":"2025-01-01","endDate":"2025-01-01","type":"Public","name":[{"language":"en","text":"New Year's Day"}],"nationwide":true,"subdivisions":[]}]`
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(holidaysJSON))
+	}))
+	defer server.Close()
+	failingServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failingServer.Close()
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	unitID := uuid.New()
+
+	tests := []struct {
+		name                 string
+		publicHolidaysConfig configuration.PublicHolidaysConfig
+		initializedYears     []int
+		expectedError        bool
+		expectedHoliday      bool
+		expectedInitialized  []int
+	}{
+		{
+			name:                 "NextYearIsCreatedWithHolidays",
+			publicHolidaysConfig: configuration.PublicHolidaysConfig{Enabled: true, Country: "DE", APIURL: server.URL},
+			initializedYears:     []int{2024},
+			expectedHoliday:      true,
+			expectedInitialized:  []int{2024, 2025},
+		},
+		{
+			name:                 "InitializedYearIsNotChanged",
+			publicHolidaysConfig: configuration.PublicHolidaysConfig{Enabled: true, Country: "DE", APIURL: server.URL},
+			initializedYears:     []int{2025},
+			expectedHoliday:      false,
+			expectedInitialized:  []int{2025},
+		},
+		{
+			name:                 "DisabledHolidaysAreNotLoaded",
+			publicHolidaysConfig: configuration.PublicHolidaysConfig{Enabled: false, APIURL: failingServer.URL},
+			expectedHoliday:      false,
+			expectedInitialized:  []int{2025},
+		},
+		{
+			name:                 "FailingHolidaysLeaveTheYearUninitialized",
+			publicHolidaysConfig: configuration.PublicHolidaysConfig{Enabled: true, Country: "DE", APIURL: failingServer.URL},
+			expectedError:        true,
+			expectedHoliday:      false,
+			expectedInitialized:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := models.AeonVault{
+				Days: map[string]*models.AeonDay{
+					"2025-01-01": {IsoWeekNumber: 1, IsoWeekDay: 3, Units: map[uuid.UUID]models.AeonUnit{unitID: {Start: &start, Type: WorkType}}},
+				},
+				InitializedYears: tt.initializedYears,
+			}
+
+			err := EnsureYear(&vault, 2025, tt.publicHolidaysConfig)
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedInitialized, vault.InitializedYears)
+			assert.Equal(t, tt.expectedHoliday, vault.Days["2025-01-01"].PublicHoliday)
+			assert.Contains(t, vault.Days["2025-01-01"].Units, unitID, "existing units must be kept")
+			if !slices.Contains(tt.initializedYears, 2025) {
+				assert.Len(t, vault.Days, 365)
+			}
+		})
+	}
+}
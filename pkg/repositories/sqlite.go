@@ -0,0 +1,548 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/pkg/eventlog"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	runningUnitSettingKey      = "current_running_unit"
+	initializedYearsSettingKey = "initialized_years"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS days (
+	day_key             TEXT PRIMARY KEY,
+	iso_week_number     INTEGER NOT NULL,
+	iso_week_day        INTEGER NOT NULL,
+	public_holiday      INTEGER NOT NULL,
+	public_holiday_name TEXT NOT NULL DEFAULT '',
+	vacation_day        INTEGER NOT NULL,
+	week_end            INTEGER NOT NULL,
+	total_hours         INTEGER,
+	overtime_hours      INTEGER
+);
+CREATE TABLE IF NOT EXISTS units (
+	id       TEXT PRIMARY KEY,
+	day_key  TEXT NOT NULL REFERENCES days (day_key) ON DELETE CASCADE,
+	start    TEXT,
+	stop     TEXT,
+	duration INTEGER,
+	type     TEXT NOT NULL,
+	comment  TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS units_day_key ON units (day_key);
+CREATE TABLE IF NOT EXISTS settings (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);`
+
+// SQLiteVaultRepository stores the time tracking data in a SQLite database, which supports changing and querying single days.
+// The running unit and the initialized years are stored as settings.
+type SQLiteVaultRepository struct {
+	path      string
+	validator *validator.Validate
+	db        *sql.DB
+}
+
+// sqlExecutor is implemented by both a database and a transaction
+type sqlExecutor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// NewSQLiteVaultRepository creates a repository storing the time tracking data in the SQLite database of the provided folder.
+// The database is created when data is saved for the first time.
+func NewSQLiteVaultRepository(folder string, validator *validator.Validate) *SQLiteVaultRepository {
+	return &SQLiteVaultRepository{path: filepath.Join(folder, sqliteFileName), validator: validator}
+}
+
+func (r *SQLiteVaultRepository) Load() (models.AeonVault, error) {
+	db, err := r.open(false)
+	if err != nil {
+		return models.AeonVault{}, err
+	}
+	data, _, err := readVault(db)
+	if err != nil {
+		return models.AeonVault{}, err
+	}
+	if err = ValidateAeonVault(&data, r.validator); err != nil {
+		return models.AeonVault{}, err
+	}
+	return data, nil
+}
+
+func (r *SQLiteVaultRepository) Save(data models.AeonVault) error {
+	return r.saveChanges(data, nil)
+}
+
+// saveChanges writes only the days, units and settings which differ from the stored data, in a single transaction.
+// If data was stored before and changes, backup is called with the stored data before the changes are committed.
+func (r *SQLiteVaultRepository) saveChanges(data models.AeonVault, backup func(previous models.AeonVault) error) error {
+	existed, err := r.Exists()
+	if err != nil {
+		return err
+	}
+	db, err := r.open(true)
+	if err != nil {
+		return err
+	}
+	return inTransaction(db, func(tx *sql.Tx) error {
+		previous, storedVersion, err := readVault(tx)
+		if err != nil {
+			return err
+		}
+		events := eventlog.Diff(&previous, &data)
+		currentVersion := strconv.Itoa(CurrentSchemaVersion)
+		if len(events) == 0 && storedVersion == currentVersion {
+			return nil
+		}
+		if existed && backup != nil {
+			if err = backup(previous); err != nil {
+				return err
+			}
+		}
+		if storedVersion != currentVersion {
+			if err = saveSetting(tx, schemaVersionKey, currentVersion); err != nil {
+				return err
+			}
+		}
+		for _, event := range events {
+			if err = saveEvent(tx, data, event); err != nil {
+				return err
+			}
+		}
+		// Starting and stopping units changes the running unit without an event of its own
+		if !sameRunningUnit(previous.CurrentRunningUnit, data.CurrentRunningUnit) {
+			return saveRunningUnit(tx, data.CurrentRunningUnit)
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteVaultRepository) Exists() (bool, error) {
+	_, err := os.Stat(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *SQLiteVaultRepository) Day(dayKey string) (*models.AeonDay, error) {
+	days, err := r.DaysInRange(dayKey, dayKey)
+	if err != nil {
+		return nil, err
+	}
+	day, ok := days[dayKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: day %s", os.ErrNotExist, dayKey)
+	}
+	return day, nil
+}
+
+func (r *SQLiteVaultRepository) DaysInRange(fromDayKey, toDayKey string) (map[string]*models.AeonDay, error) {
+	db, err := r.open(false)
+	if err != nil {
+		return nil, err
+	}
+	return queryDays(db, fromDayKey, toDayKey)
+}
+
+func (r *SQLiteVaultRepository) SaveDay(dayKey string, day *models.AeonDay) error {
+	db, err := r.open(true)
+	if err != nil {
+		return err
+	}
+	return inTransaction(db, func(tx *sql.Tx) error {
+		return saveDay(tx, dayKey, day)
+	})
+}
+
+func (r *SQLiteVaultRepository) DeleteDay(dayKey string) error {
+	db, err := r.open(false)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("DELETE FROM days WHERE day_key = ?", dayKey)
+	return err
+}
+
+func (r *SQLiteVaultRepository) SaveUnit(dayKey string, unitID uuid.UUID, unit models.AeonUnit) error {
+	db, err := r.open(false)
+	if err != nil {
+		return err
+	}
+	return saveUnit(db, dayKey, unitID, unit)
+}
+
+func (r *SQLiteVaultRepository) DeleteUnit(dayKey string, unitID uuid.UUID) error {
+	db, err := r.open(false)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("DELETE FROM units WHERE day_key = ? AND id = ?", dayKey, unitID.String())
+	return err
+}
+
+func (r *SQLiteVaultRepository) RunningUnit() (*models.AeonCurrentRunningUnit, error) {
+	db, err := r.open(false)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := querySettings(db)
+	if err != nil {
+		return nil, err
+	}
+	var data models.AeonVault
+	if err = decodeReservedSettings(settings, &data); err != nil {
+		return nil, err
+	}
+	return data.CurrentRunningUnit, nil
+}
+
+func (r *SQLiteVaultRepository) SetRunningUnit(runningUnit *models.AeonCurrentRunningUnit) error {
+	db, err := r.open(true)
+	if err != nil {
+		return err
+	}
+	return saveRunningUnit(db, runningUnit)
+}
+
+func (r *SQLiteVaultRepository) Setting(key string) (string, bool, error) {
+	db, err := r.open(false)
+	if err != nil {
+		return "", false, err
+	}
+	var value string
+	err = db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (r *SQLiteVaultRepository) SetSetting(key, value string) error {
+	db, err := r.open(true)
+	if err != nil {
+		return err
+	}
+	return saveSetting(db, key, value)
+}
+
+func (r *SQLiteVaultRepository) Close() error {
+	if r.db == nil {
+		return nil
+	}
+	err := r.db.Close()
+	r.db = nil
+	return err
+}
+
+// open opens the database and creates its schema, if create is not set, a missing database returns an error wrapping os.ErrNotExist
+func (r *SQLiteVaultRepository) open(create bool) (*sql.DB, error) {
+	if r.db != nil {
+		return r.db, nil
+	}
+	if !create {
+		if _, err := os.Stat(r.path); err != nil {
+			return nil, err
+		}
+	}
+	db, err := sql.Open("sqlite", "file:"+r.path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// A single connection keeps the pragmas and serializes all access of this process
+	db.SetMaxOpenConns(1)
+	if _, err = db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	r.db = db
+	return db, nil
+}
+
+// inTransaction runs the provided function in a transaction, which is committed if the function succeeds
+func inTransaction(db *sql.DB, run func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = run(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// queryDays returns all days from the first to the last day key including their units, empty keys select all days
+func queryDays(db sqlExecutor, fromDayKey, toDayKey string) (map[string]*models.AeonDay, error) {
+	condition, args := "", []any{}
+	if fromDayKey != "" || toDayKey != "" {
+		condition, args = " WHERE day_key BETWEEN ? AND ?", []any{fromDayKey, toDayKey}
+	}
+	rows, err := db.Query("SELECT day_key, iso_week_number, iso_week_day, public_holiday, public_holiday_name, vacation_day, week_end, total_hours, overtime_hours FROM days"+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+	days := map[string]*models.AeonDay{}
+	for rows.Next() {
+		var dayKey string
+		var totalHours, overtimeHours sql.NullInt64
+		day := &models.AeonDay{Units: map[uuid.UUID]models.AeonUnit{}}
+		if err = rows.Scan(&dayKey, &day.IsoWeekNumber, &day.IsoWeekDay, &day.PublicHoliday, &day.PublicHolidayName, &day.VacationDay, &day.WeekEnd, &totalHours, &overtimeHours); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		day.TotalHours = durationFromNullInt(totalHours)
+		day.OvertimeHours = durationFromNullInt(overtimeHours)
+		days[dayKey] = day
+	}
+	if err = errors.Join(rows.Err(), rows.Close()); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query("SELECT id, day_key, start, stop, duration, type, comment FROM units"+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+	for rows.Next() {
+		var id, dayKey string
+		var start, stop sql.NullString
+		var duration sql.NullInt64
+		var unit models.AeonUnit
+		if err = rows.Scan(&id, &dayKey, &start, &stop, &duration, &unit.Type, &unit.Comment); err != nil {
+			return nil, err
+		}
+		unitID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, err
+		}
+		if unit.Start, err = timeFromNullString(start); err != nil {
+			return nil, err
+		}
+		if unit.Stop, err = timeFromNullString(stop); err != nil {
+			return nil, err
+		}
+		unit.Duration = durationFromNullInt(duration)
+		if day, ok := days[dayKey]; ok {
+			day.Units[unitID] = unit
+		}
+	}
+	return days, rows.Err()
+}
+
+// readVault reads all time tracking data without validating it, storedVersion is the stored schema version setting
+func readVault(db sqlExecutor) (data models.AeonVault, storedVersion string, err error) {
+	days, err := queryDays(db, "", "")
+	if err != nil {
+		return models.AeonVault{}, "", err
+	}
+	data = models.AeonVault{Days: days}
+	settings, err := querySettings(db)
+	if err != nil {
+		return models.AeonVault{}, "", err
+	}
+	storedVersion = settings[schemaVersionKey]
+	if err = decodeReservedSettings(settings, &data); err != nil {
+		return models.AeonVault{}, "", err
+	}
+	if len(settings) > 0 {
+		data.Settings = settings
+	}
+	return data, storedVersion, nil
+}
+
+// querySettings returns all settings
+func querySettings(db sqlExecutor) (map[string]string, error) {
+	rows, err := db.Query("SELECT key, value FROM settings")
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+	settings := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		if err = rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		settings[key] = value
+	}
+	return settings, rows.Err()
+}
+
+// decodeReservedSettings decodes the settings holding fields of the time tracking data and removes them from the settings.
+// They are decoded as time tracking data document, so they are upgraded by the same migrations as the JSON file.
+func decodeReservedSettings(settings map[string]string, data *models.AeonVault) error {
+	document := map[string]any{}
+	for _, key := range []string{schemaVersionKey, runningUnitSettingKey, initializedYearsSettingKey} {
+		value, ok := settings[key]
+		if !ok {
+			continue
+		}
+		var decoded any
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			return fmt.Errorf("invalid setting %s: %w", key, err)
+		}
+		document[key] = decoded
+		delete(settings, key)
+	}
+	if _, err := migrateDocument(document); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, data)
+}
+
+// saveEvent writes the change of the data described by an event of eventlog.Diff, the rows are written from the data
+func saveEvent(db sqlExecutor, data models.AeonVault, event eventlog.Event) error {
+	var err error
+	switch event.Type {
+	case eventlog.DayRemoved:
+		_, err = db.Exec("DELETE FROM days WHERE day_key = ?", event.DayKey)
+	case eventlog.DayCreated, eventlog.DayUpdated, eventlog.VacationSet, eventlog.VacationCleared:
+		err = saveDayFields(db, event.DayKey, data.Days[event.DayKey])
+	case eventlog.UnitStarted, eventlog.UnitStopped, eventlog.UnitAdded, eventlog.UnitChanged:
+		err = saveUnit(db, event.DayKey, *event.UnitID, data.Days[event.DayKey].Units[*event.UnitID])
+	case eventlog.UnitRemoved:
+		_, err = db.Exec("DELETE FROM units WHERE day_key = ? AND id = ?", event.DayKey, event.UnitID.String())
+	case eventlog.RunningUnitChanged:
+		// The running unit is compared after all events, as starting and stopping units changes it too
+	case eventlog.YearsInitialized:
+		if len(data.InitializedYears) == 0 {
+			_, err = db.Exec("DELETE FROM settings WHERE key = ?", initializedYearsSettingKey)
+			break
+		}
+		var value []byte
+		if value, err = json.Marshal(data.InitializedYears); err == nil {
+			err = saveSetting(db, initializedYearsSettingKey, string(value))
+		}
+	case eventlog.SettingSet:
+		err = saveSetting(db, event.Key, event.Value)
+	case eventlog.SettingRemoved:
+		_, err = db.Exec("DELETE FROM settings WHERE key = ?", event.Key)
+	default:
+		err = fmt.Errorf("event of the unknown type %s", event.Type)
+	}
+	return err
+}
+
+// saveDay creates or replaces a day and replaces all of its units
+func saveDay(db sqlExecutor, dayKey string, day *models.AeonDay) error {
+	if err := saveDayFields(db, dayKey, day); err != nil {
+		return err
+	}
+	if _, err := db.Exec("DELETE FROM units WHERE day_key = ?", dayKey); err != nil {
+		return err
+	}
+	for unitID, unit := range day.Units {
+		if err := saveUnit(db, dayKey, unitID, unit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveDayFields creates a day or replaces its fields, keeping its units
+func saveDayFields(db sqlExecutor, dayKey string, day *models.AeonDay) error {
+	_, err := db.Exec(`INSERT INTO days (day_key, iso_week_number, iso_week_day, public_holiday, public_holiday_name, vacation_day, week_end, total_hours, overtime_hours)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (day_key) DO UPDATE SET iso_week_number = excluded.iso_week_number, iso_week_day = excluded.iso_week_day,
+			public_holiday = excluded.public_holiday, public_holiday_name = excluded.public_holiday_name, vacation_day = excluded.vacation_day,
+			week_end = excluded.week_end, total_hours = excluded.total_hours, overtime_hours = excluded.overtime_hours`,
+		dayKey, day.IsoWeekNumber, day.IsoWeekDay, day.PublicHoliday, day.PublicHolidayName, day.VacationDay, day.WeekEnd,
+		nullIntFromDuration(day.TotalHours), nullIntFromDuration(day.OvertimeHours))
+	return err
+}
+
+// saveUnit creates or replaces a unit
+func saveUnit(db sqlExecutor, dayKey string, unitID uuid.UUID, unit models.AeonUnit) error {
+	_, err := db.Exec(`INSERT INTO units (id, day_key, start, stop, duration, type, comment) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET day_key = excluded.day_key, start = excluded.start, stop = excluded.stop,
+			duration = excluded.duration, type = excluded.type, comment = excluded.comment`,
+		unitID.String(), dayKey, nullStringFromTime(unit.Start), nullStringFromTime(unit.Stop), nullIntFromDuration(unit.Duration), unit.Type, unit.Comment)
+	return err
+}
+
+// saveRunningUnit stores the running unit as setting, nil deletes it
+func saveRunningUnit(db sqlExecutor, runningUnit *models.AeonCurrentRunningUnit) error {
+	if runningUnit == nil {
+		_, err := db.Exec("DELETE FROM settings WHERE key = ?", runningUnitSettingKey)
+		return err
+	}
+	value, err := json.Marshal(runningUnit)
+	if err != nil {
+		return err
+	}
+	return saveSetting(db, runningUnitSettingKey, string(value))
+}
+
+// sameRunningUnit reports whether both running units are none or the same unit
+func sameRunningUnit(a, b *models.AeonCurrentRunningUnit) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// saveSetting creates or replaces a setting
+func saveSetting(db sqlExecutor, key, value string) error {
+	_, err := db.Exec("INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value", key, value)
+	return err
+}
+
+func nullIntFromDuration(d *models.AeonDuration) sql.NullInt64 {
+	if d == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(d.Duration), Valid: true}
+}
+
+func durationFromNullInt(value sql.NullInt64) *models.AeonDuration {
+	if !value.Valid {
+		return nil
+	}
+	return &models.AeonDuration{Duration: time.Duration(value.Int64)}
+}
+
+func nullStringFromTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.Format(time.RFC3339Nano), Valid: true}
+}
+
+func timeFromNullString(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value.String)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %s: %w", strconv.Quote(value.String), err)
+	}
+	return &t, nil
+}
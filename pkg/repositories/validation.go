@@ -0,0 +1,192 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/models"
+)
+
+// Tags of the cross-field validations, and their descriptions
+const (
+	stopNotBeforeStartTag = "stop_not_before_start"
+	stopAfterStartTag     = "stop_after_start"
+	durationMatchesTag    = "duration_matches"
+	singleRunningUnitTag  = "single_running_unit"
+	calendarMatchesTag    = "calendar_matches"
+)
+
+var validationReasons = map[string]string{
+	stopNotBeforeStartTag: "must not be before the start time",
+	stopAfterStartTag:     "must be after the start time",
+	durationMatchesTag:    "must match stop - start",
+	singleRunningUnitTag:  "must contain at most one running unit",
+	calendarMatchesTag:    "must match the date of the day",
+	"required":            "is required",
+	"required_if":         "is required",
+	"min":                 "is out of range",
+	"max":                 "is out of range",
+	"oneof":               "is not allowed",
+	"datetime":            "has an invalid format",
+}
+
+// ValidationError describes an invalid field of a day or a unit.
+type ValidationError struct {
+	DayKey string
+	UnitID *uuid.UUID
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	location := "day " + e.DayKey
+	if e.UnitID != nil {
+		location += " unit " + e.UnitID.String()
+	}
+	return fmt.Sprintf("%s: %s %s", location, e.Field, e.Reason)
+}
+
+// Unwrap makes validation errors match aeonerrors.ErrInvalidData.
+func (e *ValidationError) Unwrap() error {
+	return aeonerrors.ErrInvalidData
+}
+
+// RegisterValidations registers the cross-field validations of days, units and requests with the validator:
+// units must not stop before they start and their duration must match, days hold at most one running unit,
+// and work time requests must not stop before they start.
+func RegisterValidations(v *validator.Validate) {
+	v.RegisterStructValidation(validateUnit, models.AeonUnit{})
+	v.RegisterStructValidation(validateDay, models.AeonDay{})
+	v.RegisterStructValidation(validateWorkTimeRequest, models.WorkTimeRequest{})
+}
+
+// ValidateAeonVault validates the vault, all of its days and all of their units, including the cross-field validations.
+// Days are validated in order, the error names the day and unit of the first invalid field.
+// A nil validator skips the validation, to load inconsistent data for repairs.
+func ValidateAeonVault(data *models.AeonVault, v *validator.Validate) error {
+	if v == nil {
+		return nil
+	}
+	RegisterValidations(v)
+	if err := v.Struct(data); err != nil {
+		return err
+	}
+	dayKeys := make([]string, 0, len(data.Days))
+	for dayKey := range data.Days {
+		dayKeys = append(dayKeys, dayKey)
+	}
+	slices.Sort(dayKeys)
+	for _, dayKey := range dayKeys {
+		if err := ValidateDay(dayKey, data.Days[dayKey], v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateDay validates a day and its units, and that its calendar fields match the date of the day key.
+// The validator must have the cross-field validations registered.
+func ValidateDay(dayKey string, day *models.AeonDay, v *validator.Validate) error {
+	if day == nil {
+		return &ValidationError{DayKey: dayKey, Field: "day", Reason: "must not be null"}
+	}
+	if err := validationError(v.Struct(day), dayKey, nil); err != nil {
+		return err
+	}
+	date, err := time.Parse(time.DateOnly, dayKey)
+	if err != nil {
+		return &ValidationError{DayKey: dayKey, Field: "key", Reason: "must be a date in the format YYYY-MM-DD"}
+	}
+	expected := NewAoenDay(date)
+	switch {
+	case day.IsoWeekNumber != expected.IsoWeekNumber:
+		return &ValidationError{DayKey: dayKey, Field: "IsoWeekNumber", Reason: validationReasons[calendarMatchesTag]}
+	case day.IsoWeekDay != expected.IsoWeekDay:
+		return &ValidationError{DayKey: dayKey, Field: "IsoWeekDay", Reason: validationReasons[calendarMatchesTag]}
+	}
+
+	unitIDs := make([]uuid.UUID, 0, len(day.Units))
+	for unitID := range day.Units {
+		unitIDs = append(unitIDs, unitID)
+	}
+	slices.SortFunc(unitIDs, func(a, b uuid.UUID) int {
+		return slices.Compare(a[:], b[:])
+	})
+	for _, unitID := range unitIDs {
+		if err = validationError(v.Struct(day.Units[unitID]), dayKey, &unitID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateWorkTimeRequest validates a work time request, the error names the day of the request.
+// The validator must have the cross-field validations registered.
+func ValidateWorkTimeRequest(request models.WorkTimeRequest, v *validator.Validate) error {
+	return validationError(v.Struct(request), request.Date, nil)
+}
+
+// validationError converts the first error of a validator into a ValidationError of the day and unit, other errors are returned unchanged.
+func validationError(err error, dayKey string, unitID *uuid.UUID) error {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) || len(fieldErrors) == 0 {
+		return err
+	}
+	return &ValidationError{DayKey: dayKey, UnitID: unitID, Field: fieldErrors[0].Field(), Reason: reason(fieldErrors[0])}
+}
+
+// reason describes why a field is invalid
+func reason(fieldError validator.FieldError) string {
+	if description, ok := validationReasons[fieldError.Tag()]; ok {
+		return description
+	}
+	return "failed the " + fieldError.Tag() + " validation"
+}
+
+func validateUnit(sl validator.StructLevel) {
+	unit := sl.Current().Interface().(models.AeonUnit)
+	if unit.Stop == nil {
+		if unit.Duration != nil {
+			sl.ReportError(unit.Duration, "Duration", "Duration", durationMatchesTag, "")
+		}
+		return
+	}
+	if unit.Start == nil {
+		sl.ReportError(unit.Start, "Start", "Start", "required", "")
+		return
+	}
+	if unit.Stop.Before(*unit.Start) {
+		sl.ReportError(unit.Stop, "Stop", "Stop", stopNotBeforeStartTag, "")
+		return
+	}
+	if unit.Duration == nil || unit.Duration.Duration != unit.Stop.Sub(*unit.Start) {
+		sl.ReportError(unit.Duration, "Duration", "Duration", durationMatchesTag, "")
+	}
+}
+
+func validateDay(sl validator.StructLevel) {
+	day := sl.Current().Interface().(models.AeonDay)
+	running := 0
+	for _, unit := range day.Units {
+		if unit.Stop == nil {
+			running++
+		}
+	}
+	if running > 1 {
+		sl.ReportError(day.Units, "Units", "Units", singleRunningUnitTag, "")
+	}
+}
+
+func validateWorkTimeRequest(sl validator.StructLevel) {
+	request := sl.Current().Interface().(models.WorkTimeRequest)
+	start, startErr := time.Parse(time.RFC3339, request.Start)
+	stop, stopErr := time.Parse(time.RFC3339, request.Stop)
+	if startErr == nil && stopErr == nil && !stop.After(start) {
+		sl.ReportError(request.Stop, "Stop", "Stop", stopAfterStartTag, "")
+	}
+}
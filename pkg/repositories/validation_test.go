@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAeonVault(t *testing.T) {
+	unitID := uuid.MustParse("11111111-1111-4111-8111-111111111111")
+	otherID := uuid.MustParse("22222222-2222-4222-8222-222222222222")
+	start := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+	completed := models.AeonUnit{Start: &start, Stop: &stop, Duration: &models.AeonDuration{Duration: time.Hour}, Type: WorkType}
+
+	tests := []struct {
+		name          string
+		dayKey        string
+		modify        func(day *models.AeonDay)
+		null          bool
+		expectedError string
+	}{
+		{
+			name:   "Valid",
+			dayKey: "2024-01-02",
+			modify: func(day *models.AeonDay) {},
+		},
+		{
+			name:   "StopBeforeStart",
+			dayKey: "2024-01-02",
+			modify: func(day *models.AeonDay) {
+				before := start.Add(-time.Hour)
+				day.Units[unitID] = models.AeonUnit{Start: &start, Stop: &before, Duration: &models.AeonDuration{Duration: -time.Hour}, Type: WorkType}
+			},
+			expectedError: "day 2024-01-02 unit 11111111-1111-4111-8111-111111111111: Stop must not be before the start time",
+		},
+		{
+			name:   "DurationMismatch",
+			dayKey: "2024-01-02",
+			modify: func(day *models.AeonDay) {
+				unit := completed
+				unit.Duration = &models.AeonDuration{Duration: 2 * time.Hour}
+				day.Units[unitID] = unit
+			},
+			expectedError: "day 2024-01-02 unit 11111111-1111-4111-8111-111111111111: Duration must match stop - start",
+		},
+		{
+			name:   "DurationOfRunningUnit",
+			dayKey: "2024-01-02",
+			modify: func(day *models.AeonDay) {
+				day.Units[unitID] = models.AeonUnit{Start: &start, Duration: &models.AeonDuration{Duration: time.Hour}, Type: WorkType}
+			},
+			expectedError: "day 2024-01-02 unit 11111111-1111-4111-8111-111111111111: Duration must match stop - start",
+		},
+		{
+			name:   "TwoRunningUnits",
+			dayKey: "2024-01-02",
+			modify: func(day *models.AeonDay) {
+				later := stop.Add(time.Hour)
+				day.Units[unitID] = models.AeonUnit{Start: &start, Type: WorkType}
+				day.Units[otherID] = models.AeonUnit{Start: &later, Type: WorkType}
+			},
+			expectedError: "day 2024-01-02: Units must contain at most one running unit",
+		},
+		{
+			name:          "IsoWeekNumberMismatch",
+			dayKey:        "2024-01-09",
+			modify:        func(day *models.AeonDay) {},
+			expectedError: "day 2024-01-09: IsoWeekNumber must match the date of the day",
+		},
+		{
+			name:   "InvalidType",
+			dayKey: "2024-01-02",
+			modify: func(day *models.AeonDay) {
+				unit := completed
+				unit.Type = "BREAK"
+				day.Units[unitID] = unit
+			},
+			expectedError: "day 2024-01-02 unit 11111111-1111-4111-8111-111111111111: Type is not allowed",
+		},
+		{
+			name:          "NullDay",
+			dayKey:        "2024-01-02",
+			modify:        func(day *models.AeonDay) {},
+			null:          true,
+			expectedError: "day 2024-01-02: day must not be null",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day := NewAoenDay(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+			day.Units[unitID] = completed
+			tt.modify(day)
+			data := &models.AeonVault{Days: map[string]*models.AeonDay{tt.dayKey: day}}
+			if tt.null {
+				data.Days[tt.dayKey] = nil
+			}
+
+			err := ValidateAeonVault(data, validator.New())
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.expectedError, err.Error())
+			assert.ErrorIs(t, err, aeonerrors.ErrInvalidData)
+			assert.NoError(t, ValidateAeonVault(data, nil), "a nil validator skips the validation")
+		})
+	}
+}
+
+func TestValidateWorkTimeRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		request       models.WorkTimeRequest
+		expectedError string
+	}{
+		{
+			name:    "Valid",
+			request: models.WorkTimeRequest{Date: "2024-01-02", Start: "2024-01-02T09:00:00Z", Stop: "2024-01-02T10:00:00Z"},
+		},
+		{
+			name:          "StopBeforeStart",
+			request:       models.WorkTimeRequest{Date: "2024-01-02", Start: "2024-01-02T10:00:00Z", Stop: "2024-01-02T09:00:00Z"},
+			expectedError: "day 2024-01-02: Stop must be after the start time",
+		},
+		{
+			name:          "InvalidDate",
+			request:       models.WorkTimeRequest{Date: "02.01.2024", Start: "2024-01-02T09:00:00Z", Stop: "2024-01-02T10:00:00Z"},
+			expectedError: "day 02.01.2024: Date has an invalid format",
+		},
+		{
+			name:          "MissingStart",
+			request:       models.WorkTimeRequest{Date: "2024-01-02", Stop: "2024-01-02T10:00:00Z"},
+			expectedError: "day 2024-01-02: Start is required",
+		},
+	}
+	v := validator.New()
+	RegisterValidations(v)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWorkTimeRequest(tt.request, v)
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.expectedError)
+		})
+	}
+}
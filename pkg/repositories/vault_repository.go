@@ -0,0 +1,322 @@
+package repositories
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/configuration"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
+)
+
+const (
+	// JSONBackend stores the time tracking data in a single JSON file
+	JSONBackend = "json"
+	// SQLiteBackend stores the time tracking data in a SQLite database
+	SQLiteBackend = "sqlite"
+	// EventLogBackend stores the time tracking data as an append-only log of events with snapshots
+	EventLogBackend = "eventlog"
+)
+
+// VaultRepository stores the time tracking data, covering days, units, the running state and settings.
+// Methods reading data return an error wrapping os.ErrNotExist if no data has been stored yet.
+type VaultRepository interface {
+	// Load loads and validates all time tracking data.
+	Load() (models.AeonVault, error)
+	// Save replaces all time tracking data, backends storing days separately write only what changed.
+	Save(data models.AeonVault) error
+	// Exists reports whether time tracking data has been stored.
+	Exists() (bool, error)
+	// Day returns the day with the provided key, including its units.
+	Day(dayKey string) (*models.AeonDay, error)
+	// DaysInRange returns all days from the first to the last day key, both inclusive.
+	DaysInRange(fromDayKey, toDayKey string) (map[string]*models.AeonDay, error)
+	// SaveDay creates or replaces the day with the provided key, including its units.
+	SaveDay(dayKey string, day *models.AeonDay) error
+	// DeleteDay deletes the day with the provided key, including its units.
+	DeleteDay(dayKey string) error
+	// SaveUnit creates or replaces a unit of an existing day.
+	SaveUnit(dayKey string, unitID uuid.UUID, unit models.AeonUnit) error
+	// DeleteUnit deletes a unit of a day.
+	DeleteUnit(dayKey string, unitID uuid.UUID) error
+	// RunningUnit returns the currently running unit, nil if no unit is running.
+	RunningUnit() (*models.AeonCurrentRunningUnit, error)
+	// SetRunningUnit sets the currently running unit, nil if no unit is running.
+	SetRunningUnit(runningUnit *models.AeonCurrentRunningUnit) error
+	// Setting returns the value of a setting and whether it is set.
+	Setting(key string) (string, bool, error)
+	// SetSetting sets the value of a setting.
+	SetSetting(key, value string) error
+	// Close releases all resources held by the repository.
+	Close() error
+}
+
+// NewVaultRepository creates the repository for the storage backend selected in the provided storage configuration.
+// If a key is provided, the time tracking data is encrypted with it, which is only supported by the JSON backend.
+func NewVaultRepository(folder string, storageConfig configuration.StorageConfig, validator *validator.Validate, key *vaultcrypt.Key) (VaultRepository, error) {
+	switch storageConfig.Backend {
+	case JSONBackend, "":
+		return NewEncryptedJSONVaultRepository(folder, validator, key), nil
+	case SQLiteBackend:
+		if key != nil {
+			return nil, fmt.Errorf("the %s backend does not support encryption", SQLiteBackend)
+		}
+		return NewSQLiteVaultRepository(folder, validator), nil
+	case EventLogBackend:
+		if key != nil {
+			return nil, fmt.Errorf("the %s backend does not support encryption", EventLogBackend)
+		}
+		return NewEventLogVaultRepository(folder, validator, storageConfig.SnapshotInterval), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", storageConfig.Backend)
+	}
+}
+
+// SaveVaultWithBackup saves the time tracking data to the repository, after keeping a backup of the previous data in the backup folder.
+// Old backups are removed according to the provided backup configuration. If the data did not change, nothing is written.
+func SaveVaultWithBackup(repository VaultRepository, folder string, data models.AeonVault, backupConfig configuration.BackupConfig) error {
+	// Data is always saved in the current schema version
+	data.SchemaVersion = CurrentSchemaVersion
+	if saver, ok := repository.(changeSaver); ok {
+		// The repository compares the data with the stored data it reads anyway, which is only encoded for a backup
+		return saver.saveChanges(data, func(previous models.AeonVault) error {
+			if !backupConfig.Enabled {
+				return nil
+			}
+			previousData, err := json.MarshalIndent(previous, "", "    ")
+			if err != nil {
+				return err
+			}
+			return backupVaultData(repository, folder, previousData, backupConfig)
+		})
+	}
+
+	jsonData, err := json.MarshalIndent(data, "", "    ")
+	if err != nil {
+		return err
+	}
+	previous, err := repository.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return repository.Save(data)
+	}
+	if err != nil {
+		return err
+	}
+	previousData, err := json.MarshalIndent(previous, "", "    ")
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(previousData, jsonData) {
+		return nil
+	}
+	if backupConfig.Enabled {
+		if err = backupVaultData(repository, folder, previousData, backupConfig); err != nil {
+			return err
+		}
+	}
+
+	return repository.Save(data)
+}
+
+// backupVaultData keeps the previous data as backup, sealed by the repository if it encrypts backups, and removes old backups
+func backupVaultData(repository VaultRepository, folder string, previousData []byte, backupConfig configuration.BackupConfig) error {
+	backupData := previousData
+	if sealer, ok := repository.(backupSealer); ok {
+		var err error
+		if backupData, err = sealer.sealBackup(previousData); err != nil {
+			return err
+		}
+	}
+	if _, err := createBackup(folder, backupData); err != nil {
+		return fmt.Errorf("error creating backup: %w", err)
+	}
+	if err := PruneBackups(folder, backupConfig); err != nil {
+		return fmt.Errorf("error removing old backups: %w", err)
+	}
+	return nil
+}
+
+// changeSaver is implemented by repositories which write only the changes of the data to the stored data. backup is
+// called with the stored data before changes are written, if data was stored before.
+type changeSaver interface {
+	saveChanges(data models.AeonVault, backup func(previous models.AeonVault) error) error
+}
+
+// backupSealer is implemented by repositories which encrypt the backups of their data
+type backupSealer interface {
+	sealBackup(data []byte) ([]byte, error)
+}
+
+// JSONVaultRepository stores the time tracking data in a single JSON file, every change rewrites the whole file.
+type JSONVaultRepository struct {
+	folder    string
+	validator *validator.Validate
+	key       *vaultcrypt.Key
+	loaded    *models.AeonVault
+}
+
+// NewJSONVaultRepository creates a repository storing the time tracking data in the JSON file of the provided folder.
+func NewJSONVaultRepository(folder string, validator *validator.Validate) *JSONVaultRepository {
+	return &JSONVaultRepository{folder: folder, validator: validator}
+}
+
+// NewEncryptedJSONVaultRepository creates a repository storing the time tracking data in the JSON file of the provided folder, encrypted with the key.
+// Without key the data is stored in plaintext.
+func NewEncryptedJSONVaultRepository(folder string, validator *validator.Validate, key *vaultcrypt.Key) *JSONVaultRepository {
+	return &JSONVaultRepository{folder: folder, validator: validator, key: key}
+}
+
+func (r *JSONVaultRepository) Load() (models.AeonVault, error) {
+	return LoadAeonVaultWithKey(r.folder, r.validator, r.key)
+}
+
+func (r *JSONVaultRepository) Save(data models.AeonVault) error {
+	r.loaded = nil
+	if err := backupOutdatedAeonVault(r.folder, r.key); err != nil {
+		return err
+	}
+	return SaveAeonVaultWithKey(r.folder, data, r.key)
+}
+
+func (r *JSONVaultRepository) Exists() (bool, error) {
+	_, err := os.Stat(filepath.Join(r.folder, dataFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *JSONVaultRepository) Day(dayKey string) (*models.AeonDay, error) {
+	data, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	day, ok := data.Days[dayKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: day %s", os.ErrNotExist, dayKey)
+	}
+	return day, nil
+}
+
+func (r *JSONVaultRepository) DaysInRange(fromDayKey, toDayKey string) (map[string]*models.AeonDay, error) {
+	data, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	days := map[string]*models.AeonDay{}
+	for dayKey, day := range data.Days {
+		if dayKey >= fromDayKey && dayKey <= toDayKey {
+			days[dayKey] = day
+		}
+	}
+	return days, nil
+}
+
+func (r *JSONVaultRepository) SaveDay(dayKey string, day *models.AeonDay) error {
+	return r.update(func(data *models.AeonVault) error {
+		data.Days[dayKey] = day
+		return nil
+	})
+}
+
+func (r *JSONVaultRepository) DeleteDay(dayKey string) error {
+	return r.update(func(data *models.AeonVault) error {
+		delete(data.Days, dayKey)
+		return nil
+	})
+}
+
+func (r *JSONVaultRepository) SaveUnit(dayKey string, unitID uuid.UUID, unit models.AeonUnit) error {
+	return r.update(func(data *models.AeonVault) error {
+		day, ok := data.Days[dayKey]
+		if !ok {
+			return fmt.Errorf("%w: day %s", os.ErrNotExist, dayKey)
+		}
+		if day.Units == nil {
+			day.Units = map[uuid.UUID]models.AeonUnit{}
+		}
+		day.Units[unitID] = unit
+		return nil
+	})
+}
+
+func (r *JSONVaultRepository) DeleteUnit(dayKey string, unitID uuid.UUID) error {
+	return r.update(func(data *models.AeonVault) error {
+		if day, ok := data.Days[dayKey]; ok {
+			delete(day.Units, unitID)
+		}
+		return nil
+	})
+}
+
+func (r *JSONVaultRepository) RunningUnit() (*models.AeonCurrentRunningUnit, error) {
+	data, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	return data.CurrentRunningUnit, nil
+}
+
+func (r *JSONVaultRepository) SetRunningUnit(runningUnit *models.AeonCurrentRunningUnit) error {
+	return r.update(func(data *models.AeonVault) error {
+		data.CurrentRunningUnit = runningUnit
+		return nil
+	})
+}
+
+func (r *JSONVaultRepository) Setting(key string) (string, bool, error) {
+	data, err := r.read()
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := data.Settings[key]
+	return value, ok, nil
+}
+
+func (r *JSONVaultRepository) SetSetting(key, value string) error {
+	return r.update(func(data *models.AeonVault) error {
+		if data.Settings == nil {
+			data.Settings = map[string]string{}
+		}
+		data.Settings[key] = value
+		return nil
+	})
+}
+
+func (r *JSONVaultRepository) Close() error {
+	return nil
+}
+
+func (r *JSONVaultRepository) sealBackup(data []byte) ([]byte, error) {
+	return sealVaultData(data, r.key)
+}
+
+// read loads the whole file once for the reading methods, saving discards it
+func (r *JSONVaultRepository) read() (models.AeonVault, error) {
+	if r.loaded == nil {
+		data, err := r.Load()
+		if err != nil {
+			return models.AeonVault{}, err
+		}
+		r.loaded = &data
+	}
+	return *r.loaded, nil
+}
+
+// update loads the whole file, applies the change and saves the whole file again
+func (r *JSONVaultRepository) update(change func(data *models.AeonVault) error) error {
+	data, err := r.Load()
+	if err != nil {
+		return err
+	}
+	if err = change(&data); err != nil {
+		return err
+	}
+	return r.Save(data)
+}
@@ -0,0 +1,313 @@
+package repositories
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jame-developer/aeontrac/configuration"
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/jame-developer/aeontrac/pkg/models"
+	"github.com/jame-developer/aeontrac/pkg/vaultcrypt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRepositoryTestVault(unitID uuid.UUID) models.AeonVault {
+	start := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	stop := start.Add(8 * time.Hour)
+	return models.AeonVault{
+		SchemaVersion: CurrentSchemaVersion,
+		Days: map[string]*models.AeonDay{
+			"2024-01-01": {IsoWeekNumber: 1, IsoWeekDay: 1, PublicHoliday: true, PublicHolidayName: "New Year's Day", Units: map[uuid.UUID]models.AeonUnit{}},
+			"2024-01-02": {
+				IsoWeekNumber: 1,
+				IsoWeekDay:    2,
+				TotalHours:    &models.AeonDuration{Duration: 8 * time.Hour},
+				Units: map[uuid.UUID]models.AeonUnit{
+					unitID: {Start: &start, Stop: &stop, Duration: &models.AeonDuration{Duration: 8 * time.Hour}, Type: WorkType, Comment: "planning"},
+				},
+			},
+		},
+		CurrentRunningUnit: &models.AeonCurrentRunningUnit{DayKey: "2024-01-02", UnitID: unitID},
+		InitializedYears:   []int{2024},
+		Settings:           map[string]string{"theme": "dark"},
+	}
+}
+
+func TestVaultRepository(t *testing.T) {
+	tests := []struct {
+		name          string
+		newRepository func(folder string) VaultRepository
+	}{
+		{
+			name: "JSON",
+			newRepository: func(folder string) VaultRepository {
+				return NewJSONVaultRepository(folder, validator.New())
+			},
+		},
+		{
+			name: "SQLite",
+			newRepository: func(folder string) VaultRepository {
+				return NewSQLiteVaultRepository(folder, validator.New())
+			},
+		},
+		{
+			name: "EventLog",
+			newRepository: func(folder string) VaultRepository {
+				return NewEventLogVaultRepository(folder, validator.New(), 3)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := tt.newRepository(t.TempDir())
+			defer func(repository VaultRepository) {
+				assert.NoError(t, repository.Close())
+			}(repository)
+
+			exists, err := repository.Exists()
+			assert.NoError(t, err)
+			assert.False(t, exists)
+			_, err = repository.Load()
+			assert.ErrorIs(t, err, os.ErrNotExist)
+
+			unitID := uuid.New()
+			data := newRepositoryTestVault(unitID)
+			assert.NoError(t, repository.Save(data))
+			exists, err = repository.Exists()
+			assert.NoError(t, err)
+			assert.True(t, exists)
+			loaded, err := repository.Load()
+			assert.NoError(t, err)
+			expected, err := json.Marshal(data)
+			assert.NoError(t, err)
+			actual, err := json.Marshal(loaded)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(actual))
+
+			days, err := repository.DaysInRange("2024-01-02", "2024-01-31")
+			assert.NoError(t, err)
+			assert.Len(t, days, 1)
+			assert.Contains(t, days, "2024-01-02")
+
+			unit := data.Days["2024-01-02"].Units[unitID]
+			unit.Comment = "review"
+			assert.NoError(t, repository.SaveUnit("2024-01-02", unitID, unit))
+			day, err := repository.Day("2024-01-02")
+			assert.NoError(t, err)
+			assert.Equal(t, "review", day.Units[unitID].Comment)
+
+			assert.NoError(t, repository.DeleteUnit("2024-01-02", unitID))
+			day, err = repository.Day("2024-01-02")
+			assert.NoError(t, err)
+			assert.Empty(t, day.Units)
+
+			assert.NoError(t, repository.SaveDay("2024-01-03", &models.AeonDay{IsoWeekNumber: 1, IsoWeekDay: 3, Units: map[uuid.UUID]models.AeonUnit{}}))
+			assert.NoError(t, repository.DeleteDay("2024-01-01"))
+			_, err = repository.Day("2024-01-01")
+			assert.ErrorIs(t, err, os.ErrNotExist)
+			_, err = repository.Day("2024-01-03")
+			assert.NoError(t, err)
+
+			assert.NoError(t, repository.SetRunningUnit(nil))
+			runningUnit, err := repository.RunningUnit()
+			assert.NoError(t, err)
+			assert.Nil(t, runningUnit)
+
+			assert.NoError(t, repository.SetSetting("theme", "light"))
+			value, ok, err := repository.Setting("theme")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, "light", value)
+			_, ok, err = repository.Setting("missing")
+			assert.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestSQLiteVaultRepositorySavesOnlyChanges(t *testing.T) {
+	repository := NewSQLiteVaultRepository(t.TempDir(), validator.New())
+	defer func(repository VaultRepository) {
+		assert.NoError(t, repository.Close())
+	}(repository)
+	unitID := uuid.New()
+	data := newRepositoryTestVault(unitID)
+	require.NoError(t, repository.Save(data))
+	changedRows := func() int {
+		var changes int
+		require.NoError(t, repository.db.QueryRow("SELECT total_changes()").Scan(&changes))
+		return changes
+	}
+
+	before := changedRows()
+	require.NoError(t, repository.Save(data))
+	assert.Equal(t, before, changedRows(), "unchanged data must not be written")
+
+	unit := data.Days["2024-01-02"].Units[unitID]
+	unit.Comment = "review"
+	data.Days["2024-01-02"].Units[unitID] = unit
+	delete(data.Days, "2024-01-01")
+	require.NoError(t, repository.Save(data))
+	assert.Equal(t, before+2, changedRows(), "only the changed unit and the removed day must be written")
+	loaded, err := repository.Load()
+	require.NoError(t, err)
+	assertSameVault(t, data, loaded)
+
+	// Starting and stopping a unit changes the running unit without an event of its own
+	data.CurrentRunningUnit = nil
+	require.NoError(t, repository.Save(data))
+	startedID := uuid.New()
+	start := time.Date(2024, 1, 2, 18, 0, 0, 0, time.UTC)
+	data.Days["2024-01-02"].Units[startedID] = models.AeonUnit{Start: &start, Type: WorkType}
+	data.CurrentRunningUnit = &models.AeonCurrentRunningUnit{DayKey: "2024-01-02", UnitID: startedID}
+	require.NoError(t, repository.Save(data))
+	runningUnit, err := repository.RunningUnit()
+	require.NoError(t, err)
+	assert.Equal(t, data.CurrentRunningUnit, runningUnit)
+	stop := start.Add(time.Hour)
+	data.Days["2024-01-02"].Units[startedID] = models.AeonUnit{Start: &start, Stop: &stop, Duration: &models.AeonDuration{Duration: time.Hour}, Type: WorkType}
+	data.CurrentRunningUnit = nil
+	require.NoError(t, repository.Save(data))
+	runningUnit, err = repository.RunningUnit()
+	require.NoError(t, err)
+	assert.Nil(t, runningUnit)
+	loaded, err = repository.Load()
+	require.NoError(t, err)
+	assertSameVault(t, data, loaded)
+}
+
+func TestEncryptedJSONVaultRepository(t *testing.T) {
+	folder := t.TempDir()
+	key, err := vaultcrypt.NewPassphraseKey("secret")
+	assert.NoError(t, err)
+	repository := NewEncryptedJSONVaultRepository(folder, validator.New(), key)
+	backupConfig := configuration.BackupConfig{Enabled: true}
+
+	data := newRepositoryTestVault(uuid.New())
+	assert.NoError(t, SaveVaultWithBackup(repository, folder, data, backupConfig))
+	data.Days["2024-01-02"].VacationDay = true
+	assert.NoError(t, SaveVaultWithBackup(repository, folder, data, backupConfig))
+
+	encrypted, err := IsAeonVaultEncrypted(folder)
+	assert.NoError(t, err)
+	assert.True(t, encrypted)
+	_, err = LoadAeonVault(folder, validator.New())
+	assert.ErrorIs(t, err, aeonerrors.ErrVaultLocked)
+	loaded, err := repository.Load()
+	assert.NoError(t, err)
+	assert.True(t, loaded.Days["2024-01-02"].VacationDay)
+
+	backups, err := ListBackups(folder)
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+	backupData, err := os.ReadFile(backups[0].Path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(backupData), "planning", "backups must not contain plaintext")
+	_, err = LoadBackup(folder, "latest", validator.New(), key)
+	assert.NoError(t, err)
+
+	// Decrypting rewrites the data and all backups in plaintext
+	assert.NoError(t, ReencryptAeonVault(folder, key, nil))
+	encrypted, err = IsAeonVaultEncrypted(folder)
+	assert.NoError(t, err)
+	assert.False(t, encrypted)
+	_, err = LoadBackup(folder, "latest", validator.New(), nil)
+	assert.NoError(t, err)
+	_, err = NewVaultRepository(folder, configuration.StorageConfig{Backend: SQLiteBackend}, validator.New(), key)
+	assert.Error(t, err, "the SQLite backend does not support encryption")
+}
+
+func TestReencryptAeonVault(t *testing.T) {
+	oldKey, err := vaultcrypt.NewPassphraseKey("old")
+	require.NoError(t, err)
+	newKey, err := vaultcrypt.NewPassphraseKey("new")
+	require.NoError(t, err)
+	otherKey, err := vaultcrypt.NewPassphraseKey("other")
+	require.NoError(t, err)
+	tests := []struct {
+		name          string
+		oldKey        *vaultcrypt.Key
+		newKey        *vaultcrypt.Key
+		damage        func(t *testing.T, backupPath string)
+		expectedError string
+	}{
+		{name: "Rekey", oldKey: oldKey, newKey: newKey},
+		{name: "Decrypt", oldKey: oldKey},
+		{name: "WrongKeyChangesNothing", oldKey: otherKey, newKey: newKey, expectedError: "error decrypting aeon_vault.json"},
+		{
+			name:   "DamagedBackupChangesNothing",
+			oldKey: oldKey,
+			newKey: newKey,
+			damage: func(t *testing.T, backupPath string) {
+				sealed, err := vaultcrypt.Encrypt(otherKey, []byte("{}"))
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(backupPath, sealed, 0600))
+			},
+			expectedError: "error decrypting",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folder := t.TempDir()
+			repository := NewEncryptedJSONVaultRepository(folder, validator.New(), oldKey)
+			backupConfig := configuration.BackupConfig{Enabled: true}
+			data := newRepositoryTestVault(uuid.New())
+			require.NoError(t, SaveVaultWithBackup(repository, folder, data, backupConfig))
+			data.Days["2024-01-02"].VacationDay = true
+			require.NoError(t, SaveVaultWithBackup(repository, folder, data, backupConfig))
+			journalPath := filepath.Join(folder, "aeon_journal.json")
+			sealed, err := vaultcrypt.Encrypt(oldKey, []byte("[]"))
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(journalPath, sealed, 0600))
+			backups, err := ListBackups(folder)
+			require.NoError(t, err)
+			require.Len(t, backups, 1)
+			if tt.damage != nil {
+				tt.damage(t, backups[0].Path)
+			}
+			fileNames := []string{filepath.Join(folder, dataFileName), backups[0].Path, journalPath}
+			before, perms := make(map[string][]byte, len(fileNames)), make(map[string]os.FileMode, len(fileNames))
+			for _, fileName := range fileNames {
+				before[fileName], err = os.ReadFile(fileName)
+				require.NoError(t, err)
+				info, err := os.Stat(fileName)
+				require.NoError(t, err)
+				perms[fileName] = info.Mode().Perm()
+			}
+
+			err = ReencryptAeonVault(folder, tt.oldKey, tt.newKey, journalPath, filepath.Join(folder, "missing.json"))
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				for _, fileName := range fileNames {
+					after, err := os.ReadFile(fileName)
+					require.NoError(t, err)
+					assert.Equal(t, before[fileName], after, "%s must not be changed", filepath.Base(fileName))
+				}
+				return
+			}
+			require.NoError(t, err)
+			for _, fileName := range fileNames {
+				info, err := os.Stat(fileName)
+				require.NoError(t, err)
+				assert.Equal(t, perms[fileName], info.Mode().Perm())
+				plain, err := readVaultFile(fileName, tt.newKey)
+				require.NoError(t, err, filepath.Base(fileName))
+				encrypted, err := IsAeonVaultFileEncrypted(fileName)
+				require.NoError(t, err)
+				assert.Equal(t, tt.newKey != nil, encrypted)
+				if fileName != journalPath {
+					assert.Contains(t, string(plain), "planning")
+				}
+			}
+			assert.NoFileExists(t, filepath.Join(folder, "missing.json"))
+		})
+	}
+}
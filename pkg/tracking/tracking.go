@@ -197,3 +197,33 @@ func calculateDayCompensatoryDurations(currentDay *models.AeonDay, newUnit *mode
 
 	return totalHours, overtimeHours
 }
+
+// RecalculateDay recalculates the total and overtime hours of a day from the durations of its completed units.
+// Work units add to the total hours, compensatory units subtract from them.
+func RecalculateDay(day *models.AeonDay, workingHoursConfig configuration.WorkingHoursConfig) {
+	var totalHours time.Duration
+	for _, unit := range day.Units {
+		if unit.Duration == nil {
+			continue
+		}
+		if unit.Type == repositories.CompensatoryType {
+			totalHours -= unit.Duration.Duration
+		} else {
+			totalHours += unit.Duration.Duration
+		}
+	}
+	day.TotalHours = &models.AeonDuration{Duration: totalHours}
+	day.OvertimeHours = &models.AeonDuration{Duration: OvertimeHours(day, totalHours, workingHoursConfig)}
+}
+
+// OvertimeHours returns the overtime of a day with the total hours: all of them on vacation days, public holidays
+// and weekends, the hours beyond the work day otherwise, and none if working hours are not enabled.
+func OvertimeHours(day *models.AeonDay, totalHours time.Duration, workingHoursConfig configuration.WorkingHoursConfig) time.Duration {
+	if !workingHoursConfig.Enabled {
+		return 0
+	}
+	if day.VacationDay || day.PublicHoliday || day.WeekEnd {
+		return totalHours
+	}
+	return totalHours - workingHoursConfig.WorkDay.Duration
+}
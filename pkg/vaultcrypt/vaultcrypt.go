@@ -0,0 +1,209 @@
+// Package vaultcrypt provides authenticated encryption of the time tracking data with a passphrase or a keyfile.
+package vaultcrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// PassphraseSource marks data encrypted with a key derived from a passphrase
+	PassphraseSource = "passphrase"
+	// KeyFileSource marks data encrypted with a key derived from the content of a keyfile
+	KeyFileSource = "keyfile"
+
+	// MinKeyFileSize is the minimum size of a keyfile in bytes
+	MinKeyFileSize = 32
+
+	formatVersion = 1
+	cipherName    = "AES-256-GCM"
+	keySize       = 32
+	saltSize      = 16
+	// Argon2id parameters for passphrases, following the recommendation of RFC 9106 for memory constrained environments
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+)
+
+type (
+	// Key is the secret used to encrypt and decrypt data, derived keys are cached per salt.
+	Key struct {
+		mu      sync.Mutex
+		source  string
+		secret  []byte
+		derived map[string][]byte
+		// salt is the salt used to encrypt, it is taken from the last decrypted data to avoid deriving the key again
+		salt []byte
+	}
+	// Header describes how the encrypted data has been encrypted
+	Header struct {
+		Version   int    `json:"version"`
+		Cipher    string `json:"cipher"`
+		KeySource string `json:"key_source"`
+		KDF       string `json:"kdf"`
+		Salt      []byte `json:"salt"`
+		Time      uint32 `json:"time,omitempty"`
+		Memory    uint32 `json:"memory,omitempty"`
+		Threads   uint8  `json:"threads,omitempty"`
+		Nonce     []byte `json:"nonce"`
+	}
+	// envelope is the encoded form of encrypted data, it is JSON so encrypted data files remain recognizable
+	envelope struct {
+		Encryption *Header `json:"aeontrac_encryption"`
+		Ciphertext []byte  `json:"ciphertext"`
+	}
+)
+
+// NewPassphraseKey creates a key from a passphrase.
+func NewPassphraseKey(passphrase string) (*Key, error) {
+	if passphrase == "" {
+		return nil, errors.New("the passphrase must not be empty")
+	}
+	return &Key{source: PassphraseSource, secret: []byte(passphrase), derived: map[string][]byte{}}, nil
+}
+
+// LoadKeyFile creates a key from the content of a keyfile.
+func LoadKeyFile(path string) (*Key, error) {
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading keyfile: %w", err)
+	}
+	if len(secret) < MinKeyFileSize {
+		return nil, fmt.Errorf("the keyfile %s must contain at least %d bytes", path, MinKeyFileSize)
+	}
+	return &Key{source: KeyFileSource, secret: secret, derived: map[string][]byte{}}, nil
+}
+
+// GenerateKeyFile writes a new random keyfile, which is readable only by its owner.
+func GenerateKeyFile(path string) error {
+	secret := make([]byte, keySize)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(secret); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Source returns whether the key is derived from a passphrase or a keyfile.
+func (k *Key) Source() string {
+	return k.source
+}
+
+// IsEncrypted reports whether the data has been encrypted by Encrypt.
+func IsEncrypted(data []byte) bool {
+	if !bytes.Contains(data, []byte(`"aeontrac_encryption"`)) {
+		return false
+	}
+	var e envelope
+	return json.Unmarshal(data, &e) == nil && e.Encryption != nil
+}
+
+// Encrypt encrypts and authenticates the data with the key.
+func Encrypt(key *Key, plaintext []byte) ([]byte, error) {
+	key.mu.Lock()
+	defer key.mu.Unlock()
+	if key.salt == nil {
+		key.salt = make([]byte, saltSize)
+		if _, err := rand.Read(key.salt); err != nil {
+			return nil, err
+		}
+	}
+	header := &Header{Version: formatVersion, Cipher: cipherName, KeySource: key.source, Salt: key.salt}
+	if key.source == PassphraseSource {
+		header.KDF, header.Time, header.Memory, header.Threads = "argon2id", argonTime, argonMemory, argonThreads
+	} else {
+		header.KDF = "hkdf-sha256"
+	}
+	aead, err := key.aead(header)
+	if err != nil {
+		return nil, err
+	}
+	header.Nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(header.Nonce); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(envelope{
+		Encryption: header,
+		Ciphertext: aead.Seal(nil, header.Nonce, plaintext, nil),
+	}, "", "    ")
+}
+
+// Decrypt decrypts data encrypted by Encrypt, it fails if the key is wrong or the data has been tampered with.
+func Decrypt(key *Key, data []byte) ([]byte, error) {
+	var e envelope
+	if err := json.Unmarshal(data, &e); err != nil || e.Encryption == nil {
+		return nil, errors.New("the data is not encrypted")
+	}
+	header := e.Encryption
+	if header.Version != formatVersion || header.Cipher != cipherName {
+		return nil, fmt.Errorf("unsupported encryption format %d with cipher %s", header.Version, header.Cipher)
+	}
+	if key == nil {
+		return nil, aeonerrors.ErrVaultLocked
+	}
+	key.mu.Lock()
+	defer key.mu.Unlock()
+	if header.KeySource != key.source {
+		return nil, fmt.Errorf("%w: the data is encrypted with a %s", aeonerrors.ErrWrongKey, header.KeySource)
+	}
+	aead, err := key.aead(header)
+	if err != nil {
+		return nil, err
+	}
+	if len(header.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce of encrypted data")
+	}
+	plaintext, err := aead.Open(nil, header.Nonce, e.Ciphertext, nil)
+	if err != nil {
+		return nil, aeonerrors.ErrWrongKey
+	}
+	key.salt = header.Salt
+	return plaintext, nil
+}
+
+// aead returns the cipher for the salt and key derivation parameters of the header, the key must be locked
+func (k *Key) aead(header *Header) (cipher.AEAD, error) {
+	derived, ok := k.derived[string(header.Salt)]
+	if !ok {
+		switch header.KDF {
+		case "argon2id":
+			if header.Time == 0 || header.Memory == 0 || header.Threads == 0 {
+				return nil, errors.New("invalid key derivation parameters")
+			}
+			derived = argon2.IDKey(k.secret, header.Salt, header.Time, header.Memory, header.Threads, keySize)
+		case "hkdf-sha256":
+			var err error
+			derived, err = hkdf.Key(sha256.New, k.secret, header.Salt, "aeontrac vault", keySize)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported key derivation %s", header.KDF)
+		}
+		k.derived[string(header.Salt)] = derived
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
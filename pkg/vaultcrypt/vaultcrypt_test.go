@@ -0,0 +1,110 @@
+package vaultcrypt
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	aeonerrors "github.com/jame-developer/aeontrac/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKeyFile(t *testing.T, content []byte) string {
+	path := filepath.Join(t.TempDir(), "aeontrac.key")
+	assert.NoError(t, os.WriteFile(path, content, 0600))
+	return path
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	plaintext := []byte(`{"aeon_days":{}}`)
+	passphraseKey, err := NewPassphraseKey("correct horse battery staple")
+	assert.NoError(t, err)
+	keyFileKey, err := LoadKeyFile(newTestKeyFile(t, bytes.Repeat([]byte{7}, MinKeyFileSize)))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		encryptKey    *Key
+		decryptKey    func() *Key
+		tamper        bool
+		expectedError error
+	}{
+		{
+			name:       "Passphrase",
+			encryptKey: passphraseKey,
+			decryptKey: func() *Key {
+				key, _ := NewPassphraseKey("correct horse battery staple")
+				return key
+			},
+		},
+		{
+			name:       "KeyFile",
+			encryptKey: keyFileKey,
+			decryptKey: func() *Key { return keyFileKey },
+		},
+		{
+			name:       "WrongPassphrase",
+			encryptKey: passphraseKey,
+			decryptKey: func() *Key {
+				key, _ := NewPassphraseKey("wrong")
+				return key
+			},
+			expectedError: aeonerrors.ErrWrongKey,
+		},
+		{
+			name:          "KeyFileForPassphrase",
+			encryptKey:    passphraseKey,
+			decryptKey:    func() *Key { return keyFileKey },
+			expectedError: aeonerrors.ErrWrongKey,
+		},
+		{
+			name:          "MissingKey",
+			encryptKey:    passphraseKey,
+			decryptKey:    func() *Key { return nil },
+			expectedError: aeonerrors.ErrVaultLocked,
+		},
+		{
+			name:          "TamperedCiphertext",
+			encryptKey:    keyFileKey,
+			decryptKey:    func() *Key { return keyFileKey },
+			tamper:        true,
+			expectedError: aeonerrors.ErrWrongKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := Encrypt(tt.encryptKey, plaintext)
+			assert.NoError(t, err)
+			assert.True(t, IsEncrypted(encrypted))
+			assert.NotContains(t, string(encrypted), "aeon_days")
+			if tt.tamper {
+				var e envelope
+				assert.NoError(t, json.Unmarshal(encrypted, &e))
+				e.Ciphertext[0] ^= 1
+				encrypted, err = json.Marshal(e)
+				assert.NoError(t, err)
+			}
+
+			decrypted, err := Decrypt(tt.decryptKey(), encrypted)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, plaintext, decrypted)
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	_, err := NewPassphraseKey("")
+	assert.Error(t, err)
+	_, err = LoadKeyFile(newTestKeyFile(t, []byte("short")))
+	assert.Error(t, err)
+	_, err = LoadKeyFile(filepath.Join(t.TempDir(), "missing.key"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.False(t, IsEncrypted([]byte(`{"aeon_days":{}}`)))
+}